@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"ai-financial-coach/internal/belvofake"
+)
+
+// Runs the fake Belvo API so the backend can be used fully offline:
+//
+//	go run ./cmd/fakebelvo
+//	BELVO_BASE_URL=http://localhost:9090 go run ./cmd/api
+func main() {
+	addr := os.Getenv("FAKE_BELVO_ADDR")
+	if addr == "" {
+		addr = ":9090"
+	}
+
+	// Leave both empty to accept any credentials
+	secretID := os.Getenv("FAKE_BELVO_SECRET_ID")
+	secretKey := os.Getenv("FAKE_BELVO_SECRET_KEY")
+
+	server := belvofake.NewServer(secretID, secretKey)
+
+	fmt.Printf("🧪 Fake Belvo API listening on %s\n", addr)
+	for linkID, persona := range server.Personas() {
+		fmt.Printf("   Link %s → %s (%d transactions)\n", linkID, persona.Owner.DisplayName, len(persona.Transactions))
+	}
+
+	if err := http.ListenAndServe(addr, server); err != nil {
+		fmt.Printf("❌ Fake Belvo server stopped: %v\n", err)
+		os.Exit(1)
+	}
+}
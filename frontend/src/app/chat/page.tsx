@@ -15,8 +15,8 @@ interface Message {
 }
 
 interface BelvoSession {
-  secretId: string
-  secretKey: string
+  token: string
+  expiresAt?: string
   linkId?: string
   authenticated: boolean
   dataStatus?: string
@@ -53,6 +53,12 @@ interface DetailedBelvoLink {
   institution?: string
 }
 
+// authHeaders authenticates a request with the session token from login
+const authHeaders = (session: BelvoSession): Record<string, string> => ({
+  'Content-Type': 'application/json',
+  Authorization: `Bearer ${session.token}`
+})
+
 export default function ChatPage() {
   const [messages, setMessages] = useState<Message[]>([])
   const [inputMessage, setInputMessage] = useState('')
@@ -62,6 +68,8 @@ export default function ChatPage() {
   const [availableLinks, setAvailableLinks] = useState<BelvoLink[]>([])
   const [selectedLink, setSelectedLink] = useState<DetailedBelvoLink | null>(null)
   const [showLinkSelection, setShowLinkSelection] = useState(false)
+  // The server keeps the history of this conversation; empty starts a new one
+  const [conversationId, setConversationId] = useState('')
   const messagesEndRef = useRef<HTMLDivElement>(null)
   const router = useRouter()
 
@@ -75,13 +83,16 @@ export default function ChatPage() {
 
   useEffect(() => {
     // Check if user is authenticated (try both session keys)
-    const sessionData = sessionStorage.getItem('belvo_session') || sessionStorage.getItem('belvo_credentials')
-    if (!sessionData) {
+    const sessionData = sessionStorage.getItem('belvo_session')
+    const parsedSession = sessionData ? JSON.parse(sessionData) as BelvoSession : null
+    if (!parsedSession?.token) {
+      // Sessions from before login tokens carried the raw secrets; drop them
+      sessionStorage.removeItem('belvo_session')
+      sessionStorage.removeItem('belvo_credentials')
       router.push('/')
       return
     }
 
-    const parsedSession = JSON.parse(sessionData) as BelvoSession
     setSession(parsedSession)
 
     // If link_selection method, fetch available links
@@ -123,6 +134,7 @@ export default function ChatPage() {
       timestamp: new Date()
     }
     setMessages([welcomeMessage])
+    setConversationId('')
   }, [language, router])
 
   const sendMessage = async () => {
@@ -159,25 +171,23 @@ export default function ChatPage() {
       
       console.log('✅ Backend is running')
 
-      // Send the AI chat request
-      console.log('🤖 Step 2: Sending AI chat request...')
-      const response = await fetch(`${API_URL}/api/ai/chat`, {
+      // Stream the AI chat answer
+      console.log('🤖 Step 2: Streaming AI chat response...')
+      const response = await fetch(`${API_URL}/api/ai/chat/stream`, {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: authHeaders(session),
         body: JSON.stringify({
           message: messageText,
           language: language === 'en' ? 'en' : 'pt',
           credential_mode: 'custom',
           link_id: selectedLink?.link_id || session.linkId,
-          secret_id: session.secretId,
-          secret_key: session.secretKey
+          conversation_id: conversationId || undefined
         })
       })
 
       console.log('📡 Response status:', response.status)
-      console.log('📡 Response headers:', Object.fromEntries(response.headers.entries()))
 
-      if (!response.ok) {
+      if (!response.ok || !response.body) {
         const errorText = await response.text()
         console.error('❌ AI API Error:', {
           status: response.status,
@@ -187,31 +197,52 @@ export default function ChatPage() {
         throw new Error(`AI API failed with status ${response.status}: ${errorText}`)
       }
 
-      const data = await response.json()
-      console.log('📦 AI Response data:', data)
-      
-      // Try different response formats
-      const aiContent = 
-        data.data?.chat_response?.message ||
-        data.chat_response?.message ||
-        data.data?.chat_response?.response ||
-        data.chat_response?.response ||
-        data.chat_response ||
-        data.data?.response ||
-        data.response ||
-        data.message ||
-        'Sorry, I could not process your request.'
-
-      console.log('💬 Extracted AI content:', aiContent)
-      
-      const aiMessage: Message = {
-        id: (Date.now() + 1).toString(),
+      const aiMessageId = (Date.now() + 1).toString()
+      setMessages(prev => [...prev, {
+        id: aiMessageId,
         role: 'assistant',
-        content: aiContent,
+        content: '',
         timestamp: new Date()
-      }
+      }])
+      setIsLoading(false)
 
-      setMessages(prev => [...prev, aiMessage])
+      const setAIContent = (update: (content: string) => string) =>
+        setMessages(prev => prev.map(m => m.id === aiMessageId ? { ...m, content: update(m.content) } : m))
+
+      // Events are "event: <name>\ndata: <json>" blocks separated by a blank line
+      const reader = response.body.getReader()
+      const decoder = new TextDecoder()
+      let buffer = ''
+      for (;;) {
+        const { done, value } = await reader.read()
+        if (done) break
+        buffer += decoder.decode(value, { stream: true })
+
+        let boundary
+        while ((boundary = buffer.indexOf('\n\n')) !== -1) {
+          const block = buffer.slice(0, boundary)
+          buffer = buffer.slice(boundary + 2)
+
+          let event = 'message'
+          let data = ''
+          for (const line of block.split('\n')) {
+            if (line.startsWith('event:')) event = line.slice(6).trim()
+            else if (line.startsWith('data:')) data += line.slice(5).trim()
+          }
+          if (!data) continue
+          const payload = JSON.parse(data)
+
+          if (event === 'delta') {
+            setAIContent(content => content + payload.content)
+          } else if (event === 'done') {
+            console.log('📦 AI Response data:', payload)
+            if (payload.conversation_id) setConversationId(payload.conversation_id)
+            setAIContent(content => content || payload.message || 'Sorry, I could not process your request.')
+          } else if (event === 'error') {
+            throw new Error(payload.error?.message || 'AI stream failed')
+          }
+        }
+      }
 
     } catch (error) {
       console.error('💥 Chat error:', error)
@@ -234,11 +265,8 @@ export default function ChatPage() {
       console.log('🔍 Fetching available links...')
       const response = await fetch(`${API_URL}/api/belvo/links/for-selection`, {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({
-          secret_id: session.secretId,
-          secret_key: session.secretKey
-        })
+        headers: authHeaders(session),
+        body: JSON.stringify({})
       })
 
       console.log('📡 Response status:', response.status)
@@ -249,6 +277,10 @@ export default function ChatPage() {
         const links = data.data?.links || []
         console.log('🔗 Setting available links:', links.length, 'links')
         setAvailableLinks(links)
+      } else if (response.status === 401) {
+        // Session expired or revoked: log in again
+        sessionStorage.removeItem('belvo_session')
+        router.push('/')
       } else {
         console.error('❌ Failed to fetch links, status:', response.status)
       }
@@ -288,11 +320,8 @@ export default function ChatPage() {
       console.log('🔍 Loading detailed data for:', link.id)
       const response = await fetch(`${API_URL}/api/belvo/links/detailed-info/${link.id}`, {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({
-          secret_id: session!.secretId,
-          secret_key: session!.secretKey
-        })
+        headers: authHeaders(session!),
+        body: JSON.stringify({})
       })
 
       if (response.ok) {
@@ -307,7 +336,7 @@ export default function ChatPage() {
         try {
           await fetch(`${API_URL}/api/ai/cache-context`, {
             method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
+            headers: authHeaders(session!),
             body: JSON.stringify({
               link_id: detailedLink.link_id,
               owner_name: detailedLink.owner_name,
@@ -386,6 +415,7 @@ export default function ChatPage() {
         }
 
         setMessages([welcomeMessage])
+        setConversationId('')
       } else {
         throw new Error('Failed to load detailed customer data')
       }
@@ -450,6 +480,9 @@ export default function ChatPage() {
           </button>
           <button
             onClick={() => {
+              if (session) {
+                fetch(`${API_URL}/api/auth/logout`, { method: 'POST', headers: authHeaders(session) }).catch(() => {})
+              }
               sessionStorage.removeItem('belvo_session')
               router.push('/')
             }}
@@ -29,10 +29,10 @@ export default function AuthPage() {
     setError('')
 
     try {
-      console.log('🔐 Step 1: Testing Belvo authentication...')
+      console.log('🔐 Step 1: Logging in with Belvo credentials...')
       
-      // First, test Belvo connection with provided credentials
-      const authResponse = await fetch(`${API_URL}/api/belvo/test-connection`, {
+      // The credentials are checked once; the server keeps them and returns a session token
+      const authResponse = await fetch(`${API_URL}/api/auth/login`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({
@@ -46,25 +46,25 @@ export default function AuthPage() {
       }
 
       const authData = await authResponse.json()
-      if (!authData.data?.connected && authData.data?.status !== 'success') {
+      if (!authData.data?.token) {
         throw new Error('Failed to authenticate with Belvo API')
       }
 
       console.log('✅ Belvo authentication successful')
 
-                     console.log('🔍 Step 2: Credentials verified, proceeding to link selection...')
+      console.log('🔍 Step 2: Credentials verified, proceeding to link selection...')
 
-                     // Store session with credentials only - link selection will happen in chat
-               sessionStorage.setItem('belvo_session', JSON.stringify({
-                   secretId,
-                   secretKey,
-                   authenticated: true,
-                   method: 'link_selection',
-                   timestamp: Date.now()
-               }))
+      // Store only the session token - link selection will happen in chat
+      sessionStorage.setItem('belvo_session', JSON.stringify({
+        token: authData.data.token,
+        expiresAt: authData.data.expires_at,
+        authenticated: true,
+        method: 'link_selection',
+        timestamp: Date.now()
+      }))
 
-               console.log('🎉 Authentication complete, redirecting to AI assistant...')
-               router.push('/chat')
+      console.log('🎉 Authentication complete, redirecting to AI assistant...')
+      router.push('/chat')
 
     } catch (error) {
       console.error('❌ Connection error:', error)
@@ -1,8 +1,13 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,67 +24,320 @@ type ContextCache struct {
 }
 
 type CachedContext struct {
-	Summary   *models.FinancialSummary
-	LinkID    string
-	OwnerName string
-	CachedAt  time.Time
-	ExpiresAt time.Time
+	Summary     *models.FinancialSummary
+	LinkID      string
+	OwnerName   string
+	Account     string // Credential scope of the session that loaded it (see sessionOwner)
+	Credentials []byte // Sealed credentials of the session that loaded it (see BelvoClients.Seal)
+	CachedAt    time.Time
+	ExpiresAt   time.Time
 }
 
 // AIHandler handles HTTP requests related to AI financial coaching
 type AIHandler struct {
 	aiService     *service.AIService
-	belvoService  *service.BelvoService
+	belvoClients  *BelvoClients
 	marketService *service.MarketService
 	contextCache  *ContextCache
+	conversations service.ConversationStore
 }
 
-// NewAIHandler creates a new AIHandler instance
-func NewAIHandler(openAIAPIKey string, belvoService *service.BelvoService, marketService *service.MarketService) *AIHandler {
+// NewAIHandler creates a new AIHandler instance. A nil llm runs chat in mock mode.
+func NewAIHandler(llm *service.LLMConfig, belvoClients *BelvoClients, marketService *service.MarketService, conversations service.ConversationStore) *AIHandler {
 	return &AIHandler{
-		aiService:     service.NewAIService(openAIAPIKey, marketService, belvoService),
-		belvoService:  belvoService,
+		aiService:     service.NewAIService(llm, marketService, belvoClients.Default()),
+		belvoClients:  belvoClients,
 		marketService: marketService,
+		conversations: conversations,
 		contextCache: &ContextCache{
 			contexts: make(map[string]*CachedContext),
 		},
 	}
 }
 
-// StoreContext caches financial context for a link
-func (ah *AIHandler) StoreContext(linkID string, summary *models.FinancialSummary, ownerName string) {
+// GetAIService returns the AI service instance
+func (ah *AIHandler) GetAIService() *service.AIService {
+	return ah.aiService
+}
+
+// StoreContext caches financial context for a link, together with the credentials of
+// the request's session so a webhook can rebuild it for the same Belvo account. Entries
+// belong to the session's account: another account caching the same link gets its own.
+func (ah *AIHandler) StoreContext(ctx context.Context, linkID string, summary *models.FinancialSummary, ownerName string) error {
+	credentials, err := ah.belvoClients.Seal(ctx)
+	if err != nil {
+		return err
+	}
+	account, _ := sessionOwner(ctx)
+	ah.storeContext(linkID, summary, ownerName, account, credentials)
+	return nil
+}
+
+func (ah *AIHandler) storeContext(linkID string, summary *models.FinancialSummary, ownerName, account string, credentials []byte) {
 	ah.contextCache.mu.Lock()
 	defer ah.contextCache.mu.Unlock()
 
-	sessionKey := linkID
-	ah.contextCache.contexts[sessionKey] = &CachedContext{
-		Summary:   summary,
-		LinkID:    linkID,
-		OwnerName: ownerName,
-		CachedAt:  time.Now(),
-		ExpiresAt: time.Now().Add(24 * time.Hour),
+	// Readers only hold the read lock, so expired entries are dropped here
+	now := time.Now()
+	for key, cached := range ah.contextCache.contexts {
+		if now.After(cached.ExpiresAt) {
+			delete(ah.contextCache.contexts, key)
+		}
+	}
+
+	ah.contextCache.contexts[cacheKey(account, linkID)] = &CachedContext{
+		Summary:     summary,
+		LinkID:      linkID,
+		OwnerName:   ownerName,
+		Account:     account,
+		Credentials: credentials,
+		CachedAt:    time.Now(),
+		ExpiresAt:   time.Now().Add(24 * time.Hour),
 	}
 }
 
-// GetCachedContext retrieves cached financial context
-func (ah *AIHandler) GetCachedContext(linkID string) (*models.FinancialSummary, bool) {
+// cacheKey is the context cache key of a link for one Belvo account
+func cacheKey(account, linkID string) string {
+	return account + "|" + linkID
+}
+
+// GetCachedContext retrieves the financial context the session's account cached for a link
+func (ah *AIHandler) GetCachedContext(ctx context.Context, linkID string) (*models.FinancialSummary, bool) {
+	account, _ := sessionOwner(ctx)
+
 	ah.contextCache.mu.RLock()
 	defer ah.contextCache.mu.RUnlock()
 
-	sessionKey := linkID
-	cached, exists := ah.contextCache.contexts[sessionKey]
+	cached, exists := ah.contextCache.contexts[cacheKey(account, linkID)]
 	if !exists {
 		return nil, false
 	}
 
-	// Check if expired
+	// Expired entries are left for storeContext to drop; deleting needs the write lock
 	if time.Now().After(cached.ExpiresAt) {
-		delete(ah.contextCache.contexts, sessionKey)
 		return nil, false
 	}
 	return cached.Summary, true
 }
 
+// aiError reports links routed to different models as a 400 and deadlines as a 504,
+// and wraps anything else
+func aiError(action string, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &APIError{
+			Status:  http.StatusGatewayTimeout,
+			Code:    "timeout",
+			Message: fmt.Sprintf("failed to %s: request timed out", action),
+		}
+	}
+	if errors.Is(err, service.ErrConflictingLLMRoutes) {
+		return &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    "conflicting_llm_routes",
+			Message: err.Error(),
+		}
+	}
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
+
+// sessionLinkIDs returns linkIDs plus every link whose context the session's Belvo
+// account has cached. Routing chats and analyses by these, not only by the links a
+// request names, keeps a self-hosted customer's data off other models when a client
+// leaves the link out.
+func (ah *AIHandler) sessionLinkIDs(ctx context.Context, linkIDs ...string) []string {
+	account, ok := sessionOwner(ctx)
+	if !ok {
+		return linkIDs
+	}
+
+	ah.contextCache.mu.RLock()
+	defer ah.contextCache.mu.RUnlock()
+
+	now := time.Now()
+	for _, cached := range ah.contextCache.contexts {
+		if cached.Account != account || now.After(cached.ExpiresAt) {
+			continue
+		}
+		linkIDs = append(linkIDs, strings.Split(cached.LinkID, ",")...)
+	}
+	return linkIDs
+}
+
+// InvalidateContext drops every account's cached financial context for a link,
+// returning the accounts that had one
+func (ah *AIHandler) InvalidateContext(linkID string) []string {
+	return contextAccounts(ah.removeContext(linkID))
+}
+
+// removeContext drops every account's cached context for a link and every consolidated
+// context that includes it, returning the dropped entries
+func (ah *AIHandler) removeContext(linkID string) []*CachedContext {
+	ah.contextCache.mu.Lock()
+	defer ah.contextCache.mu.Unlock()
+
+	var removed []*CachedContext
+	for key, cached := range ah.contextCache.contexts {
+		// Consolidated contexts that include the link are stale too
+		if cached.LinkID == linkID || (cached.Summary != nil && containsLinkID(cached.Summary.LinkIDs, linkID)) {
+			delete(ah.contextCache.contexts, key)
+			removed = append(removed, cached)
+		}
+	}
+	return removed
+}
+
+// contextAccounts returns the distinct accounts of cached entries
+func contextAccounts(entries []*CachedContext) []string {
+	var accounts []string
+	for _, cached := range entries {
+		if !slices.Contains(accounts, cached.Account) {
+			accounts = append(accounts, cached.Account)
+		}
+	}
+	return accounts
+}
+
+// financialSummaryFor fetches the summary for one link, or the consolidated summary
+// when the person has several links
+func financialSummaryFor(ctx context.Context, belvoService service.BelvoClient, linkIDs []string, opts service.SummaryOptions) (*models.FinancialSummary, error) {
+	if len(linkIDs) == 1 {
+		return belvoService.GetFinancialSummary(ctx, linkIDs[0], opts)
+	}
+	return belvoService.GetConsolidatedFinancialSummary(ctx, linkIDs, opts)
+}
+
+// chatDataLinkIDs returns the links a chat's data comes from: those it names, otherwise
+// those its summary was built from
+func chatDataLinkIDs(request *models.ChatRequest) []string {
+	linkIDs := requestLinkIDs(request.LinkID, request.LinkIDs)
+	if len(linkIDs) == 0 && request.UserContext != nil {
+		linkIDs = service.NormalizeLinkIDs(append(strings.Split(request.UserContext.UserID, ","), request.UserContext.LinkIDs...))
+	}
+	return linkIDs
+}
+
+// transactionFetcher reads the transactions of linkIDs between two days, for chat tools
+// asking about days outside the summary's window
+func transactionFetcher(belvoService service.BelvoClient, linkIDs []string) func(ctx context.Context, from, to time.Time) ([]models.BelvoTransaction, error) {
+	if len(linkIDs) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, from, to time.Time) ([]models.BelvoTransaction, error) {
+		var transactions []models.BelvoTransaction
+		for _, linkID := range linkIDs {
+			linkTransactions, err := belvoService.GetStoredTransactions(ctx, linkID, &from, &to)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get transactions for link %s: %w", linkID, err)
+			}
+			transactions = append(transactions, linkTransactions...)
+		}
+		return transactions, nil
+	}
+}
+
+// summaryFits reports whether a cached summary answers a request for opts. A request
+// that didn't ask for a window or currency takes whatever was cached.
+func summaryFits(summary *models.FinancialSummary, lookbackMonths int, opts service.SummaryOptions) bool {
+	if lookbackMonths != 0 && (summary.Period == nil || summary.Period.LookbackMonths != opts.LookbackMonths) {
+		return false
+	}
+	return opts.ReportingCurrency == "" || summary.Currency == opts.ReportingCurrency
+}
+
+// summaryOptionsOf returns the options a summary was built with, so it can be rebuilt alike
+func summaryOptionsOf(summary *models.FinancialSummary) service.SummaryOptions {
+	opts := service.DefaultSummaryOptions
+	if summary == nil {
+		return opts
+	}
+	if summary.Period != nil && summary.Period.LookbackMonths > 0 {
+		opts.LookbackMonths = summary.Period.LookbackMonths
+	}
+	opts.ReportingCurrency = summary.Currency
+	return opts
+}
+
+// marketDataFor returns current market data with asset prices in the summary's
+// currency, so BTC's dollar price isn't shown next to amounts in reais
+func (ah *AIHandler) marketDataFor(ctx context.Context, summary *models.FinancialSummary) (*models.MarketDataSummary, error) {
+	marketData, err := ah.marketService.GetMarketDataSummary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if summary == nil || summary.Currency == "" {
+		return marketData, nil
+	}
+	return ah.marketService.ConvertMarketData(ctx, marketData, summary.Currency)
+}
+
+// requestLinkIDs merges a single link_id with a link_ids list into a normalized set
+func requestLinkIDs(linkID string, linkIDs []string) []string {
+	return service.NormalizeLinkIDs(append([]string{linkID}, linkIDs...))
+}
+
+// contextKey is the cache key for the context of a set of links
+func contextKey(linkIDs []string) string {
+	return strings.Join(linkIDs, ",")
+}
+
+func containsLinkID(linkIDs []string, linkID string) bool {
+	for _, id := range linkIDs {
+		if id == linkID {
+			return true
+		}
+	}
+	return false
+}
+
+// backgroundRefreshTimeout bounds a context rebuild triggered by a webhook
+const backgroundRefreshTimeout = 2 * time.Minute
+
+// RefreshContext invalidates every account's cached context for a link and rebuilds
+// each in the background with the credentials it was loaded with, so links created with
+// a customer's own Belvo secret refresh too. It returns the accounts that had context
+// cached. When an entry's credentials can't be used any more it is only dropped and the
+// error says why; the next chat loads it again with its session.
+func (ah *AIHandler) RefreshContext(linkID string) ([]string, error) {
+	removed := ah.removeContext(linkID)
+
+	var firstErr error
+	for _, cached := range removed {
+		if cached.LinkID != linkID {
+			continue // Consolidated contexts are rebuilt by the next chat that needs them
+		}
+		belvoService, err := ah.belvoClients.ForSealed(cached.Credentials)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		go ah.rebuildContext(belvoService, cached)
+	}
+	return contextAccounts(removed), firstErr
+}
+
+// rebuildContext fetches a fresh summary for a dropped entry and caches it for the same
+// account. It runs detached from the webhook request, which returns before it finishes.
+func (ah *AIHandler) rebuildContext(belvoService service.BelvoClient, cached *CachedContext) {
+	ctx, cancel := context.WithTimeout(context.Background(), backgroundRefreshTimeout)
+	defer cancel()
+
+	linkID := cached.LinkID
+	// Pull the new transactions Belvo just announced before rebuilding
+	if _, err := belvoService.SyncTransactions(ctx, linkID); err != nil && !errors.Is(err, service.ErrTransactionSyncDisabled) {
+		fmt.Printf("⚠️ Failed to sync transactions for link %s: %v\n", linkID, err)
+	}
+
+	summary, err := belvoService.GetFinancialSummary(ctx, linkID, summaryOptionsOf(cached.Summary))
+	if err != nil {
+		fmt.Printf("❌ Failed to refresh cached context for link %s: %v\n", linkID, err)
+		return
+	}
+	ah.storeContext(linkID, summary, cached.OwnerName, cached.Account, cached.Credentials)
+	fmt.Printf("✅ Refreshed cached context for link %s\n", linkID)
+}
+
 // CacheContextFromSummary handles POST /api/ai/cache-context - stores financial context
 func (ah *AIHandler) CacheContextFromSummary(ctx *gofr.Context) (interface{}, error) {
 	var request struct {
@@ -88,16 +346,24 @@ func (ah *AIHandler) CacheContextFromSummary(ctx *gofr.Context) (interface{}, er
 		Summary   *models.FinancialSummary `json:"financial_summary"`
 	}
 
+	if _, err := requireSessionOwner(ctx); err != nil {
+		return nil, err
+	}
 	if err := ctx.Bind(&request); err != nil {
-		return nil, fmt.Errorf("invalid request body: %w", err)
+		return nil, invalidParam("body", fmt.Sprintf("must be a cached context: %v", err))
 	}
 
-	if request.LinkID == "" || request.Summary == nil {
-		return nil, fmt.Errorf("link_id and financial_summary are required")
+	if request.LinkID == "" {
+		return nil, invalidParam("link_id", "is required")
+	}
+	if request.Summary == nil {
+		return nil, invalidParam("financial_summary", "is required")
 	}
 
 	// Store the context
-	ah.StoreContext(request.LinkID, request.Summary, request.OwnerName)
+	if err := ah.StoreContext(ctx, request.LinkID, request.Summary, request.OwnerName); err != nil {
+		return nil, err
+	}
 
 	return map[string]interface{}{
 		"message": fmt.Sprintf("Financial context cached for link: %s", request.LinkID[:8]),
@@ -125,11 +391,12 @@ func (ah *AIHandler) AnalyzeFinancialProfile(ctx *gofr.Context) (interface{}, er
 	if request.Language == "" {
 		request.Language = "pt-BR" // Default Portuguese
 	}
+	request.SessionLinkIDs = ah.sessionLinkIDs(ctx)
 
 	// Perform AI analysis
-	analysis, err := ah.aiService.AnalyzeFinancialProfile(&request)
+	analysis, err := ah.aiService.AnalyzeFinancialProfile(ctx, &request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to analyze financial profile: %w", err)
+		return nil, aiError("analyze financial profile", err)
 	}
 
 	return map[string]interface{}{
@@ -158,7 +425,7 @@ func (ah *AIHandler) GetPortfolioRecommendation(ctx *gofr.Context) (interface{},
 
 	// Create a basic request for portfolio recommendation
 	mockSummary := ah.createMockFinancialSummary(monthlyBudget)
-	marketData, err := ah.marketService.GetMarketDataSummary()
+	marketData, err := ah.marketDataFor(ctx, mockSummary)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get market data: %w", err)
 	}
@@ -173,7 +440,7 @@ func (ah *AIHandler) GetPortfolioRecommendation(ctx *gofr.Context) (interface{},
 		Language:          "pt-BR",
 	}
 
-	analysis, err := ah.aiService.AnalyzeFinancialProfile(request)
+	analysis, err := ah.aiService.AnalyzeFinancialProfile(ctx, request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate portfolio recommendation: %w", err)
 	}
@@ -197,9 +464,10 @@ func (ah *AIHandler) GenerateWhatIf(ctx *gofr.Context) (interface{}, error) {
 		return nil, fmt.Errorf("invalid request body: %w", err)
 	}
 
-	scenario, err := ah.aiService.GenerateWhatIfScenario(&request.BaseRequest, &request.ScenarioParams)
+	request.BaseRequest.SessionLinkIDs = ah.sessionLinkIDs(ctx)
+	scenario, err := ah.aiService.GenerateWhatIfScenario(ctx, &request.BaseRequest, &request.ScenarioParams)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate what-if scenario: %w", err)
+		return nil, aiError("generate what-if scenario", err)
 	}
 
 	return map[string]interface{}{
@@ -215,33 +483,46 @@ func (ah *AIHandler) GetQuickAnalysis(ctx *gofr.Context) (interface{}, error) {
 		return nil, fmt.Errorf("link_id parameter is required")
 	}
 
+	// Additional links of the same person can be passed as ?link_ids=a,b
+	linkIDs := requestLinkIDs(linkID, strings.Split(ctx.Param("link_ids"), ","))
+	opts, err := querySummaryOptions(ctx.Param("lookback_months"), ctx.Param("reporting_currency"))
+	if err != nil {
+		return nil, err
+	}
+
 	// Get financial data from Belvo
-	financialSummary, err := ah.belvoService.GetFinancialSummary(linkID)
+	belvoService, err := ah.belvoClients.For(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get financial summary: %w", err)
+		return nil, err
+	}
+
+	financialSummary, err := financialSummaryFor(ctx, belvoService, linkIDs, opts)
+	if err != nil {
+		return nil, belvoError(err, "failed to get financial summary")
 	}
 
 	// Get market data
-	marketData, err := ah.marketService.GetMarketDataSummary()
+	marketData, err := ah.marketDataFor(ctx, financialSummary)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get market data: %w", err)
 	}
 
 	// Create AI analysis request
 	request := &models.AIAnalysisRequest{
-		UserID:            "belvo-user-" + linkID,
+		UserID:            "belvo-user-" + contextKey(linkIDs),
 		FinancialSummary:  financialSummary,
 		MarketData:        marketData,
 		RiskProfile:       ah.determineRiskProfile(financialSummary),
 		InvestmentHorizon: 5,
 		MonthlyBudget:     financialSummary.MonthlySurplus * 0.8, // 80% of surplus
 		Language:          "pt-BR",
+		SessionLinkIDs:    ah.sessionLinkIDs(ctx, linkIDs...),
 	}
 
 	// Perform AI analysis
-	analysis, err := ah.aiService.AnalyzeFinancialProfile(request)
+	analysis, err := ah.aiService.AnalyzeFinancialProfile(ctx, request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to analyze financial profile: %w", err)
+		return nil, aiError("analyze financial profile", err)
 	}
 
 	return map[string]interface{}{
@@ -277,6 +558,12 @@ func (ah *AIHandler) GetMockAnalysis(ctx *gofr.Context) (interface{}, error) {
 	}
 
 	linkID := ctx.Param("link_id")
+	linkIDs := requestLinkIDs(linkID, strings.Split(ctx.Param("link_ids"), ","))
+
+	opts, err := querySummaryOptions(ctx.Param("lookback_months"), ctx.Param("reporting_currency"))
+	if err != nil {
+		return nil, err
+	}
 
 	monthlyIncomeStr := ctx.Param("monthly_income")
 	monthlyIncome := 8500.0 // Default
@@ -296,16 +583,26 @@ func (ah *AIHandler) GetMockAnalysis(ctx *gofr.Context) (interface{}, error) {
 		mockSummary = ah.createMockFinancialSummaryWithIncome(monthlyIncome)
 		dataSource = "Mock Data (Demo Mode)"
 	case "test", "custom":
-		if linkID != "" {
-			// Try to get real Belvo data
-			realSummary, err := ah.belvoService.GetFinancialSummary(linkID)
+		if len(linkIDs) > 0 {
+			// Try to get real Belvo data: test mode reads with the server's credentials, where
+			// they are shared, and custom with the session's
+			var belvoService service.BelvoClient
+			if credentialMode == "test" {
+				belvoService, err = ah.belvoClients.server()
+			} else {
+				belvoService, err = ah.belvoClients.For(ctx)
+			}
+			if err != nil {
+				return nil, err
+			}
+			realSummary, err := financialSummaryFor(ctx, belvoService, linkIDs, opts)
 			if err != nil {
 				// Fallback to mock data if Belvo fails
 				mockSummary = ah.createMockFinancialSummaryWithIncome(monthlyIncome)
 				dataSource = "Mock Data (Belvo Fallback)"
 			} else {
 				mockSummary = realSummary
-				dataSource = fmt.Sprintf("Real Belvo Data (Link: %s)", linkID)
+				dataSource = fmt.Sprintf("Real Belvo Data (Link: %s)", contextKey(linkIDs))
 			}
 		} else {
 			// No link ID provided, use mock data
@@ -319,7 +616,7 @@ func (ah *AIHandler) GetMockAnalysis(ctx *gofr.Context) (interface{}, error) {
 	}
 
 	// Get real market data
-	marketData, err := ah.marketService.GetMarketDataSummary()
+	marketData, err := ah.marketDataFor(ctx, mockSummary)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get market data: %w", err)
 	}
@@ -333,6 +630,7 @@ func (ah *AIHandler) GetMockAnalysis(ctx *gofr.Context) (interface{}, error) {
 		InvestmentHorizon: 5,
 		MonthlyBudget:     mockSummary.MonthlySurplus * 0.8,
 		Language:          language,
+		SessionLinkIDs:    ah.sessionLinkIDs(ctx, linkIDs...),
 		Goals: []models.InvestmentGoal{
 			{
 				Type:         "retirement",
@@ -352,9 +650,9 @@ func (ah *AIHandler) GetMockAnalysis(ctx *gofr.Context) (interface{}, error) {
 	}
 
 	// Perform AI analysis
-	analysis, err := ah.aiService.AnalyzeFinancialProfile(request)
+	analysis, err := ah.aiService.AnalyzeFinancialProfile(ctx, request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to analyze mock profile: %w", err)
+		return nil, aiError("analyze mock profile", err)
 	}
 
 	return map[string]interface{}{
@@ -365,6 +663,7 @@ func (ah *AIHandler) GetMockAnalysis(ctx *gofr.Context) (interface{}, error) {
 			"language":        language,
 			"credential_mode": credentialMode,
 			"link_id":         linkID,
+			"link_ids":        linkIDs,
 		},
 		"data_source": dataSource,
 		"message":     getLocalizedMessage("mock_analysis_success", language),
@@ -420,15 +719,15 @@ func getLocalizedMessage(messageType, language string) string {
 
 // GetInvestmentAdvice handles GET /api/ai/advice
 func (ah *AIHandler) GetInvestmentAdvice(ctx *gofr.Context) (interface{}, error) {
+	// Create simple mock for analysis
+	mockSummary := ah.createMockFinancialSummary(2000) // R$ 2000 monthly investment
+
 	// Get market data for current opportunities
-	marketData, err := ah.marketService.GetMarketDataSummary()
+	marketData, err := ah.marketDataFor(ctx, mockSummary)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get market data: %w", err)
 	}
 
-	// Create simple mock for analysis
-	mockSummary := ah.createMockFinancialSummary(2000) // R$ 2000 monthly investment
-
 	request := &models.AIAnalysisRequest{
 		UserID:            "advice-seeker",
 		FinancialSummary:  mockSummary,
@@ -439,7 +738,7 @@ func (ah *AIHandler) GetInvestmentAdvice(ctx *gofr.Context) (interface{}, error)
 		Language:          "pt-BR",
 	}
 
-	analysis, err := ah.aiService.AnalyzeFinancialProfile(request)
+	analysis, err := ah.aiService.AnalyzeFinancialProfile(ctx, request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate investment advice: %w", err)
 	}
@@ -466,29 +765,63 @@ func (ah *AIHandler) Chat(ctx *gofr.Context) (interface{}, error) {
 		return nil, fmt.Errorf("invalid request body: %w", err)
 	}
 
+	// Continue a stored conversation, or start one
+	isNew, err := ah.loadConversation(ctx, &request)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ah.prepareChat(ctx, &request); err != nil {
+		return nil, err
+	}
+
+	// Call AI service for conversational response
+	response, err := ah.aiService.Chat(ctx, &request)
+	if err != nil {
+		return nil, aiError("generate AI chat response", err)
+	}
+	if err := ah.saveConversation(ctx, &request, response, isNew); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"chat_response": response,
+		"message":       "AI chat response generated successfully",
+	}, nil
+}
+
+// prepareChat fills in the defaults and the financial and market context a chat
+// request didn't bring: cached context first, then a fresh summary from Belvo
+func (ah *AIHandler) prepareChat(ctx context.Context, request *models.ChatRequest) error {
 	// Set default language if not provided
 	if request.Language == "" {
 		request.Language = "en"
 	}
 
+	opts, err := summaryOptions(request.LookbackMonths, request.ReportingCurrency)
+	if err != nil {
+		return err
+	}
+
 	// Try to use cached context first, then fetch if needed
 	if request.UserContext == nil {
-		if (request.CredentialMode == "test" || request.CredentialMode == "custom") && request.LinkID != "" {
-			// First, try to get cached context
-			if cachedSummary, found := ah.GetCachedContext(request.LinkID); found {
+		linkIDs := requestLinkIDs(request.LinkID, request.LinkIDs)
+		if (request.CredentialMode == "test" || request.CredentialMode == "custom") && len(linkIDs) > 0 {
+			// First, try to get cached context built over the requested window
+			if cachedSummary, found := ah.GetCachedContext(ctx, contextKey(linkIDs)); found && summaryFits(cachedSummary, request.LookbackMonths, opts) {
 				request.UserContext = cachedSummary
 			} else {
-				// Use dynamic Belvo service with user credentials if provided
-				var belvoService *service.BelvoService
-				if request.SecretID != "" && request.SecretKey != "" {
-					belvoService = service.NewBelvoService(request.SecretID, request.SecretKey, "sandbox")
-				} else {
-					belvoService = ah.belvoService
+				// Use dynamic Belvo service with the session's credentials
+				belvoService, err := ah.belvoClients.For(ctx)
+				if err != nil {
+					return err
 				}
 
-				if summary, err := belvoService.GetFinancialSummary(request.LinkID); err == nil {
+				if summary, err := financialSummaryFor(ctx, belvoService, linkIDs, opts); err == nil {
 					request.UserContext = summary
-					ah.StoreContext(request.LinkID, summary, "Unknown Customer")
+					if err := ah.StoreContext(ctx, contextKey(linkIDs), summary, "Unknown Customer"); err != nil {
+						fmt.Printf("⚠️ Failed to cache context for %s: %v\n", contextKey(linkIDs), err)
+					}
 				} else {
 					request.UserContext = ah.createMockFinancialSummaryWithIncome(8500.0)
 				}
@@ -498,24 +831,23 @@ func (ah *AIHandler) Chat(ctx *gofr.Context) (interface{}, error) {
 		}
 	}
 
+	// Route by the links the server knows the chat is about, not only those it names
+	request.SessionLinkIDs = ah.sessionLinkIDs(ctx, request.SessionLinkIDs...)
+
+	// Let transaction tools read days outside the summary's window with the session's credentials
+	if belvoService, err := ah.belvoClients.For(ctx); err == nil {
+		request.FetchTransactions = transactionFetcher(belvoService, chatDataLinkIDs(request))
+	}
+
 	// Get market context
 	if request.MarketContext == nil {
-		marketData, err := ah.marketService.GetMarketDataSummary()
+		marketData, err := ah.marketDataFor(ctx, request.UserContext)
 		if err == nil {
 			request.MarketContext = marketData
 		}
 	}
 
-	// Call AI service for conversational response
-	response, err := ah.aiService.Chat(&request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate AI chat response: %w", err)
-	}
-
-	return map[string]interface{}{
-		"chat_response": response,
-		"message":       "AI chat response generated successfully",
-	}, nil
+	return nil
 }
 
 // createMockFinancialSummary creates a realistic mock financial summary
@@ -536,6 +868,7 @@ func (ah *AIHandler) createMockFinancialSummaryWithIncome(monthlyIncome float64)
 		MonthlyVariableExpenses: variableExpenses,
 		MonthlySurplus:          surplus,
 		TotalBalance:            surplus * 6, // 6 months of surplus as emergency fund
+		NetWorth:                surplus * 6,
 		Currency:                "BRL",
 	}
 }
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"ai-financial-coach/internal/models"
+)
+
+// sessionContext returns a context carrying a session for the given Belvo secret
+func sessionContext(secretID string) context.Context {
+	creds := models.RequestCredentials{SecretID: secretID, Environment: "sandbox"}
+	return context.WithValue(context.Background(), sessionContextKey{}, &requestSession{credentials: creds})
+}
+
+func TestContextCacheIsScopedToAccount(t *testing.T) {
+	handler := newMockChatHandler()
+	alice, bob := sessionContext("alice"), sessionContext("bob")
+	aliceAccount, _ := sessionOwner(alice)
+	bobAccount, _ := sessionOwner(bob)
+
+	handler.storeContext("link-1", &models.FinancialSummary{MonthlyIncome: 1000}, "Alice", aliceAccount, nil)
+
+	if _, found := handler.GetCachedContext(bob, "link-1"); found {
+		t.Fatal("another account read the cached context")
+	}
+
+	handler.storeContext("link-1", &models.FinancialSummary{MonthlyIncome: 5}, "Bob", bobAccount, nil)
+	summary, found := handler.GetCachedContext(alice, "link-1")
+	if !found || summary.MonthlyIncome != 1000 {
+		t.Fatalf("cached context = %+v, %v; want the account's own summary", summary, found)
+	}
+
+	if accounts := handler.InvalidateContext("link-1"); len(accounts) != 2 {
+		t.Errorf("InvalidateContext() accounts = %v, want both", accounts)
+	}
+	if _, found := handler.GetCachedContext(alice, "link-1"); found {
+		t.Error("context still cached after invalidation")
+	}
+}
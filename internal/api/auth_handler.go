@@ -0,0 +1,206 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gofr.dev/pkg/gofr"
+
+	"ai-financial-coach/internal/models"
+	"ai-financial-coach/internal/service"
+)
+
+// AuthHandler exchanges Belvo credentials for a session token, so the secrets are sent
+// once at login instead of in every request body
+type AuthHandler struct {
+	clients  *BelvoClients
+	sessions *service.SessionManager
+}
+
+// NewAuthHandler creates a new AuthHandler
+func NewAuthHandler(clients *BelvoClients, sessions *service.SessionManager) *AuthHandler {
+	return &AuthHandler{
+		clients:  clients,
+		sessions: sessions,
+	}
+}
+
+// Login handles POST /api/auth/login. The credentials are checked against Belvo before
+// a session is issued.
+func (ah *AuthHandler) Login(ctx *gofr.Context) (interface{}, error) {
+	var req models.RequestCredentials
+	if err := ctx.Bind(&req); err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+	if !req.HasSecrets() {
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    "credentials_required",
+			Message: "secret_id and secret_key are required",
+		}
+	}
+
+	environment, err := ah.clients.ResolveEnvironment(req.Environment)
+	if err != nil {
+		return nil, err
+	}
+	req.Environment = environment
+
+	belvoService, err := ah.clients.ForCredentials(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := belvoService.Ping(ctx); err != nil {
+		return nil, belvoError(err, "failed to validate Belvo credentials")
+	}
+
+	token, session, err := ah.sessions.Create(req)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("🔑 Session %s started for %s (%s)\n", session.ID[:8], session.SecretIDHint, session.Environment)
+	return map[string]interface{}{
+		"token":      token,
+		"token_type": "Bearer",
+		"expires_at": session.ExpiresAt,
+		"session":    session,
+		"message":    "Belvo credentials validated",
+	}, nil
+}
+
+// Logout handles POST /api/auth/logout
+func (ah *AuthHandler) Logout(ctx *gofr.Context) (interface{}, error) {
+	rs, ok := ctx.Value(sessionContextKey{}).(*requestSession)
+	if !ok {
+		return nil, unauthorized(service.ErrInvalidSessionToken)
+	}
+	if err := ah.sessions.Revoke(rs.token); err != nil {
+		return nil, unauthorized(err)
+	}
+
+	return map[string]interface{}{
+		"message": "Logged out",
+	}, nil
+}
+
+// GetSession handles GET /api/auth/session - the session behind the request's token
+func (ah *AuthHandler) GetSession(ctx *gofr.Context) (interface{}, error) {
+	session, ok := SessionFrom(ctx)
+	if !ok {
+		return nil, unauthorized(service.ErrInvalidSessionToken)
+	}
+
+	return map[string]interface{}{
+		"session": session,
+	}, nil
+}
+
+// sessionContextKey keys the resolved session in the request context
+type sessionContextKey struct{}
+
+// requestSession is what SessionAuthMiddleware stores for the handlers
+type requestSession struct {
+	token       string
+	session     *models.Session
+	credentials models.RequestCredentials
+}
+
+// SessionFrom returns the session the request was authenticated with
+func SessionFrom(ctx context.Context) (*models.Session, bool) {
+	rs, ok := ctx.Value(sessionContextKey{}).(*requestSession)
+	if !ok {
+		return nil, false
+	}
+	return rs.session, true
+}
+
+// SessionCredentials returns the decrypted Belvo credentials of the request's session
+func SessionCredentials(ctx context.Context) (models.RequestCredentials, bool) {
+	rs, ok := ctx.Value(sessionContextKey{}).(*requestSession)
+	if !ok {
+		return models.RequestCredentials{}, false
+	}
+	return rs.credentials, true
+}
+
+// sessionOwner scopes stored data (conversations, consents) to the Belvo account of the
+// session, so it outlives the session and other accounts can't see it
+func sessionOwner(ctx context.Context) (string, bool) {
+	creds, ok := SessionCredentials(ctx)
+	if !ok {
+		return "", false
+	}
+	return service.CredentialScope(creds.Environment, creds.SecretID), true
+}
+
+func requireSessionOwner(ctx context.Context) (string, error) {
+	owner, ok := sessionOwner(ctx)
+	if !ok {
+		return "", unauthorized(service.ErrInvalidSessionToken)
+	}
+	return owner, nil
+}
+
+// SessionAuthMiddleware requires a valid "Authorization: Bearer <token>" header on every
+// request except the public paths, and puts the session and its credentials in the
+// request context. Public paths match exactly.
+func SessionAuthMiddleware(sessions *service.SessionManager, publicPaths []string) func(http.Handler) http.Handler {
+	return func(inner http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions || isPublicPath(r.URL.Path, publicPaths) {
+				inner.ServeHTTP(w, r)
+				return
+			}
+
+			token := bearerToken(r.Header.Get("Authorization"))
+			if token == "" {
+				writeAPIError(w, unauthorized(errors.New("missing bearer token; log in at /api/auth/login")))
+				return
+			}
+
+			creds, session, err := sessions.Resolve(token)
+			if err != nil {
+				writeAPIError(w, unauthorized(err))
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), sessionContextKey{}, &requestSession{token: token, session: session, credentials: creds})
+			inner.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func isPublicPath(path string, publicPaths []string) bool {
+	for _, public := range publicPaths {
+		if path == public {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken extracts the token from an Authorization header
+func bearerToken(header string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// unauthorized turns a session error into a 401
+func unauthorized(err error) *APIError {
+	code := "invalid_session"
+	if errors.Is(err, service.ErrSessionExpired) {
+		code = "session_expired"
+	}
+	return &APIError{
+		Status:  http.StatusUnauthorized,
+		Code:    code,
+		Message: err.Error(),
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"gofr.dev/pkg/gofr"
+
+	"ai-financial-coach/internal/service"
+)
+
+// defaultBalanceHistoryDays is the range returned when from is omitted
+const defaultBalanceHistoryDays = 90
+
+// GetBalanceHistory handles GET /api/belvo/balances/{link_id}?from=&to=&granularity=
+func (bh *BelvoHandler) GetBalanceHistory(ctx *gofr.Context) (interface{}, error) {
+	linkID := ctx.PathParam("link_id")
+	if linkID == "" {
+		return nil, fmt.Errorf("link_id parameter is required")
+	}
+
+	to := time.Now()
+	if value := ctx.Param("to"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, invalidParam("to", "must be a date in YYYY-MM-DD format")
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -defaultBalanceHistoryDays)
+	if value := ctx.Param("from"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, invalidParam("from", "must be a date in YYYY-MM-DD format")
+		}
+		from = parsed
+	}
+	if to.Before(from) {
+		return nil, invalidParam("from", "must not be after to")
+	}
+
+	granularity := ctx.Param("granularity")
+	if granularity == "" {
+		granularity = service.GranularityDaily
+	}
+	if !service.IsValidGranularity(granularity) {
+		return nil, invalidParam("granularity", "must be daily, weekly or monthly")
+	}
+
+	// For now, use default service credentials like the other GET routes
+	belvoService, err := bh.clients.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := belvoService.GetBalanceHistory(ctx, linkID, from, to, granularity)
+	if err != nil {
+		return nil, belvoError(err, "failed to build balance history")
+	}
+
+	return map[string]interface{}{
+		"balance_history": history,
+		"link_id":         linkID,
+		"message":         fmt.Sprintf("Balance history for %d accounts", len(history.Accounts)),
+	}, nil
+}
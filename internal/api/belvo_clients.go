@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ai-financial-coach/internal/models"
+	"ai-financial-coach/internal/service"
+)
+
+// BelvoClients resolves per-request credentials into Belvo clients. The environment
+// travels with the credentials and is checked against an allow-list, so one
+// deployment can serve sandbox and production customers side by side.
+type BelvoClients struct {
+	defaultClient       service.BelvoClient
+	newClient           service.BelvoClientFactory
+	defaultEnvironment  string
+	allowedEnvironments []string
+	sessions            *service.SessionManager
+
+	// allowServerCredentials lets requests without a session, and the "test" credential
+	// modes, read with the server's own credentials. Off unless configured for development.
+	allowServerCredentials bool
+}
+
+// NewBelvoClients creates a resolver around the server's default client. All known
+// Belvo environments are allowed until SetAllowedEnvironments narrows the list.
+func NewBelvoClients(defaultClient service.BelvoClient, newClient service.BelvoClientFactory, defaultEnvironment string) *BelvoClients {
+	return &BelvoClients{
+		defaultClient:       defaultClient,
+		newClient:           newClient,
+		defaultEnvironment:  defaultEnvironment,
+		allowedEnvironments: []string{service.EnvironmentSandbox, service.EnvironmentProduction},
+	}
+}
+
+// SetAllowedEnvironments restricts which environments requests may select. Unknown
+// names are ignored; the default environment is always allowed.
+func (bc *BelvoClients) SetAllowedEnvironments(environments []string) {
+	allowed := []string{bc.defaultEnvironment}
+	for _, environment := range environments {
+		environment = strings.ToLower(strings.TrimSpace(environment))
+		if !service.IsKnownEnvironment(environment) {
+			fmt.Printf("⚠️ Ignoring unknown Belvo environment %q in allow-list\n", environment)
+			continue
+		}
+		if environment != bc.defaultEnvironment {
+			allowed = append(allowed, environment)
+		}
+	}
+	bc.allowedEnvironments = allowed
+}
+
+// SetSessions gives the resolver the session manager whose key seals credentials kept
+// past a request (see Seal)
+func (bc *BelvoClients) SetSessions(sessions *service.SessionManager) {
+	bc.sessions = sessions
+}
+
+// SetAllowServerCredentials lets requests without a session, and the "test" credential
+// modes, use the server's own Belvo credentials. Only the sandbox can be opened up this
+// way, for local development.
+func (bc *BelvoClients) SetAllowServerCredentials(allow bool) error {
+	if allow && bc.defaultEnvironment != service.EnvironmentSandbox {
+		return fmt.Errorf("server credentials can only be shared in the %s environment, not %s", service.EnvironmentSandbox, bc.defaultEnvironment)
+	}
+	bc.allowServerCredentials = allow
+	return nil
+}
+
+// AllowsServerCredentials reports whether requests may use the server's own credentials
+func (bc *BelvoClients) AllowsServerCredentials() bool {
+	return bc.allowServerCredentials
+}
+
+// AllowedEnvironments returns the environments requests may select
+func (bc *BelvoClients) AllowedEnvironments() []string {
+	return bc.allowedEnvironments
+}
+
+// SandboxOnly reports whether requests can only reach the sandbox
+func (bc *BelvoClients) SandboxOnly() bool {
+	for _, environment := range bc.allowedEnvironments {
+		if environment != service.EnvironmentSandbox {
+			return false
+		}
+	}
+	return true
+}
+
+// Default returns the client built from the server's own credentials
+func (bc *BelvoClients) Default() service.BelvoClient {
+	return bc.defaultClient
+}
+
+// DefaultEnvironment returns the environment used when a request doesn't name one
+func (bc *BelvoClients) DefaultEnvironment() string {
+	return bc.defaultEnvironment
+}
+
+// ResolveEnvironment validates a requested environment, defaulting empty values
+func (bc *BelvoClients) ResolveEnvironment(environment string) (string, error) {
+	environment = strings.ToLower(strings.TrimSpace(environment))
+	if environment == "" {
+		return bc.defaultEnvironment, nil
+	}
+
+	for _, allowed := range bc.allowedEnvironments {
+		if environment == allowed {
+			return environment, nil
+		}
+	}
+
+	return "", &APIError{
+		Status:  http.StatusBadRequest,
+		Code:    "invalid_environment",
+		Message: fmt.Sprintf("environment %q is not allowed", environment),
+		Details: map[string]interface{}{
+			"allowed_environments": bc.allowedEnvironments,
+		},
+	}
+}
+
+// For returns a client for the credentials of the request's session. Requests without
+// a session get an error, unless server credentials are allowed (see
+// SetAllowServerCredentials), in which case they get the default client.
+func (bc *BelvoClients) For(ctx context.Context) (service.BelvoClient, error) {
+	if creds, ok := SessionCredentials(ctx); ok {
+		return bc.ForCredentials(creds)
+	}
+	if !bc.allowServerCredentials {
+		return nil, &APIError{
+			Status:  http.StatusUnauthorized,
+			Code:    "session_required",
+			Message: "Belvo requests need a session; log in with POST /api/auth/login",
+		}
+	}
+	return bc.defaultClient, nil
+}
+
+// server returns the default client for the "test" credential modes, if server
+// credentials are allowed
+func (bc *BelvoClients) server() (service.BelvoClient, error) {
+	if !bc.allowServerCredentials {
+		return nil, serverCredentialsDisabled()
+	}
+	return bc.defaultClient, nil
+}
+
+func serverCredentialsDisabled() error {
+	return &APIError{
+		Status:  http.StatusForbidden,
+		Code:    "server_credentials_disabled",
+		Message: "test credentials are disabled on this server; log in with your own Belvo credentials",
+	}
+}
+
+// ForCredentials returns a client for explicit credentials. Without secrets it falls
+// back to the default client, which only serves the default environment, if server
+// credentials are allowed.
+func (bc *BelvoClients) ForCredentials(creds models.RequestCredentials) (service.BelvoClient, error) {
+	environment, err := bc.ResolveEnvironment(creds.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	if creds.HasSecrets() {
+		return bc.newClient(creds.SecretID, creds.SecretKey, environment), nil
+	}
+
+	if environment != bc.defaultEnvironment || !bc.allowServerCredentials {
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    "credentials_required",
+			Message: fmt.Sprintf("secret_id and secret_key are required for the %s environment", environment),
+		}
+	}
+	return bc.defaultClient, nil
+}
+
+// Seal encrypts the credentials of the request's session, so work that outlives the
+// request (a webhook-driven refresh, a consent renewal) can act for the same Belvo
+// account. It returns nil for requests without a session.
+func (bc *BelvoClients) Seal(ctx context.Context) ([]byte, error) {
+	creds, ok := SessionCredentials(ctx)
+	if !ok {
+		return nil, nil
+	}
+	if bc.sessions == nil {
+		return nil, fmt.Errorf("no session manager to seal credentials with")
+	}
+	return bc.sessions.SealCredentials(creds)
+}
+
+// ForSealed returns a client for credentials sealed by Seal. nil stands for a request
+// without a session and resolves as For does for one: an error unless server
+// credentials are allowed.
+func (bc *BelvoClients) ForSealed(sealed []byte) (service.BelvoClient, error) {
+	if sealed == nil {
+		return bc.For(context.Background())
+	}
+	if bc.sessions == nil {
+		return nil, fmt.Errorf("no session manager to open sealed credentials with")
+	}
+	creds, err := bc.sessions.OpenCredentials(sealed)
+	if err != nil {
+		return nil, err
+	}
+	return bc.ForCredentials(creds)
+}
@@ -1,12 +1,7 @@
 package api
 
 import (
-	"bytes"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"time"
 
 	"gofr.dev/pkg/gofr"
@@ -17,15 +12,21 @@ import (
 
 // BelvoHandler handles HTTP requests related to Belvo API
 type BelvoHandler struct {
-	belvoService  *service.BelvoService
+	clients       *BelvoClients
 	testSecretID  string
 	testSecretKey string
+	onLinkRemoved func(linkID string)
 }
 
-// NewBelvoHandler creates a new BelvoHandler instance
-func NewBelvoHandler(secretID, secretKey, environment string) *BelvoHandler {
+// NewBelvoHandler creates a new BelvoHandler instance. baseURL overrides the Belvo
+// host for every client the handler creates (empty keeps the environment default).
+func NewBelvoHandler(secretID, secretKey, environment, baseURL string) *BelvoHandler {
 	if environment == "" {
-		environment = "sandbox" // Default to sandbox
+		environment = service.EnvironmentSandbox // Default to sandbox
+	}
+	if !service.IsKnownEnvironment(environment) {
+		fmt.Printf("⚠️ BelvoHandler: Unknown environment %q, falling back to sandbox\n", environment)
+		environment = service.EnvironmentSandbox
 	}
 
 	if secretID == "" || secretKey == "" {
@@ -42,8 +43,14 @@ func NewBelvoHandler(secretID, secretKey, environment string) *BelvoHandler {
 	}
 
 	fmt.Printf("✅ BelvoHandler: Initializing with provided credentials\n")
+	client := service.NewBelvoServiceWithBaseURL(secretID, secretKey, environment, baseURL)
+	return NewBelvoHandlerWithClients(NewBelvoClients(client, service.NewBelvoClientFactory(baseURL), environment))
+}
+
+// NewBelvoHandlerWithClients creates a BelvoHandler around an existing client resolver
+func NewBelvoHandlerWithClients(clients *BelvoClients) *BelvoHandler {
 	return &BelvoHandler{
-		belvoService: service.NewBelvoService(secretID, secretKey, environment),
+		clients: clients,
 	}
 }
 
@@ -60,20 +67,35 @@ type CreateLinkRequest struct {
 	Password     string `json:"password"`
 	UsernameType string `json:"username_type"`
 	AccessMode   string `json:"access_mode"`
-	SecretID     string `json:"secret_id,omitempty"`
-	SecretKey    string `json:"secret_key,omitempty"`
 }
 
-// GetInstitutions handles GET /api/belvo/institutions
+// GetInstitutions handles GET /api/belvo/institutions?country=&type=&resource=&status=&q=
+// from the cached institution list
 func (bh *BelvoHandler) GetInstitutions(ctx *gofr.Context) (interface{}, error) {
-	institutions, err := bh.belvoService.GetInstitutions()
+	filter := service.InstitutionFilter{
+		Country:  ctx.Param("country"),
+		Type:     ctx.Param("type"),
+		Resource: ctx.Param("resource"),
+		Status:   ctx.Param("status"),
+		Query:    ctx.Param("q"),
+	}
+
+	belvoService, err := bh.clients.For(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve institutions: %w", err)
+		return nil, err
+	}
+
+	catalog, err := belvoService.GetInstitutionCatalog(ctx, filter)
+	if err != nil {
+		return nil, belvoError(err, "failed to retrieve institutions")
 	}
 
 	return map[string]interface{}{
-		"institutions": institutions,
-		"count":        len(institutions),
+		"institutions": catalog.Institutions,
+		"count":        catalog.Count,
+		"total":        catalog.Total,
+		"cached_at":    catalog.CachedAt,
+		"expires_at":   catalog.ExpiresAt,
 	}, nil
 }
 
@@ -89,14 +111,9 @@ func (bh *BelvoHandler) CreateLink(ctx *gofr.Context) (interface{}, error) {
 		return nil, fmt.Errorf("institution, username, and password are required")
 	}
 
-	// Determine which Belvo service to use
-	var belvoService *service.BelvoService
-	if req.SecretID != "" && req.SecretKey != "" {
-		// Use user-provided credentials
-		belvoService = service.NewBelvoService(req.SecretID, req.SecretKey, "sandbox")
-	} else {
-		// Use default service credentials
-		belvoService = bh.belvoService
+	belvoService, err := bh.clients.For(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create link with additional parameters for Open Finance institutions
@@ -112,9 +129,9 @@ func (bh *BelvoHandler) CreateLink(ctx *gofr.Context) (interface{}, error) {
 		linkParams["username_type"] = req.UsernameType
 	}
 
-	link, err := belvoService.CreateLinkWithCustomParams(linkParams)
+	link, err := belvoService.CreateLinkWithCustomParams(ctx, linkParams)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create link: %w", err)
+		return nil, belvoError(err, "failed to create link")
 	}
 
 	return map[string]interface{}{
@@ -130,8 +147,6 @@ func (bh *BelvoHandler) CreateLinkWithCredentials(ctx *gofr.Context) (interface{
 		Username       string `json:"username"`
 		Password       string `json:"password"`
 		CredentialType string `json:"credential_type"` // "demo", "test", "custom"
-		SecretID       string `json:"secret_id,omitempty"`
-		SecretKey      string `json:"secret_key,omitempty"`
 	}
 
 	if err := ctx.Bind(&request); err != nil {
@@ -139,7 +154,8 @@ func (bh *BelvoHandler) CreateLinkWithCredentials(ctx *gofr.Context) (interface{
 	}
 
 	// Determine which credentials to use
-	var secretID, secretKey string
+	var tempBelvoService service.BelvoClient
+	var err error
 	switch request.CredentialType {
 	case "demo":
 		// Use mock data - no real Belvo call needed
@@ -149,19 +165,23 @@ func (bh *BelvoHandler) CreateLinkWithCredentials(ctx *gofr.Context) (interface{
 			"message": "Demo mode activated - using mock financial data",
 		}, nil
 	case "test":
-		// Use test credentials from environment
-		secretID = bh.testSecretID
-		secretKey = bh.testSecretKey
+		// Use test credentials from environment, only where the server shares its own
+		if !bh.clients.AllowsServerCredentials() {
+			return nil, serverCredentialsDisabled()
+		}
+		tempBelvoService, err = bh.clients.ForCredentials(models.RequestCredentials{
+			SecretID:  bh.testSecretID,
+			SecretKey: bh.testSecretKey,
+		})
 	case "custom":
-		// Use user-provided credentials
-		secretID = request.SecretID
-		secretKey = request.SecretKey
+		// Use the credentials the session was opened with
+		tempBelvoService, err = bh.clients.For(ctx)
 	default:
 		return nil, fmt.Errorf("invalid credential_type: %s", request.CredentialType)
 	}
-
-	// Create temporary Belvo service with appropriate credentials
-	tempBelvoService := service.NewBelvoService(secretID, secretKey, "sandbox")
+	if err != nil {
+		return nil, err
+	}
 
 	// Standard link creation for all institutions
 	linkRequest := map[string]interface{}{
@@ -171,9 +191,9 @@ func (bh *BelvoHandler) CreateLinkWithCredentials(ctx *gofr.Context) (interface{
 	}
 
 	// Create the link using the raw service call with custom parameters
-	link, err := tempBelvoService.CreateLinkWithCustomParams(linkRequest)
+	link, err := tempBelvoService.CreateLinkWithCustomParams(ctx, linkRequest)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Belvo link: %w", err)
+		return nil, belvoError(err, "failed to create Belvo link")
 	}
 
 	return map[string]interface{}{
@@ -186,122 +206,41 @@ func (bh *BelvoHandler) CreateLinkWithCredentials(ctx *gofr.Context) (interface{
 // GetConnectToken handles POST /api/belvo/connect-token to generate widget token
 func (bh *BelvoHandler) GetConnectToken(ctx *gofr.Context) (interface{}, error) {
 	var req struct {
-		Scopes    string `json:"scopes"`
-		SecretID  string `json:"secret_id"`
-		SecretKey string `json:"secret_key"`
+		Scopes string `json:"scopes"`
 	}
 	_ = ctx.Bind(&req)
 
-	// If client provided credentials, use them to generate a token; otherwise use default service
-	var token map[string]interface{}
-	var err error
-	if req.SecretID != "" && req.SecretKey != "" {
-		temp := service.NewBelvoService(req.SecretID, req.SecretKey, "sandbox")
-		token, err = temp.GenerateAccessToken(req.Scopes)
-	} else {
-		token, err = bh.belvoService.GenerateAccessToken(req.Scopes)
-	}
+	belvoService, err := bh.clients.For(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate connect token: %w", err)
+		return nil, err
 	}
 
-	return map[string]interface{}{
-		"token":   token,
-		"message": "Belvo Connect token generated",
-	}, nil
-}
-
-// GenerateOFDAWidgetTokenDirect handles POST /api/belvo/widget-token/direct - direct call to Belvo API
-func (bh *BelvoHandler) GenerateOFDAWidgetTokenDirect(ctx *gofr.Context) (interface{}, error) {
-	var req struct {
-		SecretID  string `json:"secret_id"`
-		SecretKey string `json:"secret_key"`
-	}
-	if err := ctx.Bind(&req); err != nil {
-		return nil, fmt.Errorf("invalid request body: %w", err)
-	}
-
-	if req.SecretID == "" || req.SecretKey == "" {
-		return nil, fmt.Errorf("secret_id and secret_key are required")
-	}
-
-	// Direct HTTP call to Belvo API (exactly like our working curl)
-
-	payload := map[string]interface{}{
-		"id":              req.SecretID,
-		"password":        req.SecretKey,
-		"scopes":          "read_institutions,write_links,read_consents,write_consents,write_consent_callback,delete_consents",
-		"stale_in":        "7d",
-		"fetch_resources": []string{"ACCOUNTS", "TRANSACTIONS", "OWNERS"},
-		"widget": map[string]interface{}{
-			"branding": map[string]interface{}{
-				"company_name": "AI Financial Coach",
-			},
-			"customer_id": "test-user",
-			"identification_info": []map[string]interface{}{
-				{
-					"type":  "CPF",
-					"value": "12345678901",
-				},
-			},
-			"permissions": []string{"REGISTER", "ACCOUNTS", "CREDIT_CARDS", "CREDIT_OPERATIONS"},
-			"callback_urls": map[string]interface{}{
-				"success": "http://localhost:3000/success",
-				"exit":    "http://localhost:3000/exit",
-				"event":   "http://localhost:3000/event",
-			},
-		},
-	}
-
-	jsonData, _ := json.Marshal(payload)
-
-	httpReq, _ := http.NewRequest("POST", "https://sandbox.belvo.com/api/token/", bytes.NewBuffer(jsonData))
-	auth := base64.StdEncoding.EncodeToString([]byte(req.SecretID + ":" + req.SecretKey))
-	httpReq.Header.Set("Authorization", "Basic "+auth)
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+	token, err := belvoService.GenerateAccessToken(ctx, req.Scopes)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("Belvo API error %d: %s", resp.StatusCode, string(body))
+		return nil, belvoError(err, "failed to generate connect token")
 	}
 
-	var result map[string]interface{}
-	json.Unmarshal(body, &result)
-
 	return map[string]interface{}{
-		"widget_token": result,
-		"widget_url":   fmt.Sprintf("https://widget.belvo.io/#/connect?token=%s", result["access"]),
-		"message":      "Direct OFDA widget token generated successfully",
+		"token":   token,
+		"message": "Belvo Connect token generated",
 	}, nil
 }
 
 // CreateEreborLink handles POST /api/belvo/create-erebor-link to create erebor_br_retail link with real data
 func (bh *BelvoHandler) CreateEreborLink(ctx *gofr.Context) (interface{}, error) {
 	var req struct {
-		SecretID  string `json:"secret_id"`
-		SecretKey string `json:"secret_key"`
-		Username  string `json:"username,omitempty"`
-		Password  string `json:"password,omitempty"`
+		Username string `json:"username,omitempty"`
+		Password string `json:"password,omitempty"`
 	}
 
 	if err := ctx.Bind(&req); err != nil {
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	// Create a dynamic Belvo service with user credentials
-	var belvoService *service.BelvoService
-	if req.SecretID != "" && req.SecretKey != "" {
-		belvoService = service.NewBelvoService(req.SecretID, req.SecretKey, "sandbox")
-	} else {
-		belvoService = bh.belvoService
+	// Create a dynamic Belvo service with the session's credentials
+	belvoService, err := bh.clients.For(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Use default sandbox credentials for erebor_br_retail (works without consent issues)
@@ -327,11 +266,11 @@ func (bh *BelvoHandler) CreateEreborLink(ctx *gofr.Context) (interface{}, error)
 	// Debug logging
 	fmt.Printf("🔗 Creating erebor_br_retail link with request: %+v\n", linkRequest)
 
-	link, err := belvoService.CreateLinkWithCustomParams(linkRequest)
+	link, err := belvoService.CreateLinkWithCustomParams(ctx, linkRequest)
 	if err != nil {
 		errMsg := fmt.Sprintf("%v", err)
 		fmt.Printf("❌ Belvo API Error: %s\n", errMsg)
-		return nil, fmt.Errorf("failed to create erebor_br_retail link: %w", err)
+		return nil, belvoError(err, "failed to create erebor_br_retail link")
 	}
 
 	fmt.Printf("✅ erebor_br_retail link created successfully: %s\n", link.ID)
@@ -346,97 +285,16 @@ func (bh *BelvoHandler) CreateEreborLink(ctx *gofr.Context) (interface{}, error)
 	}, nil
 }
 
-// GenerateOFDAWidgetToken - DEPRECATED: Widget approach not implemented due to incomplete Belvo documentation
-// See README.md for details on Open Finance limitations
-func (bh *BelvoHandler) GenerateOFDAWidgetToken(ctx *gofr.Context) (interface{}, error) {
-	var req struct {
-		SecretID    string `json:"secret_id"`
-		SecretKey   string `json:"secret_key"`
-		CustomerID  string `json:"customer_id,omitempty"`
-		CPF         string `json:"cpf,omitempty"`
-		CompanyName string `json:"company_name,omitempty"`
-	}
-	if err := ctx.Bind(&req); err != nil {
-		return nil, fmt.Errorf("invalid request body: %w", err)
-	}
-
-	// Validate required fields
-	if req.SecretID == "" || req.SecretKey == "" {
-		return nil, fmt.Errorf("secret_id and secret_key are required")
-	}
-
-	// Set defaults
-	if req.CustomerID == "" {
-		req.CustomerID = "ai-financial-coach-user"
-	}
-	if req.CPF == "" {
-		req.CPF = "12345678901" // Default test CPF
-	}
-	if req.CompanyName == "" {
-		req.CompanyName = "AI Financial Coach"
-	}
-
-	// Create the OFDA widget token request (matching the working direct API call format)
-	widgetRequest := map[string]interface{}{
-		"id":       req.SecretID,
-		"password": req.SecretKey,
-		"scopes":   "read_institutions,write_links,read_consents,write_consents,write_consent_callback,delete_consents",
-		"stale_in": "7d",
-		"fetch_resources": []string{
-			"ACCOUNTS",
-			"TRANSACTIONS",
-			"OWNERS",
-		},
-		"widget": map[string]interface{}{
-			"branding": map[string]interface{}{
-				"company_name": req.CompanyName,
-			},
-			"customer_id": req.CustomerID,
-			"identification_info": []map[string]interface{}{
-				{
-					"type":  "CPF",
-					"value": req.CPF,
-				},
-			},
-			"permissions": []string{
-				"REGISTER",
-				"ACCOUNTS",
-				"CREDIT_CARDS",
-				"CREDIT_OPERATIONS",
-			},
-			"callback_urls": map[string]interface{}{
-				"success": "http://localhost:3000/success",
-				"exit":    "http://localhost:3000/exit",
-				"event":   "http://localhost:3000/event",
-			},
-		},
-	}
-
-	// Debug: Log the request we're sending
-	fmt.Printf("🔧 Widget Request: %+v\n", widgetRequest)
-
-	// Create Belvo service with user credentials
-	belvoService := service.NewBelvoService(req.SecretID, req.SecretKey, "sandbox")
-
-	// Generate the widget token
-	token, err := belvoService.GenerateOFDAWidgetToken(widgetRequest)
+// GetLatestLink handles GET /api/belvo/links/latest - returns the most recent link id
+func (bh *BelvoHandler) GetLatestLink(ctx *gofr.Context) (interface{}, error) {
+	belvoService, err := bh.clients.For(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate OFDA widget token: %w", err)
+		return nil, err
 	}
 
-	return map[string]interface{}{
-		"widget_token": token,
-		"widget_url":   fmt.Sprintf("https://widget.belvo.io/#/connect?token=%s", token["access"]),
-		"message":      "OFDA widget token generated successfully for ofmockbank_br_retail",
-		"instructions": "Open the widget_url in a browser to complete the ofmockbank_br_retail connection",
-	}, nil
-}
-
-// GetLatestLink handles GET /api/belvo/links/latest - returns the most recent link id
-func (bh *BelvoHandler) GetLatestLink(ctx *gofr.Context) (interface{}, error) {
-	links, err := bh.belvoService.GetLinks()
+	links, err := belvoService.GetLinks(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch links: %w", err)
+		return nil, belvoError(err, "failed to fetch links")
 	}
 	if len(links) == 0 {
 		return map[string]interface{}{
@@ -454,43 +312,36 @@ func (bh *BelvoHandler) GetLatestLink(ctx *gofr.Context) (interface{}, error) {
 	}, nil
 }
 
-// GetLinksWithCredentials handles POST /api/belvo/links/with-credentials to get links using user credentials
+// GetLinksWithCredentials handles POST /api/belvo/links/with-credentials to get links using the session's credentials
 func (bh *BelvoHandler) GetLinksWithCredentials(ctx *gofr.Context) (interface{}, error) {
-	var req struct {
-		SecretID  string `json:"secret_id"`
-		SecretKey string `json:"secret_key"`
-	}
-	if err := ctx.Bind(&req); err != nil {
-		return nil, fmt.Errorf("invalid request body: %w", err)
-	}
-
-	// Validate credentials provided
-	if req.SecretID == "" || req.SecretKey == "" {
-		return nil, fmt.Errorf("secret_id and secret_key are required")
-	}
-
-	// Create temporary Belvo service with user credentials
-	belvoService := service.NewBelvoService(req.SecretID, req.SecretKey, "sandbox")
-
-	links, err := belvoService.GetLinks()
+	belvoService, err := bh.clients.For(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch links with user credentials: %w", err)
+		return nil, err
 	}
 
-	// Filter for erebor_br_retail links and check which ones have actual data
+	// Filter for erebor_br_retail links page by page and check which ones have actual data
+	var links []models.BelvoLink
 	var validLinksWithData []models.BelvoLink
 	var validLinksNoData []models.BelvoLink
 
-	for _, link := range links {
-		if link.Institution == "erebor_br_retail" && link.Status == "valid" {
-			// Check if this link has actual financial data
-			hasData := belvoService.CheckLinkHasData(link.ID)
-			if hasData {
-				validLinksWithData = append(validLinksWithData, link)
-			} else {
-				validLinksNoData = append(validLinksNoData, link)
+	iterator := belvoService.IterateLinks(ctx, service.DefaultPageOptions)
+	err = iterator.Each(func(page []models.BelvoLink) error {
+		links = append(links, page...)
+		for _, link := range page {
+			if link.Institution == "erebor_br_retail" && link.Status == "valid" {
+				// Check if this link has actual financial data
+				hasData := belvoService.CheckLinkHasData(ctx, link.ID)
+				if hasData {
+					validLinksWithData = append(validLinksWithData, link)
+				} else {
+					validLinksNoData = append(validLinksNoData, link)
+				}
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, belvoError(err, "failed to fetch links with user credentials")
 	}
 
 	return map[string]interface{}{
@@ -501,39 +352,38 @@ func (bh *BelvoHandler) GetLinksWithCredentials(ctx *gofr.Context) (interface{},
 		"links_without_data_count": len(validLinksNoData),
 		"total_count":              len(links),
 		"has_data_available":       len(validLinksWithData) > 0,
-		"message":                  "Links retrieved and data availability checked",
+		"truncated":                iterator.Truncated(),
+		"message":                  linksMessage("Links retrieved and data availability checked", iterator),
 	}, nil
 }
 
 // GetLinksForSelection handles POST /api/belvo/links/for-selection
 func (bh *BelvoHandler) GetLinksForSelection(ctx *gofr.Context) (interface{}, error) {
 	var req struct {
-		SecretID  string `json:"secret_id"`
-		SecretKey string `json:"secret_key"`
-	}
-
-	if err := ctx.Bind(&req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
-	}
-
-	if req.SecretID == "" || req.SecretKey == "" {
-		return nil, fmt.Errorf("secret_id and secret_key are required")
+		PageSize int `json:"page_size,omitempty"`
+		MaxPages int `json:"max_pages,omitempty"`
 	}
+	_ = ctx.Bind(&req) // Paging options are optional
 
-	// Create dynamic Belvo service with user credentials
-	belvoService := service.NewBelvoService(req.SecretID, req.SecretKey, "sandbox")
-
-	// Get all links
-	links, err := belvoService.GetLinks()
+	// Create dynamic Belvo service with the session's credentials
+	belvoService, err := bh.clients.For(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve links: %w", err)
+		return nil, err
 	}
 
+	// Walk every page of links so large secrets aren't cut off at the first page
+	iterator := belvoService.IterateLinks(ctx, service.PageOptions{PageSize: req.PageSize, MaxPages: req.MaxPages})
+
 	var basicLinks []map[string]interface{}
+	customerNumber := 0
+
+	err = iterator.Each(func(page []models.BelvoLink) error {
+		for _, link := range page {
+			customerNumber++
+			if link.Status != "valid" {
+				continue
+			}
 
-	for i, link := range links {
-		if link.Status == "valid" {
-			customerNumber := i + 1
 			displayName := fmt.Sprintf("Customer Account #%d", customerNumber)
 
 			basicLinks = append(basicLinks, map[string]interface{}{
@@ -546,16 +396,32 @@ func (bh *BelvoHandler) GetLinksForSelection(ctx *gofr.Context) (interface{}, er
 				"short_id":        link.ID[:8] + "...",
 			})
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, belvoError(err, "failed to retrieve links")
 	}
 
 	return map[string]interface{}{
 		"links":        basicLinks,
 		"total_count":  len(basicLinks),
-		"message":      "Customer links retrieved instantly",
+		"pages_read":   iterator.PagesRead(),
+		"skipped":      iterator.Skipped(),
+		"total_links":  iterator.TotalCount(),
+		"truncated":    iterator.Truncated(),
+		"message":      linksMessage("Customer links retrieved instantly", iterator),
 		"loading_type": "instant",
 	}, nil
 }
 
+// linksMessage is message, or a warning when the page limit left links out
+func linksMessage(message string, iterator *service.PageIterator[models.BelvoLink]) string {
+	if !iterator.Truncated() {
+		return message
+	}
+	return fmt.Sprintf("Only the first %d pages of links were read; Belvo reports %d links", iterator.PagesRead(), iterator.TotalCount())
+}
+
 // GetDetailedLinkInfo handles POST /api/belvo/links/detailed-info/{link_id} - DETAILED loading for selected customer
 func (bh *BelvoHandler) GetDetailedLinkInfo(ctx *gofr.Context) (interface{}, error) {
 	linkID := ctx.PathParam("link_id")
@@ -564,72 +430,90 @@ func (bh *BelvoHandler) GetDetailedLinkInfo(ctx *gofr.Context) (interface{}, err
 	}
 
 	var req struct {
-		SecretID  string `json:"secret_id"`
-		SecretKey string `json:"secret_key"`
+		LookbackMonths    int    `json:"lookback_months"`
+		ReportingCurrency string `json:"reporting_currency"`
 	}
+	_ = ctx.Bind(&req) // Summary options are optional
 
-	if err := ctx.Bind(&req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+	opts, err := summaryOptions(req.LookbackMonths, req.ReportingCurrency)
+	if err != nil {
+		return nil, err
 	}
 
-	if req.SecretID == "" || req.SecretKey == "" {
-		return nil, fmt.Errorf("secret_id and secret_key are required")
+	// Create dynamic Belvo service with the session's credentials
+	belvoService, err := bh.clients.For(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create dynamic Belvo service with user credentials
-	belvoService := service.NewBelvoService(req.SecretID, req.SecretKey, "sandbox")
-
 	// 🚀 PARALLEL DATA FETCHING for maximum speed
 	type fetchResult struct {
-		owners       []models.BelvoOwner
-		accounts     []models.BelvoAccount
-		transactions []models.BelvoTransaction
-		summary      *models.FinancialSummary
-		ownerErr     error
-		accountErr   error
-		transErr     error
-		summaryErr   error
+		owners            []models.BelvoOwner
+		accounts          []models.BelvoAccount
+		transactions      []models.BelvoTransaction
+		incomes           []models.BelvoIncome
+		recurringExpenses []models.BelvoRecurringExpense
+		investments       []models.BelvoInvestmentPortfolio
+		loans             []models.BelvoLoan
+		ownerErr          error
+		accountErr        error
+		transErr          error
+		incomeErr         error
+		recurringErr      error
+		investmentErr     error
+		loanErr           error
 	}
 
 	result := &fetchResult{}
-	done := make(chan bool, 4)
+	const fetches = 7
+	done := make(chan bool, fetches)
 
 	// Parallel fetch 1: Owner info
 	go func() {
 		defer func() { done <- true }()
-		result.owners, result.ownerErr = belvoService.GetOwners(linkID)
+		result.owners, result.ownerErr = belvoService.GetOwners(ctx, linkID)
 	}()
 
 	// Parallel fetch 2: Accounts
 	go func() {
 		defer func() { done <- true }()
-		result.accounts, result.accountErr = belvoService.GetAccounts(linkID)
+		result.accounts, result.accountErr = belvoService.GetAccounts(ctx, linkID)
 	}()
 
-	// Parallel fetch 3: Recent transactions (last 3 months for speed)
+	// Parallel fetch 3: Recent transactions over the lookback window (3 months by default)
+	dateTo := time.Now()
+	dateFrom := dateTo.AddDate(0, -opts.LookbackMonths, 0)
 	go func() {
 		defer func() { done <- true }()
-		now := time.Now()
-		dateFrom := now.AddDate(0, -3, 0)
-		dateTo := now
-		result.transactions, result.transErr = belvoService.GetTransactions(linkID, &dateFrom, &dateTo)
+		result.transactions, result.transErr = belvoService.GetStoredTransactions(ctx, linkID, &dateFrom, &dateTo)
 	}()
 
-	// Parallel fetch 4: Incomes (for financial summary calculation)
+	// Parallel fetches 4-7: the rest of what the financial summary is built from
 	go func() {
 		defer func() { done <- true }()
-		incomes, err := belvoService.GetIncomes(linkID)
-		if err != nil {
-			result.summaryErr = err
-		} else {
-			result.summary = &models.FinancialSummary{}
-			result.summary.IncomeStreams = incomes
-		}
+		result.incomes, result.incomeErr = belvoService.GetIncomes(ctx, linkID)
+	}()
+	go func() {
+		defer func() { done <- true }()
+		result.recurringExpenses, result.recurringErr = belvoService.GetRecurringExpenses(ctx, linkID)
+	}()
+	go func() {
+		defer func() { done <- true }()
+		result.investments, result.investmentErr = belvoService.GetInvestmentPortfolios(ctx, linkID)
+	}()
+	go func() {
+		defer func() { done <- true }()
+		result.loans, result.loanErr = belvoService.GetLoans(ctx, linkID)
 	}()
 
-	// Wait for all parallel fetches to complete
-	for i := 0; i < 4; i++ {
-		<-done
+	// Wait for all parallel fetches to complete. The fetches share ctx, so a client
+	// disconnect or route deadline stops them too and we return straight away.
+	for i := 0; i < fetches; i++ {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, belvoError(ctx.Err(), "detailed link info cancelled")
+		}
 	}
 
 	// Process owner info
@@ -646,12 +530,6 @@ func (bh *BelvoHandler) GetDetailedLinkInfo(ctx *gofr.Context) (interface{}, err
 		accounts = []models.BelvoAccount{}
 	}
 
-	// Calculate total balance
-	totalBalance := 0.0
-	for _, account := range accounts {
-		totalBalance += account.Balance.Available
-	}
-
 	// Handle transactions
 	transactions := result.transactions
 	if result.transErr != nil {
@@ -661,20 +539,40 @@ func (bh *BelvoHandler) GetDetailedLinkInfo(ctx *gofr.Context) (interface{}, err
 	// Check data availability
 	hasData := len(accounts) > 0 || len(transactions) > 0
 
-	// Build financial summary from already-fetched data (no duplicate API calls)
-	var financialSummary *models.FinancialSummary
-	if result.summaryErr != nil {
-		financialSummary = createBasicFinancialSummary(linkID, accounts, transactions)
-	} else {
-		financialSummary = createFinancialSummaryFromData(linkID, accounts, transactions, result.summary.IncomeStreams)
+	// Build the summary once from what was fetched, through the same engine chat
+	// context uses. Whatever failed to load is left out rather than failing the request.
+	for name, err := range map[string]error{
+		"incomes":               result.incomeErr,
+		"recurring expenses":    result.recurringErr,
+		"investment portfolios": result.investmentErr,
+		"loans":                 result.loanErr,
+	} {
+		if err != nil {
+			fmt.Printf("⚠️ No %s for link %s, summary built without them: %v\n", name, linkID, err)
+		}
 	}
+	summaryInput := service.SummaryInput{
+		UserID:            linkID,
+		LookbackMonths:    opts.LookbackMonths,
+		From:              dateFrom,
+		To:                dateTo,
+		Accounts:          accounts,
+		Transactions:      transactions,
+		Incomes:           result.incomes,
+		RecurringExpenses: result.recurringExpenses,
+		Investments:       result.investments,
+		Loans:             result.loans,
+		ReportingCurrency: opts.ReportingCurrency,
+	}
+	if err := service.LoadFXRates(ctx, &summaryInput); err != nil {
+		fmt.Printf("⚠️ Summary for link %s keeps foreign amounts unconverted: %v\n", linkID, err)
+	}
+	financialSummary := service.DefaultSummaryEngine.Build(summaryInput)
+	totalBalance := financialSummary.TotalBalance
 
 	// Pre-generate comprehensive AI context summary for instant responses
-	var monthlyIncome, monthlyExpenses float64
-	if financialSummary != nil {
-		monthlyIncome = financialSummary.MonthlyIncome
-		monthlyExpenses = financialSummary.MonthlyVariableExpenses
-	}
+	monthlyIncome := financialSummary.MonthlyIncome
+	monthlyExpenses := financialSummary.MonthlyFixedExpenses + financialSummary.MonthlyVariableExpenses
 
 	// Categorize accounts by type
 	accountCategories := make(map[string]int)
@@ -692,11 +590,12 @@ func (bh *BelvoHandler) GetDetailedLinkInfo(ctx *gofr.Context) (interface{}, err
 		})
 	}
 
+	currency := financialSummary.Currency
 	aiContextSummary := fmt.Sprintf(
-		"Customer: %s | Link: %s | Total Balance: %.2f BRL | Accounts: %d (%s) | Transactions: %d | Monthly Income: %.2f BRL | Monthly Expenses: %.2f BRL | Net Flow: %.2f BRL",
-		ownerName, linkID[:8], totalBalance, len(accounts),
+		"Customer: %s | Link: %s | Total Balance: %.2f %s | Liabilities: %.2f %s | Net Worth: %.2f %s | Accounts: %d (%s) | Transactions: %d | Monthly Income: %.2f %s | Monthly Expenses: %.2f %s | Net Flow: %.2f %s",
+		ownerName, linkID[:8], totalBalance, currency, financialSummary.TotalLiabilities, currency, financialSummary.NetWorth, currency, len(accounts),
 		fmt.Sprintf("%v", accountCategories), len(transactions),
-		monthlyIncome, monthlyExpenses, monthlyIncome-monthlyExpenses,
+		monthlyIncome, currency, monthlyExpenses, currency, monthlyIncome-monthlyExpenses, currency,
 	)
 
 	return map[string]interface{}{
@@ -709,7 +608,10 @@ func (bh *BelvoHandler) GetDetailedLinkInfo(ctx *gofr.Context) (interface{}, err
 		"transaction_count":    len(transactions),
 		"recent_transactions":  transactions,
 		"total_balance":        totalBalance,
-		"currency":             "BRL",
+		"total_liabilities":    financialSummary.TotalLiabilities,
+		"available_credit":     financialSummary.AvailableCredit,
+		"net_worth":            financialSummary.NetWorth,
+		"currency":             currency,
 		"has_data":             hasData,
 		"financial_summary":    financialSummary,
 		"ai_context_summary":   aiContextSummary,
@@ -734,11 +636,15 @@ func verifyBalanceCalculation(accounts []models.BelvoAccount, calculatedTotal fl
 	balances := make([]map[string]interface{}, len(accounts))
 
 	for i, account := range accounts {
-		recalculated += account.Balance.Available
+		class := service.ClassifyAccount(account)
+		if class == service.AccountClassLiquid {
+			recalculated += account.Balance.Current
+		}
 		balances[i] = map[string]interface{}{
 			"account_id": account.ID[:8] + "...",
 			"name":       account.Name,
-			"balance":    account.Balance.Available,
+			"class":      class,
+			"balance":    account.Balance.Current,
 		}
 	}
 
@@ -765,70 +671,6 @@ func getSampleTransaction(transactions []models.BelvoTransaction) map[string]int
 	}
 }
 
-// createFinancialSummaryFromData builds financial summary from already-fetched data
-func createFinancialSummaryFromData(linkID string, accounts []models.BelvoAccount, transactions []models.BelvoTransaction, incomes []models.BelvoIncome) *models.FinancialSummary {
-	// Calculate total balance
-	totalBalance := 0.0
-	for _, account := range accounts {
-		totalBalance += account.Balance.Available
-	}
-
-	// Calculate monthly income from income streams
-	monthlyIncome := 0.0
-	for _, income := range incomes {
-		monthlyIncome += income.MonthlyAverage
-	}
-
-	// Calculate income and expenses from transactions
-	totalInflow := 0.0
-	totalOutflow := 0.0
-	for _, transaction := range transactions {
-		if transaction.Type == "INFLOW" {
-			totalInflow += transaction.Amount
-		} else if transaction.Type == "OUTFLOW" {
-			totalOutflow += transaction.Amount
-		}
-	}
-
-	// Convert to monthly averages (transactions are from last 3 months)
-	monthsOfData := 3.0
-	monthlyIncomeFromTransactions := totalInflow / monthsOfData
-	monthlyExpensesFromTransactions := totalOutflow / monthsOfData
-
-	// Use transaction-based income if no formal income streams found
-	if monthlyIncome == 0 {
-		monthlyIncome = monthlyIncomeFromTransactions
-	}
-
-	monthlyVariableExpenses := monthlyExpensesFromTransactions
-	monthlySurplus := monthlyIncome - monthlyVariableExpenses
-
-	currency := "BRL"
-	if len(accounts) > 0 {
-		currency = accounts[0].Currency
-	}
-
-	return &models.FinancialSummary{
-		UserID:                  linkID,
-		GeneratedAt:             time.Now(),
-		MonthlyIncome:           monthlyIncome,
-		MonthlyFixedExpenses:    0.0,
-		MonthlyVariableExpenses: monthlyVariableExpenses,
-		MonthlySurplus:          monthlySurplus,
-		TotalBalance:            totalBalance,
-		Accounts:                accounts,
-		RecentTransactions:      transactions,
-		IncomeStreams:           incomes,
-		RecurringExpenses:       []models.BelvoRecurringExpense{},
-		Currency:                currency,
-	}
-}
-
-// createBasicFinancialSummary creates a basic summary when income data fails
-func createBasicFinancialSummary(linkID string, accounts []models.BelvoAccount, transactions []models.BelvoTransaction) *models.FinancialSummary {
-	return createFinancialSummaryFromData(linkID, accounts, transactions, []models.BelvoIncome{})
-}
-
 // VerifyLinkData handles POST /api/belvo/verify-data/{link_id} - Comprehensive data verification
 func (bh *BelvoHandler) VerifyLinkData(ctx *gofr.Context) (interface{}, error) {
 	linkID := ctx.PathParam("link_id")
@@ -836,33 +678,23 @@ func (bh *BelvoHandler) VerifyLinkData(ctx *gofr.Context) (interface{}, error) {
 		return nil, fmt.Errorf("link_id parameter is required")
 	}
 
-	var req struct {
-		SecretID  string `json:"secret_id"`
-		SecretKey string `json:"secret_key"`
-	}
-
-	if err := ctx.Bind(&req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
-	}
-
-	if req.SecretID == "" || req.SecretKey == "" {
-		return nil, fmt.Errorf("secret_id and secret_key are required")
+	// Create dynamic Belvo service with the session's credentials
+	belvoService, err := bh.clients.For(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create dynamic Belvo service with user credentials
-	belvoService := service.NewBelvoService(req.SecretID, req.SecretKey, "sandbox")
-
 	fmt.Printf("🔍 VERIFYING data integrity for link: %s\n", linkID)
 
 	// Get fresh data directly from Belvo for verification
-	owners, ownerErr := belvoService.GetOwners(linkID)
-	accounts, accountErr := belvoService.GetAccounts(linkID)
+	owners, ownerErr := belvoService.GetOwners(ctx, linkID)
+	accounts, accountErr := belvoService.GetAccounts(ctx, linkID)
 
 	// Get a small sample of transactions for verification
 	now := time.Now()
 	sampleFrom := now.AddDate(0, 0, -7) // Last week only for quick verification
 	sampleTo := now
-	sampleTransactions, transErr := belvoService.GetTransactions(linkID, &sampleFrom, &sampleTo)
+	sampleTransactions, transErr := belvoService.GetTransactions(ctx, linkID, &sampleFrom, &sampleTo)
 
 	verification := map[string]interface{}{
 		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
@@ -878,6 +710,7 @@ func (bh *BelvoHandler) VerifyLinkData(ctx *gofr.Context) (interface{}, error) {
 			"ids":           extractAccountIDs(accounts),
 			"names":         extractAccountNames(accounts),
 			"total_balance": calculateTotalBalance(accounts),
+			"balances":      service.SummarizeBalances(accounts),
 		},
 		"sample_transactions": map[string]interface{}{
 			"count":      len(sampleTransactions),
@@ -914,12 +747,9 @@ func extractAccountNames(accounts []models.BelvoAccount) []string {
 	return names
 }
 
+// calculateTotalBalance returns the checking and savings balances, leaving out card limits and debt
 func calculateTotalBalance(accounts []models.BelvoAccount) float64 {
-	var total float64
-	for _, account := range accounts {
-		total += account.Balance.Available
-	}
-	return total
+	return service.SummarizeBalances(accounts).LiquidAssets
 }
 
 // GetAccounts handles GET /api/belvo/accounts
@@ -929,9 +759,14 @@ func (bh *BelvoHandler) GetAccounts(ctx *gofr.Context) (interface{}, error) {
 		return nil, fmt.Errorf("link_id parameter is required")
 	}
 
-	accounts, err := bh.belvoService.GetAccounts(linkID)
+	belvoService, err := bh.clients.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := belvoService.GetAccounts(ctx, linkID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve accounts: %w", err)
+		return nil, belvoError(err, "failed to retrieve accounts")
 	}
 
 	return map[string]interface{}{
@@ -948,10 +783,15 @@ func (bh *BelvoHandler) GetTransactions(ctx *gofr.Context) (interface{}, error)
 		return nil, fmt.Errorf("link_id parameter is required")
 	}
 
+	belvoService, err := bh.clients.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Optional date filters can be added here
-	transactions, err := bh.belvoService.GetTransactions(linkID, nil, nil)
+	transactions, err := belvoService.GetTransactions(ctx, linkID, nil, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve transactions: %w", err)
+		return nil, belvoError(err, "failed to retrieve transactions")
 	}
 
 	return map[string]interface{}{
@@ -961,20 +801,26 @@ func (bh *BelvoHandler) GetTransactions(ctx *gofr.Context) (interface{}, error)
 	}, nil
 }
 
-// GetFinancialSummary handles GET /api/belvo/financial-summary
+// GetFinancialSummary handles GET /api/belvo/financial-summary/{link_id}?lookback_months=&reporting_currency=
 func (bh *BelvoHandler) GetFinancialSummary(ctx *gofr.Context) (interface{}, error) {
 	linkID := ctx.PathParam("link_id")
 	if linkID == "" {
 		return nil, fmt.Errorf("link_id parameter is required")
 	}
 
-	// For now, use default service credentials
-	// TODO: Add proper credential handling via request context
-	belvoService := bh.belvoService
+	opts, err := querySummaryOptions(ctx.Param("lookback_months"), ctx.Param("reporting_currency"))
+	if err != nil {
+		return nil, err
+	}
+
+	belvoService, err := bh.clients.For(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	summary, err := belvoService.GetFinancialSummary(linkID)
+	summary, err := belvoService.GetFinancialSummary(ctx, linkID, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate financial summary: %w", err)
+		return nil, belvoError(err, "failed to generate financial summary")
 	}
 
 	return map[string]interface{}{
@@ -987,9 +833,9 @@ func (bh *BelvoHandler) GetFinancialSummary(ctx *gofr.Context) (interface{}, err
 // GetFinancialSummaryWithCredentials handles POST /api/belvo/financial-summary/with-credentials
 func (bh *BelvoHandler) GetFinancialSummaryWithCredentials(ctx *gofr.Context) (interface{}, error) {
 	var req struct {
-		LinkID    string `json:"link_id"`
-		SecretID  string `json:"secret_id"`
-		SecretKey string `json:"secret_key"`
+		LinkID            string `json:"link_id"`
+		LookbackMonths    int    `json:"lookback_months"`
+		ReportingCurrency string `json:"reporting_currency"`
 	}
 	if err := ctx.Bind(&req); err != nil {
 		return nil, fmt.Errorf("invalid request body: %w", err)
@@ -999,23 +845,68 @@ func (bh *BelvoHandler) GetFinancialSummaryWithCredentials(ctx *gofr.Context) (i
 	if req.LinkID == "" {
 		return nil, fmt.Errorf("link_id is required")
 	}
-	if req.SecretID == "" || req.SecretKey == "" {
-		return nil, fmt.Errorf("secret_id and secret_key are required")
+	opts, err := summaryOptions(req.LookbackMonths, req.ReportingCurrency)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create Belvo service with user credentials
-	belvoService := service.NewBelvoService(req.SecretID, req.SecretKey, "sandbox")
+	// Create Belvo service with the session's credentials
+	belvoService, err := bh.clients.For(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	summary, err := belvoService.GetFinancialSummary(req.LinkID)
+	summary, err := belvoService.GetFinancialSummary(ctx, req.LinkID, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate financial summary: %w", err)
+		return nil, belvoError(err, "failed to generate financial summary")
 	}
 
 	return map[string]interface{}{
 		"financial_summary": summary,
 		"link_id":           req.LinkID,
-		"credential_source": "user-provided",
-		"message":           "Financial summary generated successfully with user credentials",
+		"credential_source": "session",
+		"message":           "Financial summary generated successfully with session credentials",
+	}, nil
+}
+
+// ConsolidatedSummaryRequest represents the request body for a multi-link financial summary
+type ConsolidatedSummaryRequest struct {
+	LinkIDs           []string `json:"link_ids"`
+	LookbackMonths    int      `json:"lookback_months"`    // Optional, defaults to 3
+	ReportingCurrency string   `json:"reporting_currency"` // Optional, defaults to the first account's currency
+}
+
+// GetConsolidatedFinancialSummary handles POST /api/belvo/financial-summary/consolidated
+func (bh *BelvoHandler) GetConsolidatedFinancialSummary(ctx *gofr.Context) (interface{}, error) {
+	var req ConsolidatedSummaryRequest
+	if err := ctx.Bind(&req); err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+
+	linkIDs := service.NormalizeLinkIDs(req.LinkIDs)
+	if len(linkIDs) == 0 {
+		return nil, fmt.Errorf("link_ids must contain at least one link ID")
+	}
+	opts, err := summaryOptions(req.LookbackMonths, req.ReportingCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	belvoService, err := bh.clients.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := belvoService.GetConsolidatedFinancialSummary(ctx, linkIDs, opts)
+	if err != nil {
+		return nil, belvoError(err, "failed to generate consolidated financial summary")
+	}
+
+	return map[string]interface{}{
+		"financial_summary":  summary,
+		"link_ids":           linkIDs,
+		"transfers_excluded": summary.TransfersExcluded,
+		"message":            fmt.Sprintf("Consolidated financial summary generated for %d links", len(linkIDs)),
 	}, nil
 }
 
@@ -1030,6 +921,7 @@ func (bh *BelvoHandler) GetMockData(ctx *gofr.Context) (interface{}, error) {
 		MonthlyVariableExpenses: 2800.00,
 		MonthlySurplus:          2500.00,
 		TotalBalance:            67355.41,
+		NetWorth:                67355.41,
 		Currency:                "BRL",
 		Accounts: []models.BelvoAccount{
 			{
@@ -1060,29 +952,20 @@ func (bh *BelvoHandler) GetMockData(ctx *gofr.Context) (interface{}, error) {
 
 // TestConnection handles POST /api/belvo/test-connection
 func (bh *BelvoHandler) TestConnection(ctx *gofr.Context) (interface{}, error) {
-	var req struct {
-		SecretID  string `json:"secret_id,omitempty"`
-		SecretKey string `json:"secret_key,omitempty"`
+	// Sessions are only issued for credentials that worked at login; this checks they still do
+	credentialSource := "default"
+	if _, ok := SessionFrom(ctx); ok {
+		credentialSource = "session"
 	}
 
-	// Try to bind request body - if it fails, use default credentials
-	_ = ctx.Bind(&req)
-
-	// Determine which Belvo service to use
-	var belvoService *service.BelvoService
-	var credentialSource string
-
-	if req.SecretID != "" && req.SecretKey != "" {
-		// Use user-provided credentials
-		belvoService = service.NewBelvoService(req.SecretID, req.SecretKey, "sandbox")
-		credentialSource = "user-provided"
-	} else {
-		// Use default service credentials
-		belvoService = bh.belvoService
-		credentialSource = "default"
+	belvoService, err := bh.clients.For(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	institutions, err := belvoService.GetInstitutions()
+	// A one-institution page is enough to prove the credentials work
+	started := time.Now()
+	institutionsCount, err := belvoService.Ping(ctx)
 	if err != nil {
 		return map[string]interface{}{
 			"status":            "failed",
@@ -1097,13 +980,19 @@ func (bh *BelvoHandler) TestConnection(ctx *gofr.Context) (interface{}, error) {
 		"status":             "success",
 		"message":            "Successfully connected to Belvo API",
 		"connected":          true,
-		"institutions_count": len(institutions),
+		"institutions_count": institutionsCount,
+		"latency_ms":         time.Since(started).Milliseconds(),
 		"environment":        belvoService.GetEnvironment(),
 		"credential_source":  credentialSource,
 	}, nil
 }
 
 // GetBelvoService returns the belvo service instance
-func (bh *BelvoHandler) GetBelvoService() *service.BelvoService {
-	return bh.belvoService
+func (bh *BelvoHandler) GetBelvoService() service.BelvoClient {
+	return bh.clients.Default()
+}
+
+// GetClients returns the resolver for per-request Belvo credentials
+func (bh *BelvoHandler) GetClients() *BelvoClients {
+	return bh.clients
 }
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gofr.dev/pkg/gofr"
+
+	"ai-financial-coach/internal/models"
+)
+
+const chatStreamPath = "/api/ai/chat/stream"
+
+// ChatStreamMiddleware serves POST /api/ai/chat/stream as Server-Sent Events: a "delta"
+// event per piece of the answer as the model generates it, then a "done" event with
+// the whole ChatResponse (conversation ID, show_dashboard, usage). The request body is
+// the same as for POST /api/ai/chat. gofr handlers buffer their whole response, so the
+// stream is served from the middleware chain instead of a route.
+func ChatStreamMiddleware(ah *AIHandler) func(http.Handler) http.Handler {
+	return func(inner http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.URL.Path != chatStreamPath {
+				inner.ServeHTTP(w, r)
+				return
+			}
+			streamChat(w, r, ah)
+		})
+	}
+}
+
+// ChatStreamRoute is registered for POST /api/ai/chat/stream. gofr only runs middleware
+// for requests that match a route, so the route must exist even though
+// ChatStreamMiddleware answers before this handler is reached.
+func ChatStreamRoute(ctx *gofr.Context) (interface{}, error) {
+	return nil, &APIError{
+		Status:  http.StatusInternalServerError,
+		Code:    "stream_unavailable",
+		Message: "chat streaming requires ChatStreamMiddleware",
+	}
+}
+
+// streamChat writes the event stream. Errors before the first event get a normal error
+// response; later ones end the stream with an "error" event, since the status has
+// already been sent.
+func streamChat(w http.ResponseWriter, r *http.Request, ah *AIHandler) {
+	var request models.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeAPIError(w, invalidParam("body", fmt.Sprintf("must be a chat request: %v", err)))
+		return
+	}
+	if request.Message == "" {
+		writeAPIError(w, invalidParam("message", "is required"))
+		return
+	}
+
+	ctx := r.Context()
+	isNew, err := ah.loadConversation(ctx, &request)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	if err := ah.prepareChat(ctx, &request); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	started := false
+	send := func(event string, data interface{}) error {
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+			w.Header().Set("X-Accel-Buffering", "no") // Keep proxies such as nginx from buffering the stream
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		if err := writeSSE(w, event, data); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	response, err := ah.aiService.ChatStream(ctx, &request, func(delta string) error {
+		return send("delta", map[string]string{"content": delta})
+	})
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			fmt.Printf("🔌 Chat stream closed by the client: %v\n", ctx.Err())
+			return
+		}
+		// The route's deadline still has a client waiting, so it gets told, whatever
+		// error the provider surfaced it as
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = ctx.Err()
+		}
+		err = aiError("generate AI chat response", err)
+		fmt.Printf("❌ Chat stream failed: %v\n", err)
+		if !started {
+			writeAPIError(w, err)
+			return
+		}
+		_ = send("error", errorEnvelope(err))
+		return
+	}
+
+	if err := ah.saveConversation(ctx, &request, response, isNew); err != nil {
+		_ = send("error", errorEnvelope(err))
+		return
+	}
+	_ = send("done", response)
+}
+
+// writeSSE writes one event; data is JSON-encoded on a single line
+func writeSSE(w http.ResponseWriter, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event, err)
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
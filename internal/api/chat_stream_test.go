@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-financial-coach/internal/service"
+)
+
+// stallingWriter holds the first write until ctx ends, as a slow model would
+type stallingWriter struct {
+	*httptest.ResponseRecorder
+	ctx     context.Context
+	stalled bool
+}
+
+func (w *stallingWriter) Write(p []byte) (int, error) {
+	if !w.stalled {
+		w.stalled = true
+		<-w.ctx.Done()
+	}
+	return w.ResponseRecorder.Write(p)
+}
+
+// newMockChatHandler returns a handler that answers chats from the mock replies, with
+// no model, Belvo or market data behind it
+func newMockChatHandler() *AIHandler {
+	clients := NewBelvoClients(nil, nil, service.EnvironmentSandbox)
+	return NewAIHandler(nil, clients, service.NewMarketService(), service.NewMemoryConversationStore())
+}
+
+func chatStreamRequest(ctx context.Context) *http.Request {
+	body := `{"message":"how should I invest my savings?","language":"en","user_context":{"monthly_income":1000},"market_context":{}}`
+	return httptest.NewRequest(http.MethodPost, chatStreamPath, strings.NewReader(body)).WithContext(ctx)
+}
+
+func TestStreamChatReportsDeadline(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       func() (context.Context, context.CancelFunc)
+		stall     bool
+		wantCode  int
+		wantError bool // Whether the stream ends with an "error" event
+	}{
+		{
+			name: "deadline before the first event",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+			},
+			wantCode: http.StatusGatewayTimeout,
+		},
+		{
+			name: "deadline mid-stream",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+			stall:     true,
+			wantCode:  http.StatusOK,
+			wantError: true,
+		},
+		{
+			name: "client closes mid-stream",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			stall:    true,
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			recorder := httptest.NewRecorder()
+			var w http.ResponseWriter = recorder
+			if tt.stall {
+				w = &stallingWriter{ResponseRecorder: recorder, ctx: ctx}
+			}
+			streamChat(w, chatStreamRequest(ctx), newMockChatHandler())
+
+			if recorder.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantCode)
+			}
+			body := recorder.Body.String()
+			if tt.wantCode != http.StatusOK {
+				var response struct {
+					Error struct {
+						Code string `json:"code"`
+					} `json:"error"`
+				}
+				if err := json.Unmarshal([]byte(body), &response); err != nil || response.Error.Code != "timeout" {
+					t.Errorf("body = %s, want a timeout error", body)
+				}
+				return
+			}
+
+			if !strings.Contains(body, "event: delta\n") {
+				t.Errorf("body = %q, want a delta event before the stream ended", body)
+			}
+			if got := strings.Contains(body, "event: error\n") && strings.Contains(body, `"code":"timeout"`); got != tt.wantError {
+				t.Errorf("timeout error event = %v, want %v in %q", got, tt.wantError, body)
+			}
+			if strings.Contains(body, "event: done\n") {
+				t.Errorf("body = %q, want no done event", body)
+			}
+		})
+	}
+}
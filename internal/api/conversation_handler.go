@@ -0,0 +1,205 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gofr.dev/pkg/gofr"
+
+	"ai-financial-coach/internal/models"
+	"ai-financial-coach/internal/service"
+)
+
+// maxConversationTitle is the longest title, in characters
+const maxConversationTitle = 200
+
+// ConversationHandler serves the chat conversations stored on the server
+type ConversationHandler struct {
+	store service.ConversationStore
+}
+
+// NewConversationHandler creates a new ConversationHandler
+func NewConversationHandler(store service.ConversationStore) *ConversationHandler {
+	return &ConversationHandler{store: store}
+}
+
+// ListConversations handles GET /api/ai/conversations, most recently updated first
+func (ch *ConversationHandler) ListConversations(ctx *gofr.Context) (interface{}, error) {
+	owner, err := requireSessionOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conversations, err := ch.store.List(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"conversations": conversations,
+		"count":         len(conversations),
+	}, nil
+}
+
+// GetConversation handles GET /api/ai/conversations/{conversation_id} with its messages
+func (ch *ConversationHandler) GetConversation(ctx *gofr.Context) (interface{}, error) {
+	owner, err := requireSessionOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conversation, err := ch.store.Get(ctx, owner, ctx.PathParam("conversation_id"))
+	if err != nil {
+		return nil, conversationError(err)
+	}
+
+	return map[string]interface{}{
+		"conversation": conversation,
+	}, nil
+}
+
+// RenameConversationRequest is the body of PATCH /api/ai/conversations/{conversation_id}
+type RenameConversationRequest struct {
+	Title string `json:"title"`
+}
+
+// RenameConversation handles PATCH /api/ai/conversations/{conversation_id}
+func (ch *ConversationHandler) RenameConversation(ctx *gofr.Context) (interface{}, error) {
+	owner, err := requireSessionOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var request RenameConversationRequest
+	if err := ctx.Bind(&request); err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+	title := strings.TrimSpace(request.Title)
+	if title == "" {
+		return nil, invalidParam("title", "is required")
+	}
+	if utf8.RuneCountInString(title) > maxConversationTitle {
+		return nil, invalidParam("title", fmt.Sprintf("must be at most %d characters", maxConversationTitle))
+	}
+
+	conversationID := ctx.PathParam("conversation_id")
+	if err := ch.store.Rename(ctx, owner, conversationID, title); err != nil {
+		return nil, conversationError(err)
+	}
+
+	return map[string]interface{}{
+		"conversation_id": conversationID,
+		"title":           title,
+		"message":         "Conversation renamed",
+	}, nil
+}
+
+// DeleteConversation handles DELETE /api/ai/conversations/{conversation_id}
+func (ch *ConversationHandler) DeleteConversation(ctx *gofr.Context) (interface{}, error) {
+	owner, err := requireSessionOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conversationID := ctx.PathParam("conversation_id")
+	if err := ch.store.Delete(ctx, owner, conversationID); err != nil {
+		return nil, conversationError(err)
+	}
+
+	return map[string]interface{}{
+		"conversation_id": conversationID,
+		"message":         "Conversation deleted",
+	}, nil
+}
+
+// loadConversation replaces the history a chat request brought with the stored
+// conversation it names. A request without a conversation ID gets a new one, which
+// saveConversation creates once the answer is there; it reports whether that happened.
+// Without a session the client keeps sending its own history, as before.
+func (ah *AIHandler) loadConversation(ctx context.Context, request *models.ChatRequest) (bool, error) {
+	owner, ok := sessionOwner(ctx)
+	if !ok {
+		return false, nil
+	}
+
+	if request.ConversationID == "" {
+		request.ConversationID = service.NewConversationID()
+		return true, nil
+	}
+
+	conversation, err := ah.conversations.Get(ctx, owner, request.ConversationID)
+	if err != nil {
+		return false, conversationError(err)
+	}
+
+	history := make([]models.LLMMessage, 0, len(conversation.Messages))
+	for _, message := range conversation.Messages {
+		history = append(history, models.LLMMessage{Role: message.Role, Content: message.Content})
+	}
+	request.ChatHistory = history
+	if conversation.LinkID != "" {
+		request.SessionLinkIDs = append(request.SessionLinkIDs, conversation.LinkID)
+	}
+	return false, nil
+}
+
+// saveConversation stores the question and answer of a chat. A failure is returned, so
+// the client doesn't take an answer that isn't in the conversation for a saved one.
+func (ah *AIHandler) saveConversation(ctx context.Context, request *models.ChatRequest, response *models.ChatResponse, isNew bool) error {
+	owner, ok := sessionOwner(ctx)
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	messages := []models.ConversationMessage{
+		{Role: "user", Content: request.Message, CreatedAt: now},
+		{Role: "assistant", Content: response.Message, CreatedAt: response.GeneratedAt},
+	}
+
+	var err error
+	if isNew {
+		err = ah.conversations.Create(ctx, owner, &models.Conversation{
+			ID:        request.ConversationID,
+			Title:     conversationTitle(request.Message),
+			LinkID:    request.LinkID,
+			Language:  request.Language,
+			CreatedAt: now,
+			UpdatedAt: now,
+			Messages:  messages,
+		})
+	} else {
+		err = ah.conversations.AddMessages(ctx, owner, request.ConversationID, messages...)
+	}
+	if err != nil {
+		fmt.Printf("❌ Failed to save conversation %s: %v\n", request.ConversationID, err)
+		return conversationError(fmt.Errorf("failed to save conversation %s: %w", request.ConversationID, err))
+	}
+	return nil
+}
+
+// conversationTitle names a new conversation after its first question
+func conversationTitle(message string) string {
+	title := strings.Join(strings.Fields(message), " ")
+	if utf8.RuneCountInString(title) <= 60 {
+		return title
+	}
+	return string([]rune(title)[:60]) + "…"
+}
+
+// conversationError turns ErrConversationNotFound into a 404
+func conversationError(err error) error {
+	if errors.Is(err, service.ErrConversationNotFound) {
+		return &APIError{
+			Status:  http.StatusNotFound,
+			Code:    "conversation_not_found",
+			Message: err.Error(),
+		}
+	}
+	return err
+}
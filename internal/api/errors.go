@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ai-financial-coach/internal/service"
+)
+
+// APIError is a handler error with an explicit HTTP status. gofr takes the status from
+// StatusCode and merges Response into the {"error": {"message": ...}} envelope.
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	Details map[string]interface{}
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// StatusCode returns the HTTP status for the response
+func (e *APIError) StatusCode() int {
+	return e.Status
+}
+
+// Response returns the extra fields of the error envelope
+func (e *APIError) Response() map[string]any {
+	response := map[string]any{
+		"code": e.Code,
+	}
+	for key, value := range e.Details {
+		response[key] = value
+	}
+	return response
+}
+
+// invalidParam reports a bad request parameter as a 400
+func invalidParam(field, reason string) error {
+	return &APIError{
+		Status:  http.StatusBadRequest,
+		Code:    "invalid_parameter",
+		Message: fmt.Sprintf("%s %s", field, reason),
+		Details: map[string]interface{}{
+			"field": field,
+		},
+	}
+}
+
+// summaryOptions validates a requested lookback, where zero means the default window,
+// and reporting currency, where empty means the currency of the first account
+func summaryOptions(lookbackMonths int, reportingCurrency string) (service.SummaryOptions, error) {
+	if reportingCurrency != "" && !service.IsSupportedCurrency(strings.TrimSpace(reportingCurrency)) {
+		return service.SummaryOptions{}, invalidParam("reporting_currency", fmt.Sprintf("must be one of %s", strings.Join(service.SupportedCurrencies(), ", ")))
+	}
+	opts, err := service.ValidateSummaryOptions(service.SummaryOptions{LookbackMonths: lookbackMonths, ReportingCurrency: reportingCurrency})
+	if err != nil {
+		return opts, invalidParam("lookback_months", fmt.Sprintf("must be between 1 and %d", service.MaxLookbackMonths))
+	}
+	return opts, nil
+}
+
+// querySummaryOptions is summaryOptions for the lookback_months and reporting_currency
+// query parameters
+func querySummaryOptions(lookbackMonths, reportingCurrency string) (service.SummaryOptions, error) {
+	months := 0
+	if lookbackMonths != "" {
+		parsed, err := strconv.Atoi(lookbackMonths)
+		if err != nil {
+			return service.SummaryOptions{}, invalidParam("lookback_months", "must be a whole number of months")
+		}
+		months = parsed
+	}
+	return summaryOptions(months, reportingCurrency)
+}
+
+// belvoErrorStatus maps each Belvo error class to the status this API responds with
+var belvoErrorStatus = map[service.BelvoErrorClass]int{
+	service.BelvoErrorAuthFailure:     http.StatusUnauthorized,
+	service.BelvoErrorInvalidLink:     http.StatusConflict,
+	service.BelvoErrorTokenRequired:   http.StatusPreconditionRequired,
+	service.BelvoErrorRateLimited:     http.StatusTooManyRequests,
+	service.BelvoErrorInstitutionDown: http.StatusServiceUnavailable,
+	service.BelvoErrorValidation:      http.StatusBadRequest,
+	service.BelvoErrorNotFound:        http.StatusNotFound,
+	service.BelvoErrorUnknown:         http.StatusBadGateway,
+}
+
+// belvoError converts a Belvo failure into an APIError with the matching status.
+// Deadlines become 504 and listings cut off by the page limit 502; other errors that did
+// not come from Belvo are wrapped with message as before.
+func belvoError(err error, message string) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &APIError{
+			Status:  http.StatusGatewayTimeout,
+			Code:    "timeout",
+			Message: fmt.Sprintf("%s: request timed out", message),
+		}
+	}
+	if errors.Is(err, service.ErrPageLimitReached) {
+		return &APIError{
+			Status:  http.StatusBadGateway,
+			Code:    "page_limit_reached",
+			Message: fmt.Sprintf("%s: %v", message, err),
+		}
+	}
+
+	apiErr, ok := service.AsBelvoAPIError(err)
+	if !ok {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+
+	status, ok := belvoErrorStatus[apiErr.Class]
+	if !ok {
+		status = http.StatusBadGateway
+	}
+
+	first := apiErr.First()
+	detail := first.Message
+	if detail == "" {
+		detail = apiErr.Error()
+	}
+
+	return &APIError{
+		Status:  status,
+		Code:    string(apiErr.Class),
+		Message: fmt.Sprintf("%s: %s", message, detail),
+		Details: map[string]interface{}{
+			"belvo_code":      first.Code,
+			"request_id":      first.RequestID,
+			"field":           first.Field,
+			"upstream_status": apiErr.StatusCode,
+			"errors":          apiErr.Errors,
+		},
+	}
+}
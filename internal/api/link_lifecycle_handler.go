@@ -0,0 +1,124 @@
+package api
+
+import (
+	"fmt"
+
+	"gofr.dev/pkg/gofr"
+
+	"ai-financial-coach/internal/models"
+)
+
+// ResumeLinkRequest represents the request body for submitting an MFA token
+type ResumeLinkRequest struct {
+	Session string `json:"session"`
+	Token   string `json:"token"`
+}
+
+// RefreshLinkRequest represents the request body for refreshing a link
+type RefreshLinkRequest struct {
+	models.LinkRefreshRequest
+}
+
+// SetLinkRemovedHook registers a callback run after a link is deleted, e.g. to drop cached chat context
+func (bh *BelvoHandler) SetLinkRemovedHook(hook func(linkID string)) {
+	bh.onLinkRemoved = hook
+}
+
+// ResumeLink handles PATCH /api/belvo/links/{link_id}/token
+func (bh *BelvoHandler) ResumeLink(ctx *gofr.Context) (interface{}, error) {
+	linkID := ctx.PathParam("link_id")
+	if linkID == "" {
+		return nil, fmt.Errorf("link_id is required")
+	}
+
+	var req ResumeLinkRequest
+	if err := ctx.Bind(&req); err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+	if req.Session == "" || req.Token == "" {
+		return nil, fmt.Errorf("session and token are required")
+	}
+
+	belvoService, err := bh.clients.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := belvoService.ResumeLink(ctx, linkID, req.Session, req.Token)
+	if err != nil {
+		return nil, belvoError(err, "failed to resume link")
+	}
+
+	fmt.Printf("🔐 Resume link %s → %s\n", linkID, result.Status)
+	return linkOperationResponse(linkID, result), nil
+}
+
+// RefreshLink handles PUT /api/belvo/links/{link_id}
+func (bh *BelvoHandler) RefreshLink(ctx *gofr.Context) (interface{}, error) {
+	linkID := ctx.PathParam("link_id")
+	if linkID == "" {
+		return nil, fmt.Errorf("link_id is required")
+	}
+
+	var req RefreshLinkRequest
+	_ = ctx.Bind(&req) // The MFA token is optional
+
+	belvoService, err := bh.clients.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := belvoService.RefreshLink(ctx, linkID, req.LinkRefreshRequest)
+	if err != nil {
+		return nil, belvoError(err, "failed to refresh link")
+	}
+
+	fmt.Printf("🔄 Refresh link %s → %s\n", linkID, result.Status)
+	return linkOperationResponse(linkID, result), nil
+}
+
+// DeleteLink handles DELETE /api/belvo/links/{link_id}
+func (bh *BelvoHandler) DeleteLink(ctx *gofr.Context) (interface{}, error) {
+	linkID := ctx.PathParam("link_id")
+	if linkID == "" {
+		return nil, fmt.Errorf("link_id is required")
+	}
+
+	belvoService, err := bh.clients.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := belvoService.DeleteLink(ctx, linkID)
+	if err != nil {
+		return nil, belvoError(err, "failed to delete link")
+	}
+
+	if bh.onLinkRemoved != nil {
+		bh.onLinkRemoved(linkID)
+	}
+
+	fmt.Printf("🗑️ Deleted link %s\n", linkID)
+	return linkOperationResponse(linkID, result), nil
+}
+
+// linkOperationResponse builds the response for a link lifecycle operation
+func linkOperationResponse(linkID string, result *models.LinkOperationResult) map[string]interface{} {
+	response := map[string]interface{}{
+		"link_id": linkID,
+		"status":  result.Status,
+	}
+
+	switch result.Status {
+	case models.LinkStatusTokenRequired:
+		response["token_required"] = result.TokenRequired
+		response["message"] = "The institution requires an MFA token. Submit it with the session to PATCH /api/belvo/links/{link_id}/token"
+	case models.LinkStatusDeleted:
+		response["message"] = "Link deleted"
+	default:
+		response["link"] = result.Link
+		response["message"] = "Link is up to date"
+	}
+
+	return response
+}
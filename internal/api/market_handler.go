@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"strings"
 
 	"gofr.dev/pkg/gofr"
 
@@ -36,7 +37,7 @@ func (mh *MarketHandler) GetAssetPerformance(ctx *gofr.Context) (interface{}, er
 		return nil, fmt.Errorf("symbol parameter is required")
 	}
 
-	performance, err := mh.marketService.GetAssetPerformance(symbol)
+	performance, err := mh.marketService.GetAssetPerformance(ctx, symbol)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get asset performance for %s: %w", symbol, err)
 	}
@@ -47,13 +48,26 @@ func (mh *MarketHandler) GetAssetPerformance(ctx *gofr.Context) (interface{}, er
 	}, nil
 }
 
-// GetMarketData handles GET /api/market/data
+// GetMarketData handles GET /api/market/data?currency=
 func (mh *MarketHandler) GetMarketData(ctx *gofr.Context) (interface{}, error) {
-	summary, err := mh.marketService.GetMarketDataSummary()
+	currency := ctx.Param("currency")
+	if currency != "" && !service.IsSupportedCurrency(currency) {
+		return nil, invalidParam("currency", fmt.Sprintf("must be one of %s", strings.Join(service.SupportedCurrencies(), ", ")))
+	}
+
+	summary, err := mh.marketService.GetMarketDataSummary(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get market data summary: %w", err)
 	}
 
+	// Asset prices come in the currency each one trades in unless one is asked for
+	if currency != "" {
+		summary, err = mh.marketService.ConvertMarketData(ctx, summary, currency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert market data: %w", err)
+		}
+	}
+
 	return map[string]interface{}{
 		"market_data": summary,
 		"message":     "Market data summary retrieved successfully",
@@ -62,7 +76,7 @@ func (mh *MarketHandler) GetMarketData(ctx *gofr.Context) (interface{}, error) {
 
 // GetBrazilianRates handles GET /api/market/brazilian-rates
 func (mh *MarketHandler) GetBrazilianRates(ctx *gofr.Context) (interface{}, error) {
-	rates, err := mh.marketService.GetBrazilianRates()
+	rates, err := mh.marketService.GetBrazilianRates(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Brazilian rates: %w", err)
 	}
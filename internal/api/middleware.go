@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WebhookAuthMiddleware rejects requests to webhookPath whose Authorization header
+// doesn't match the secret configured for the Belvo webhook. Without a secret every
+// webhook is rejected, unless allowUnverified accepts them unchecked (for a server that
+// only talks to the sandbox or the fake server).
+func WebhookAuthMiddleware(webhookPath, secret string, allowUnverified bool) func(http.Handler) http.Handler {
+	if secret == "" {
+		if allowUnverified {
+			fmt.Println("⚠️ BELVO_WEBHOOK_SECRET not set - sandbox webhook requests will not be verified")
+		} else {
+			fmt.Println("❌ BELVO_WEBHOOK_SECRET not set - webhook requests will be rejected")
+		}
+	}
+
+	return func(inner http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == webhookPath && secret == "" && !allowUnverified {
+				writeJSONError(w, http.StatusServiceUnavailable, "webhook verification is not configured")
+				return
+			}
+			if secret != "" && r.URL.Path == webhookPath {
+				provided := r.Header.Get("Authorization")
+				if subtle.ConstantTimeCompare([]byte(provided), []byte(secret)) != 1 {
+					writeJSONError(w, http.StatusUnauthorized, "invalid webhook authorization")
+					return
+				}
+			}
+
+			inner.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RouteTimeout is the deadline applied to requests whose path starts with Prefix
+type RouteTimeout struct {
+	Prefix  string
+	Timeout time.Duration
+}
+
+// RequestTimeoutMiddleware puts a deadline on the request context using the longest
+// matching prefix. Handlers pass that context to the services, so in-flight Belvo,
+// market and OpenAI calls are cancelled when the deadline passes or the client disconnects.
+func RequestTimeoutMiddleware(routes []RouteTimeout) func(http.Handler) http.Handler {
+	return func(inner http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var match *RouteTimeout
+			for i := range routes {
+				if strings.HasPrefix(r.URL.Path, routes[i].Prefix) && (match == nil || len(routes[i].Prefix) > len(match.Prefix)) {
+					match = &routes[i]
+				}
+			}
+
+			if match == nil || match.Timeout <= 0 {
+				inner.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), match.Timeout)
+			defer cancel()
+			inner.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// writeJSONError writes an error using the same envelope gofr uses for handler errors
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+		},
+	})
+}
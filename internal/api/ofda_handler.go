@@ -0,0 +1,274 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gofr.dev/pkg/gofr"
+
+	"ai-financial-coach/internal/models"
+	"ai-financial-coach/internal/service"
+)
+
+// OFDAHandler runs the Open Finance Brazil consent flow: it issues widget tokens with
+// the consent fields Belvo requires, receives the widget callbacks and tracks each
+// consent until it has to be renewed
+type OFDAHandler struct {
+	clients     *BelvoClients
+	tracker     *service.ConsentTracker
+	callbackURL string
+}
+
+// NewOFDAHandler creates a new OFDAHandler. callbackBaseURL is the public address of
+// this API, which the widget redirects to when the user finishes or leaves.
+func NewOFDAHandler(clients *BelvoClients, tracker *service.ConsentTracker, callbackBaseURL string) *OFDAHandler {
+	return &OFDAHandler{
+		clients:     clients,
+		tracker:     tracker,
+		callbackURL: strings.TrimRight(callbackBaseURL, "/") + "/api/belvo/ofda/callback",
+	}
+}
+
+// OFDAWidgetTokenRequest represents the request body for an Open Finance widget token
+type OFDAWidgetTokenRequest struct {
+	models.OFDAConsentRequest
+}
+
+// CreateWidgetToken handles POST /api/belvo/ofda/widget-token
+func (oh *OFDAHandler) CreateWidgetToken(ctx *gofr.Context) (interface{}, error) {
+	var req OFDAWidgetTokenRequest
+	if err := ctx.Bind(&req); err != nil {
+		return nil, invalidParam("body", fmt.Sprintf("must be a consent request: %v", err))
+	}
+
+	return oh.startWidget(ctx, req, "")
+}
+
+// RenewConsent handles POST /api/belvo/ofda/consents/{consent_id}/renew. The body
+// carries the same consent fields as the widget token request. Only the Belvo account
+// that granted a consent can renew it.
+func (oh *OFDAHandler) RenewConsent(ctx *gofr.Context) (interface{}, error) {
+	owner, err := requireSessionOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	consentID := ctx.PathParam("consent_id")
+	consent, ok := oh.tracker.Get(owner, consentID)
+	if !ok {
+		return nil, &APIError{
+			Status:  http.StatusNotFound,
+			Code:    "consent_not_found",
+			Message: fmt.Sprintf("consent %s is not tracked", consentID),
+		}
+	}
+	if consent.Status == models.ConsentStatusRevoked {
+		return nil, &APIError{
+			Status:  http.StatusConflict,
+			Code:    "consent_revoked",
+			Message: "a revoked consent can't be renewed; create a new one",
+		}
+	}
+
+	var req OFDAWidgetTokenRequest
+	if err := ctx.Bind(&req); err != nil {
+		return nil, invalidParam("body", fmt.Sprintf("must be a consent request: %v", err))
+	}
+	switch req.CustomerID {
+	case "":
+		req.CustomerID = consent.CustomerID
+	case consent.CustomerID:
+	default:
+		return nil, invalidParam("customer_id", "does not match the consent being renewed")
+	}
+	if req.Institution == "" {
+		req.Institution = consent.Institution
+	}
+	if len(req.Permissions) == 0 {
+		req.Permissions = consent.Permissions
+	}
+
+	return oh.startWidget(ctx, req, consent.ConsentID)
+}
+
+// startWidget validates the consent fields, opens a callback session and requests the widget token
+func (oh *OFDAHandler) startWidget(ctx *gofr.Context, req OFDAWidgetTokenRequest, renewConsentID string) (interface{}, error) {
+	consent, err := service.ValidateOFDAConsentRequest(req.OFDAConsentRequest)
+	if err != nil {
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    "invalid_consent",
+			Message: err.Error(),
+		}
+	}
+
+	owner, err := requireSessionOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	belvoService, err := oh.clients.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The callback arrives from the user's browser without a session token, so the
+	// consent session keeps the credentials, sealed, to look up Belvo's consent record
+	sealed, err := oh.clients.Seal(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal credentials for the consent callback: %w", err)
+	}
+	session, err := oh.tracker.StartSession(consent, renewConsentID, owner, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := belvoService.CreateOFDAWidgetToken(ctx, service.OFDAWidgetRequest{
+		Consent:        consent,
+		Callbacks:      oh.callbacks(session.State),
+		RenewConsentID: renewConsentID,
+	})
+	if err != nil {
+		oh.tracker.EndSession(session.State)
+		return nil, belvoError(err, "failed to generate OFDA widget token")
+	}
+
+	feature := service.OFDAFeatureConsentCreation
+	if renewConsentID != "" {
+		feature = service.OFDAFeatureConsentRenewal
+	}
+
+	return map[string]interface{}{
+		"widget_token":        token,
+		"widget_url":          service.OFDAWidgetURL(token),
+		"state":               session.State,
+		"state_expires_at":    session.ExpiresAt,
+		"openfinance_feature": feature,
+		"institution":         consent.Institution,
+		"permissions":         consent.Permissions,
+		"message":             "Open the widget_url to grant the Open Finance consent",
+	}, nil
+}
+
+// callbacks returns the widget callback URLs for a session
+func (oh *OFDAHandler) callbacks(state string) service.OFDACallbackURLs {
+	callback := func(outcome string) string {
+		return fmt.Sprintf("%s?state=%s&outcome=%s", oh.callbackURL, url.QueryEscape(state), outcome)
+	}
+	return service.OFDACallbackURLs{
+		Success: callback("success"),
+		Exit:    callback("exit"),
+		Event:   callback("event"),
+	}
+}
+
+// Callback handles GET /api/belvo/ofda/callback, where the widget sends the user with
+// the link and consent it created
+func (oh *OFDAHandler) Callback(ctx *gofr.Context) (interface{}, error) {
+	state := ctx.Param("state")
+	session, ok := oh.tracker.Session(state)
+	if !ok {
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    "invalid_state",
+			Message: "unknown or expired consent session; request a new widget token",
+		}
+	}
+
+	switch outcome := ctx.Param("outcome"); outcome {
+	case "exit", "event":
+		oh.tracker.EndSession(state)
+		fmt.Printf("🚪 Open Finance widget %s for customer %s: %s\n", outcome, session.Consent.CustomerID, ctx.Param("error_message"))
+		return map[string]interface{}{
+			"success":       false,
+			"outcome":       outcome,
+			"error":         ctx.Param("error"),
+			"error_message": ctx.Param("error_message"),
+			"message":       "The consent was not granted",
+		}, nil
+	case "success":
+	default:
+		return nil, invalidParam("outcome", "must be success, exit or event")
+	}
+
+	linkID := firstParam(ctx, "link_id", "link")
+	consentID := firstParam(ctx, "consent_id", "consent")
+	if linkID == "" {
+		return nil, invalidParam("link_id", "is required")
+	}
+	if consentID == "" && session.RenewConsentID == "" {
+		return nil, invalidParam("consent_id", "is required")
+	}
+
+	// Belvo's own record carries the real expiry date; without it the expiry is estimated
+	var belvoConsent *models.BelvoConsent
+	if belvoService, err := oh.clients.ForSealed(session.Credentials); err != nil {
+		fmt.Printf("⚠️ Could not open the credentials of consent session for link %s, estimating expiry: %v\n", linkID, err)
+	} else {
+		consents, err := belvoService.GetConsents(ctx, linkID)
+		if err != nil {
+			fmt.Printf("⚠️ Could not fetch consents for link %s, estimating expiry: %v\n", linkID, err)
+		}
+		for i := range consents {
+			if consents[i].ID == consentID || (consentID == "" && consents[i].ID == session.RenewConsentID) {
+				belvoConsent = &consents[i]
+				break
+			}
+		}
+	}
+
+	consent, err := oh.tracker.Complete(state, linkID, consentID, belvoConsent)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"outcome": "success",
+		"consent": consent,
+		"link_id": consent.LinkID,
+		"message": fmt.Sprintf("Open Finance consent active until %s", consent.ExpiresAt.Format("2006-01-02")),
+	}, nil
+}
+
+// ListConsents handles GET /api/belvo/ofda/consents?customer_id= - the session's
+// consents, soonest to expire first
+func (oh *OFDAHandler) ListConsents(ctx *gofr.Context) (interface{}, error) {
+	owner, err := requireSessionOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	consents := oh.tracker.List(owner, ctx.Param("customer_id"))
+
+	return map[string]interface{}{
+		"consents": consents,
+		"count":    len(consents),
+	}, nil
+}
+
+// ListReminders handles GET /api/belvo/ofda/reminders?customer_id= - reminders for the
+// session's consents, most recent first
+func (oh *OFDAHandler) ListReminders(ctx *gofr.Context) (interface{}, error) {
+	owner, err := requireSessionOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reminders := oh.tracker.Reminders(owner, ctx.Param("customer_id"))
+
+	return map[string]interface{}{
+		"reminders": reminders,
+		"count":     len(reminders),
+	}, nil
+}
+
+// firstParam returns the first non-empty query parameter among names
+func firstParam(ctx *gofr.Context, names ...string) string {
+	for _, name := range names {
+		if value := ctx.Param(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
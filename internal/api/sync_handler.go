@@ -0,0 +1,46 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gofr.dev/pkg/gofr"
+
+	"ai-financial-coach/internal/service"
+)
+
+// SyncTransactions handles POST /api/belvo/sync/{link_id} - pulls new transactions into the local store
+func (bh *BelvoHandler) SyncTransactions(ctx *gofr.Context) (interface{}, error) {
+	linkID := ctx.PathParam("link_id")
+	if linkID == "" {
+		return nil, fmt.Errorf("link_id is required")
+	}
+
+	belvoService, err := bh.clients.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := belvoService.SyncTransactions(ctx, linkID)
+	if errors.Is(err, service.ErrTransactionSyncDisabled) {
+		return nil, &APIError{
+			Status:  http.StatusServiceUnavailable,
+			Code:    "sync_disabled",
+			Message: "transaction sync is not configured on this server",
+		}
+	}
+	if err != nil {
+		return nil, belvoError(err, "failed to sync transactions")
+	}
+
+	message := fmt.Sprintf("Synced %d new and %d updated transactions", result.Added, result.Updated)
+	if result.FullSync {
+		message = fmt.Sprintf("Initial sync stored %d transactions", result.Total)
+	}
+
+	return map[string]interface{}{
+		"sync":    result,
+		"message": message,
+	}, nil
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gofr.dev/pkg/gofr"
+
+	"ai-financial-coach/internal/models"
+	"ai-financial-coach/internal/service"
+)
+
+const (
+	transactionStreamPrefix = "/api/belvo/transactions/"
+	transactionStreamSuffix = "/stream"
+
+	// streamFlushEvery is how many records are written between flushes to the client
+	streamFlushEvery = 100
+)
+
+// TransactionStreamMiddleware serves GET /api/belvo/transactions/{link_id}/stream?from=&to=&type=
+// as newline-delimited JSON, one BelvoTransaction per line, written while the pages are
+// still being decoded from Belvo. gofr handlers buffer their whole response, so the
+// export is served from the middleware chain instead of a route.
+func TransactionStreamMiddleware(clients *BelvoClients) func(http.Handler) http.Handler {
+	return func(inner http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			linkID, ok := transactionStreamLinkID(r)
+			if !ok {
+				inner.ServeHTTP(w, r)
+				return
+			}
+			streamTransactions(w, r, clients, linkID)
+		})
+	}
+}
+
+// StreamTransactionsRoute is registered for GET /api/belvo/transactions/{link_id}/stream.
+// gofr only runs middleware for requests that match a route, so the route must exist
+// even though TransactionStreamMiddleware answers before this handler is reached.
+func StreamTransactionsRoute(ctx *gofr.Context) (interface{}, error) {
+	return nil, &APIError{
+		Status:  http.StatusInternalServerError,
+		Code:    "stream_unavailable",
+		Message: "transaction streaming requires TransactionStreamMiddleware",
+	}
+}
+
+// transactionStreamLinkID matches the stream route and extracts its link ID
+func transactionStreamLinkID(r *http.Request) (string, bool) {
+	if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, transactionStreamPrefix) || !strings.HasSuffix(r.URL.Path, transactionStreamSuffix) {
+		return "", false
+	}
+	linkID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, transactionStreamPrefix), transactionStreamSuffix)
+	if linkID == "" || strings.Contains(linkID, "/") {
+		return "", false
+	}
+	return linkID, true
+}
+
+// transactionFilter parses the from, to and type query parameters
+func transactionFilter(r *http.Request) (service.TransactionFilter, error) {
+	var filter service.TransactionFilter
+	query := r.URL.Query()
+
+	for _, param := range []struct {
+		name string
+		dest **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return filter, invalidParam(param.name, "must be a date in YYYY-MM-DD format")
+		}
+		*param.dest = &parsed
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return filter, invalidParam("from", "must not be after to")
+	}
+
+	switch transactionType := strings.ToUpper(query.Get("type")); transactionType {
+	case "", "INFLOW", "OUTFLOW":
+		filter.Type = transactionType
+	default:
+		return filter, invalidParam("type", "must be INFLOW or OUTFLOW")
+	}
+	return filter, nil
+}
+
+// streamTransactions writes the export. Errors before the first record get a normal
+// error response; later ones end the stream with an {"error": ...} line, since the
+// status has already been sent. Counts are also sent as HTTP trailers.
+func streamTransactions(w http.ResponseWriter, r *http.Request, clients *BelvoClients, linkID string) {
+	filter, err := transactionFilter(r)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	belvoService, err := clients.For(r.Context())
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	started := false
+	start := func() {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Trailer", "X-Transaction-Count, X-Stream-Skipped, X-Stream-Truncated")
+		w.WriteHeader(http.StatusOK)
+		started = true
+	}
+
+	written := 0
+	result, err := belvoService.StreamTransactions(r.Context(), linkID, filter, func(transaction models.BelvoTransaction) error {
+		if !started {
+			start()
+		}
+		if err := encoder.Encode(transaction); err != nil {
+			return fmt.Errorf("failed to write transaction: %w", err)
+		}
+		written++
+		if flusher != nil && written%streamFlushEvery == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	if err != nil && !started {
+		writeAPIError(w, belvoError(err, "failed to stream transactions"))
+		return
+	}
+	if !started {
+		start() // Nothing matched: an empty stream
+	}
+	if err != nil {
+		fmt.Printf("❌ Transaction stream for link %s failed after %d records: %v\n", linkID, written, err)
+		_ = encoder.Encode(map[string]interface{}{
+			"error": map[string]interface{}{
+				"message": err.Error(),
+			},
+		})
+	}
+
+	w.Header().Set("X-Transaction-Count", strconv.Itoa(written))
+	skipped := 0
+	if result != nil {
+		skipped = result.Skipped
+	}
+	w.Header().Set("X-Stream-Skipped", strconv.Itoa(skipped))
+	w.Header().Set("X-Stream-Truncated", strconv.FormatBool(result != nil && result.Truncated))
+	if flusher != nil {
+		flusher.Flush()
+	}
+	if err == nil {
+		fmt.Printf("📤 Streamed %d transactions for link %s over %d pages\n", written, linkID, result.Pages)
+	}
+}
+
+// writeAPIError writes err with its APIError status, or as a 500
+func writeAPIError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		status = apiErr.Status
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorEnvelope(err))
+}
+
+// errorEnvelope wraps err in the {"error": {"message": ...}} envelope gofr uses, with
+// the extra fields of an APIError
+func errorEnvelope(err error) map[string]interface{} {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return map[string]interface{}{"error": map[string]interface{}{"message": err.Error()}}
+	}
+
+	body := apiErr.Response()
+	body["message"] = apiErr.Message
+	return map[string]interface{}{"error": body}
+}
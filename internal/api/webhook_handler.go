@@ -0,0 +1,135 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"gofr.dev/pkg/gofr"
+
+	"ai-financial-coach/internal/models"
+)
+
+// maxWebhookEvents bounds the in-memory webhook event log
+const maxWebhookEvents = 200
+
+// WebhookHandler receives Belvo webhooks and keeps the AI context cache in sync
+type WebhookHandler struct {
+	aiHandler *AIHandler
+
+	mu     sync.RWMutex
+	events []models.WebhookEvent
+}
+
+// NewWebhookHandler creates a new WebhookHandler instance
+func NewWebhookHandler(aiHandler *AIHandler) *WebhookHandler {
+	return &WebhookHandler{
+		aiHandler: aiHandler,
+	}
+}
+
+// HandleWebhook handles POST /api/belvo/webhooks. Payloads that can never be processed
+// get a 400, which Belvo doesn't redeliver; well-formed webhooks of types this server
+// doesn't handle are acknowledged and recorded as ignored.
+func (wh *WebhookHandler) HandleWebhook(ctx *gofr.Context) (interface{}, error) {
+	var webhook models.BelvoWebhook
+	if err := ctx.Bind(&webhook); err != nil {
+		return nil, invalidParam("body", fmt.Sprintf("must be a Belvo webhook: %v", err))
+	}
+
+	if webhook.WebhookType == "" {
+		return nil, invalidParam("webhook_type", "is required")
+	}
+	if webhook.WebhookCode == "" {
+		return nil, invalidParam("webhook_code", "is required")
+	}
+	if webhook.LinkID == "" {
+		return nil, invalidParam("link_id", "is required")
+	}
+
+	action, detail, accounts := wh.process(webhook)
+
+	event := models.WebhookEvent{
+		ReceivedAt: time.Now(),
+		Webhook:    webhook,
+		Action:     action,
+		Detail:     detail,
+		Accounts:   accounts,
+	}
+	wh.record(event)
+
+	fmt.Printf("📬 Belvo webhook %s/%s for link %s → %s\n", webhook.WebhookType, webhook.WebhookCode, webhook.LinkID, action)
+
+	return map[string]interface{}{
+		"received": true,
+		"action":   action,
+		"detail":   detail,
+	}, nil
+}
+
+// ListEvents handles GET /api/belvo/webhooks/events - most recent first. Webhooks carry
+// no account, so each session only sees the events that touched its own cached context.
+func (wh *WebhookHandler) ListEvents(ctx *gofr.Context) (interface{}, error) {
+	owner, err := requireSessionOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	linkID := ctx.Param("link_id")
+
+	wh.mu.RLock()
+	defer wh.mu.RUnlock()
+
+	events := make([]models.WebhookEvent, 0, len(wh.events))
+	for i := len(wh.events) - 1; i >= 0; i-- {
+		if !slices.Contains(wh.events[i].Accounts, owner) {
+			continue
+		}
+		if linkID == "" || wh.events[i].Webhook.LinkID == linkID {
+			events = append(events, wh.events[i])
+		}
+	}
+
+	return map[string]interface{}{
+		"events": events,
+		"count":  len(events),
+	}, nil
+}
+
+// process applies a webhook to the context cache and describes what was done, along
+// with the accounts whose cached context it touched
+func (wh *WebhookHandler) process(webhook models.BelvoWebhook) (string, string, []string) {
+	switch {
+	case webhook.WebhookType == models.WebhookTypeTransactions &&
+		(webhook.WebhookCode == models.WebhookCodeHistoricalUpdate || webhook.WebhookCode == models.WebhookCodeNewTransactionsAvailable):
+		accounts, err := wh.aiHandler.RefreshContext(webhook.LinkID)
+		switch {
+		case err != nil:
+			return "invalidated", fmt.Sprintf("cached chat context dropped; it can't be rebuilt: %v", err), accounts
+		case len(accounts) > 0:
+			return "refreshed", "cached chat context is being rebuilt from fresh Belvo data", accounts
+		}
+		return "ignored", "no cached chat context for this link", nil
+
+	case webhook.WebhookType == models.WebhookTypeLinks &&
+		(webhook.WebhookCode == models.WebhookCodeTokenRequired || webhook.WebhookCode == models.WebhookCodeInvalid):
+		if accounts := wh.aiHandler.InvalidateContext(webhook.LinkID); len(accounts) > 0 {
+			return "invalidated", fmt.Sprintf("link is %s; cached chat context dropped", webhook.WebhookCode), accounts
+		}
+		return "invalidated", fmt.Sprintf("link is %s; nothing was cached", webhook.WebhookCode), nil
+
+	default:
+		return "ignored", fmt.Sprintf("unhandled webhook %s/%s", webhook.WebhookType, webhook.WebhookCode), nil
+	}
+}
+
+// record appends an event to the bounded in-memory log
+func (wh *WebhookHandler) record(event models.WebhookEvent) {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	wh.events = append(wh.events, event)
+	if len(wh.events) > maxWebhookEvents {
+		wh.events = wh.events[len(wh.events)-maxWebhookEvents:]
+	}
+}
@@ -1,12 +1,19 @@
 package app
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"gofr.dev/pkg/gofr"
 
 	"ai-financial-coach/internal/api"
+	"ai-financial-coach/internal/service"
 )
 
 func CreateApp() *gofr.App {
@@ -31,18 +38,37 @@ func RegisterRoutes(app *gofr.App) {
 			"version": "v1.0.0",
 			"endpoints": []string{
 				"GET /health - Health check",
+				"-- Sessions --",
+				"POST /api/auth/login - Validate Belvo credentials and get a session token",
+				"POST /api/auth/logout - End the session",
+				"GET /api/auth/session - The current session",
 				"-- Belvo API (Phase 1) --",
 				"GET /api/belvo/test-connection - Test Belvo API connection",
-				"GET /api/belvo/institutions - Get available institutions",
+				"GET /api/belvo/institutions?country=&type=&resource=&status=&q= - Search the cached institution catalog",
 				"POST /api/belvo/links - Create Belvo link",
 				"GET /api/belvo/accounts/{link_id} - Get accounts for link",
 				"GET /api/belvo/transactions/{link_id} - Get transactions for link",
-				"GET /api/belvo/financial-summary/{link_id} - Get financial summary",
+				"GET /api/belvo/transactions/{link_id}/stream?from=&to=&type= - Stream transactions as NDJSON",
+				"GET /api/belvo/financial-summary/{link_id}?lookback_months=&reporting_currency= - Get financial summary",
+				"POST /api/belvo/financial-summary/with-credentials - Get financial summary with link_id, lookback_months and reporting_currency in the body",
+				"POST /api/belvo/financial-summary/consolidated - Merge several links into one financial summary",
+				"POST /api/belvo/sync/{link_id} - Sync new transactions into the local store",
+				"GET /api/belvo/balances/{link_id}?from=&to=&granularity= - Daily, weekly or monthly balance history",
 				"GET /api/belvo/mock-data - Get mock financial data for testing",
+				"PUT /api/belvo/links/{link_id} - Refresh a link (may require an MFA token)",
+				"PATCH /api/belvo/links/{link_id}/token - Resume a link with an MFA token",
+				"DELETE /api/belvo/links/{link_id} - Delete a link",
+				"POST /api/belvo/webhooks - Receive Belvo webhooks",
+				"GET /api/belvo/webhooks/events - List received Belvo webhooks",
+				"POST /api/belvo/ofda/widget-token - Open Finance widget token with consent fields",
+				"GET /api/belvo/ofda/callback - Widget callback with the link and consent IDs",
+				"GET /api/belvo/ofda/consents?customer_id= - Tracked consents and their expiry",
+				"POST /api/belvo/ofda/consents/{consent_id}/renew - Widget token to renew a consent",
+				"GET /api/belvo/ofda/reminders?customer_id= - Consent renewal reminders",
 				"-- Market Data API (Phase 2) --",
 				"GET /api/market/assets - Get available assets",
 				"GET /api/market/assets/{symbol} - Get asset performance",
-				"GET /api/market/data - Get market data summary",
+				"GET /api/market/data?currency= - Get market data summary, optionally priced in one currency",
 				"GET /api/market/brazilian-rates - Get Brazilian economic rates",
 				"GET /api/market/portfolio-templates - Get portfolio templates",
 				"GET /api/market/portfolio-templates/{risk_level} - Get specific portfolio template",
@@ -54,6 +80,11 @@ func RegisterRoutes(app *gofr.App) {
 				"GET /api/ai/mock-analysis - AI analysis with mock data",
 				"GET /api/ai/advice - General investment advice",
 				"POST /api/ai/chat - Conversational AI chat",
+				"POST /api/ai/chat/stream - Conversational AI chat streamed as Server-Sent Events",
+				"GET /api/ai/conversations - List stored chat conversations",
+				"GET /api/ai/conversations/{conversation_id} - Get a conversation with its messages",
+				"PATCH /api/ai/conversations/{conversation_id} - Rename a conversation",
+				"DELETE /api/ai/conversations/{conversation_id} - Delete a conversation",
 			},
 		}, nil
 	})
@@ -62,6 +93,7 @@ func RegisterRoutes(app *gofr.App) {
 	secretID := os.Getenv("BELVO_SECRET_ID")
 	secretKey := os.Getenv("BELVO_SECRET_PASSWORD")
 	environment := os.Getenv("BELVO_ENVIRONMENT")
+	baseURL := os.Getenv("BELVO_BASE_URL") // Optional override, e.g. http://localhost:9090 for cmd/fakebelvo
 
 	fmt.Printf("🔧 Environment Variables:\n")
 	fmt.Printf("   BELVO_SECRET_ID: %s\n", func() string {
@@ -85,6 +117,9 @@ func RegisterRoutes(app *gofr.App) {
 			return "NOT SET (will default to sandbox)"
 		}
 	}())
+	if baseURL != "" {
+		fmt.Printf("   BELVO_BASE_URL: %s\n", baseURL)
+	}
 
 	// Get test credentials from environment
 	testSecretID := os.Getenv("BELVO_TEST_SECRET_ID")
@@ -109,28 +144,261 @@ func RegisterRoutes(app *gofr.App) {
 		secretKey = testSecretKey
 	}
 
-	belvoHandler := api.NewBelvoHandler(secretID, secretKey, environment)
+	// Optional cap on concurrent Belvo calls per secret ID (sandbox throttles aggressively)
+	if maxConcurrent, err := strconv.Atoi(os.Getenv("BELVO_MAX_CONCURRENT_REQUESTS")); err == nil && maxConcurrent > 0 {
+		transportOptions := service.DefaultTransportOptions
+		transportOptions.MaxConcurrentPerSecret = maxConcurrent
+		service.ConfigureBelvoTransport(transportOptions)
+		fmt.Printf("   BELVO_MAX_CONCURRENT_REQUESTS: %d\n", maxConcurrent)
+	}
+
+	// Local transaction store: later syncs only fetch new transactions
+	transactionStoreDir := os.Getenv("TRANSACTION_STORE_DIR")
+	if transactionStoreDir == "" {
+		transactionStoreDir = "data/transactions"
+	}
+	if store, err := service.NewFileTransactionStore(transactionStoreDir); err != nil {
+		fmt.Printf("⚠️ Transaction sync disabled: %v\n", err)
+	} else {
+		service.ConfigureTransactionSync(store, service.DefaultSyncOptions)
+		fmt.Printf("   TRANSACTION_STORE_DIR: %s\n", transactionStoreDir)
+	}
+
+	// FX rates for reporting currencies: official sources by default, fixed stand-in rates offline
+	switch fxSource := os.Getenv("FX_RATES_SOURCE"); fxSource {
+	case "local":
+		service.ConfigureFX(service.NewLocalFXService())
+		fmt.Printf("   FX_RATES_SOURCE: local (fixed stand-in rates)\n")
+	case "", "live":
+		service.ConfigureFX(service.NewLiveFXService(os.Getenv("BANXICO_API_TOKEN")))
+		fmt.Printf("   FX_RATES_SOURCE: live (PTAX, TRM%s)\n", func() string {
+			if os.Getenv("BANXICO_API_TOKEN") != "" {
+				return ", Banxico FIX"
+			}
+			return ""
+		}())
+	default:
+		fmt.Printf("⚠️ Unknown FX_RATES_SOURCE %q, using live rates\n", fxSource)
+		service.ConfigureFX(service.NewLiveFXService(os.Getenv("BANXICO_API_TOKEN")))
+	}
+
+	// Institution lists are cached per Belvo account; INSTITUTION_CACHE_TTL=0 disables the cache
+	if value := os.Getenv("INSTITUTION_CACHE_TTL"); value != "" {
+		if ttl, err := time.ParseDuration(value); err != nil {
+			fmt.Printf("⚠️ Invalid INSTITUTION_CACHE_TTL %q, keeping %s\n", value, service.DefaultInstitutionCacheTTL)
+		} else {
+			service.ConfigureInstitutionCache(ttl)
+			fmt.Printf("   INSTITUTION_CACHE_TTL: %s\n", ttl)
+		}
+	}
+
+	// Sessions: Belvo secrets are sent once to /api/auth/login and kept encrypted in memory;
+	// every other route needs the session token as "Authorization: Bearer <token>". The
+	// session key also seals the credentials kept for webhook refreshes and consent renewals.
+	sessionSecret := os.Getenv("SESSION_SECRET")
+	if sessionSecret == "" {
+		fmt.Println("⚠️ SESSION_SECRET not set - using a random key, sessions end when the server restarts")
+	}
+	sessionTTL := service.DefaultSessionTTL
+	if value := os.Getenv("SESSION_TTL"); value != "" {
+		if ttl, err := time.ParseDuration(value); err != nil || ttl <= 0 {
+			fmt.Printf("⚠️ Invalid SESSION_TTL %q, keeping %s\n", value, sessionTTL)
+		} else {
+			sessionTTL = ttl
+		}
+	}
+	fmt.Printf("   SESSION_TTL: %s\n", sessionTTL)
+	sessions, err := service.NewSessionManager(sessionSecret, sessionTTL)
+	if err != nil {
+		panic(fmt.Sprintf("failed to initialize sessions: %v", err))
+	}
+
+	belvoHandler := api.NewBelvoHandler(secretID, secretKey, environment, baseURL)
+	belvoHandler.GetClients().SetSessions(sessions)
+
+	// Environments a request may select via its "environment" field (defaults to all known)
+	if allowed := os.Getenv("BELVO_ALLOWED_ENVIRONMENTS"); allowed != "" {
+		belvoHandler.GetClients().SetAllowedEnvironments(strings.Split(allowed, ","))
+	}
+	fmt.Printf("   Allowed Belvo environments: %v\n", belvoHandler.GetClients().AllowedEnvironments())
+
+	// Requests without a session and the "test" credential modes may read with the
+	// server's own credentials only when this is explicitly enabled, in the sandbox
+	if allow, _ := strconv.ParseBool(os.Getenv("ALLOW_SERVER_BELVO_CREDENTIALS")); allow {
+		if err := belvoHandler.GetClients().SetAllowServerCredentials(true); err != nil {
+			fmt.Printf("⚠️ Ignoring ALLOW_SERVER_BELVO_CREDENTIALS: %v\n", err)
+		} else {
+			fmt.Println("⚠️ ALLOW_SERVER_BELVO_CREDENTIALS set - requests without a session use the server's Belvo credentials")
+		}
+	}
 
 	// Initialize Market handler
 	marketHandler := api.NewMarketHandler()
 
-	// Initialize AI handler with OpenAI API key (optional)
+	// Language model: LLM_PROVIDER picks openai, anthropic or local (any OpenAI-compatible
+	// server such as Ollama or llama.cpp); without one, chat runs in mock mode
 	openAIAPIKey := os.Getenv("OPENAI_API_KEY")
-	if openAIAPIKey != "" {
-		fmt.Printf("✅ OpenAI API key loaded: %s...\n", openAIAPIKey[:20])
+	anthropicAPIKey := os.Getenv("ANTHROPIC_API_KEY")
+	localLLM := service.NewLocalLLMProvider(os.Getenv("LOCAL_LLM_BASE_URL"), os.Getenv("LOCAL_LLM_API_KEY"), os.Getenv("LOCAL_LLM_MODEL"))
+
+	llmProvider := os.Getenv("LLM_PROVIDER")
+	if llmProvider == "" && openAIAPIKey != "" {
+		llmProvider = "openai"
+	}
+	var llm *service.LLMConfig
+	switch llmProvider {
+	case "openai":
+		if openAIAPIKey == "" {
+			fmt.Println("❌ LLM_PROVIDER=openai but OPENAI_API_KEY is not set - chat runs in mock mode")
+			break
+		}
+		fmt.Printf("✅ OpenAI API key loaded: %s...\n", openAIAPIKey[:min(len(openAIAPIKey), 20)])
+		llm = service.NewLLMConfig(service.NewOpenAIProvider(openAIAPIKey))
+	case "anthropic":
+		if anthropicAPIKey == "" {
+			fmt.Println("❌ LLM_PROVIDER=anthropic but ANTHROPIC_API_KEY is not set - chat runs in mock mode")
+			break
+		}
+		fmt.Println("✅ Anthropic API key loaded")
+		llm = service.NewLLMConfig(service.NewAnthropicProvider(anthropicAPIKey))
+	case "local":
+		llm = service.NewLLMConfig(localLLM)
+	case "":
+		fmt.Println("❌ No language model configured (set LLM_PROVIDER or OPENAI_API_KEY) - chat runs in mock mode")
+	default:
+		fmt.Printf("❌ Unknown LLM_PROVIDER %q - chat runs in mock mode\n", llmProvider)
+	}
+	if llm != nil {
+		llm.Chat = llmSettingsFromEnv("LLM_CHAT", llm.Chat)
+		llm.Summary = llmSettingsFromEnv("LLM_SUMMARY", llm.Summary)
+		fmt.Printf("   LLM_PROVIDER: %s (chat %+v, summary %+v)\n", llm.Provider.Name(), llm.Settings(service.LLMUseChat), llm.Settings(service.LLMUseSummary))
+	}
+
+	// Chat conversations: in memory unless a database is configured
+	var conversationStore service.ConversationStore = service.NewMemoryConversationStore()
+	if driver := os.Getenv("CONVERSATION_DB_DRIVER"); driver != "" {
+		if store, err := openConversationStore(driver, os.Getenv("CONVERSATION_DB_DSN")); err != nil {
+			fmt.Printf("⚠️ Conversations kept in memory only: %v\n", err)
+		} else {
+			conversationStore = store
+			fmt.Printf("   CONVERSATION_DB_DRIVER: %s\n", driver)
+		}
 	} else {
-		fmt.Println("❌ OpenAI API key not found in environment")
+		fmt.Println("⚠️ CONVERSATION_DB_DRIVER not set - conversations are kept in memory and lost on restart")
 	}
-	aiHandler := api.NewAIHandler(openAIAPIKey, belvoHandler.GetBelvoService(), marketHandler.GetMarketService())
+	aiHandler := api.NewAIHandler(llm, belvoHandler.GetClients(), marketHandler.GetMarketService(), conversationStore)
+	conversationHandler := api.NewConversationHandler(conversationStore)
+
+	// Customers whose data must stay on our infrastructure always use the self-hosted model
+	if links := os.Getenv("SELF_HOSTED_LLM_LINKS"); links != "" {
+		selfHosted := service.NewLLMConfig(localLLM)
+		count := 0
+		for _, linkID := range strings.Split(links, ",") {
+			if linkID = strings.TrimSpace(linkID); linkID != "" {
+				aiHandler.GetAIService().SetCustomerLLM(linkID, selfHosted)
+				count++
+			}
+		}
+		fmt.Printf("   SELF_HOSTED_LLM_LINKS: %d links on %s (%s)\n", count, localLLM.Name(), localLLM.DefaultModel())
+	}
+
+	// Initialize webhook handler - keeps cached chat context in sync with Belvo background refreshes
+	webhookHandler := api.NewWebhookHandler(aiHandler)
+	// Unverified webhooks are only accepted by a server that can't reach production
+	app.UseMiddleware(api.WebhookAuthMiddleware("/api/belvo/webhooks", os.Getenv("BELVO_WEBHOOK_SECRET"), belvoHandler.GetClients().SandboxOnly()))
+
+	// Open Finance consents: tracked until expiry, with renewal reminders checked hourly
+	consentStoreFile := os.Getenv("CONSENT_STORE_FILE")
+	if consentStoreFile == "" {
+		consentStoreFile = "data/consents.json"
+	}
+	var consentStore service.ConsentStore
+	if store, err := service.NewFileConsentStore(consentStoreFile); err != nil {
+		fmt.Printf("⚠️ Consents kept in memory only: %v\n", err)
+	} else {
+		consentStore = store
+		fmt.Printf("   CONSENT_STORE_FILE: %s\n", consentStoreFile)
+	}
+	consentTracker, err := service.NewConsentTracker(consentStore)
+	if err != nil {
+		fmt.Printf("⚠️ Ignoring stored consents: %v\n", err)
+		consentTracker, _ = service.NewConsentTracker(nil)
+	}
+	go consentTracker.RunReminders(context.Background(), time.Hour)
+
+	// The widget redirects the user back to this API when the consent flow ends
+	ofdaCallbackBaseURL := os.Getenv("OFDA_CALLBACK_BASE_URL")
+	if ofdaCallbackBaseURL == "" {
+		ofdaCallbackBaseURL = "http://localhost:8000"
+	}
+	fmt.Printf("   OFDA_CALLBACK_BASE_URL: %s\n", ofdaCallbackBaseURL)
+	ofdaHandler := api.NewOFDAHandler(belvoHandler.GetClients(), consentTracker, ofdaCallbackBaseURL)
+
+	// Per-route deadlines; client disconnects cancel the same request context
+	app.UseMiddleware(api.RequestTimeoutMiddleware([]api.RouteTimeout{
+		{Prefix: "/api/belvo/", Timeout: 60 * time.Second},
+		{Prefix: "/api/belvo/links/detailed-info/", Timeout: 45 * time.Second},
+		{Prefix: "/api/belvo/transactions/", Timeout: 10 * time.Minute}, // NDJSON exports of long histories
+		{Prefix: "/api/market/", Timeout: 30 * time.Second},
+		{Prefix: "/api/ai/", Timeout: 90 * time.Second},
+	}))
+
+	authHandler := api.NewAuthHandler(belvoHandler.GetClients(), sessions)
+	app.UseMiddleware(api.SessionAuthMiddleware(sessions, []string{
+		"/",
+		"/health",
+		"/.well-known/health",
+		"/.well-known/alive",
+		"/api/auth/login",
+		"/api/belvo/webhooks",      // Verified by WebhookAuthMiddleware
+		"/api/belvo/ofda/callback", // Reached from the widget in the user's browser, verified by its state
+	}))
+
+	// Transaction exports and chat answers stream straight from the middleware chain, inside the deadline above
+	app.UseMiddleware(api.TransactionStreamMiddleware(belvoHandler.GetClients()))
+	app.UseMiddleware(api.ChatStreamMiddleware(aiHandler))
 
 	// Set test credentials for belvo handler
 	belvoHandler.SetTestCredentials(testSecretID, testSecretKey)
 
+	// Deleted links must not keep serving cached chat context
+	belvoHandler.SetLinkRemovedHook(func(linkID string) {
+		aiHandler.InvalidateContext(linkID)
+	})
+
+	// Session routes
+	app.POST("/api/auth/login", authHandler.Login)
+	app.POST("/api/auth/logout", authHandler.Logout)
+	app.GET("/api/auth/session", authHandler.GetSession)
+
 	// Core Belvo API routes
 	app.POST("/api/belvo/test-connection", belvoHandler.TestConnection)
+	app.GET("/api/belvo/institutions", belvoHandler.GetInstitutions)
 	app.POST("/api/belvo/create-erebor-link", belvoHandler.CreateEreborLink)
 	app.POST("/api/belvo/links/for-selection", belvoHandler.GetLinksForSelection)
 	app.POST("/api/belvo/links/detailed-info/{link_id}", belvoHandler.GetDetailedLinkInfo)
+	app.GET("/api/belvo/financial-summary/{link_id}", belvoHandler.GetFinancialSummary)
+	app.POST("/api/belvo/financial-summary/with-credentials", belvoHandler.GetFinancialSummaryWithCredentials)
+	app.POST("/api/belvo/financial-summary/consolidated", belvoHandler.GetConsolidatedFinancialSummary)
+	app.POST("/api/belvo/sync/{link_id}", belvoHandler.SyncTransactions)
+	app.GET("/api/belvo/balances/{link_id}", belvoHandler.GetBalanceHistory)
+	app.GET("/api/belvo/transactions/{link_id}/stream", api.StreamTransactionsRoute) // Served by TransactionStreamMiddleware
+
+	// Link lifecycle routes
+	app.PUT("/api/belvo/links/{link_id}", belvoHandler.RefreshLink)
+	app.PATCH("/api/belvo/links/{link_id}/token", belvoHandler.ResumeLink)
+	app.DELETE("/api/belvo/links/{link_id}", belvoHandler.DeleteLink)
+
+	// Belvo webhook routes
+	app.POST("/api/belvo/webhooks", webhookHandler.HandleWebhook)
+	app.GET("/api/belvo/webhooks/events", webhookHandler.ListEvents)
+
+	// Open Finance Brazil consent routes
+	app.POST("/api/belvo/ofda/widget-token", ofdaHandler.CreateWidgetToken)
+	app.GET("/api/belvo/ofda/callback", ofdaHandler.Callback)
+	app.GET("/api/belvo/ofda/consents", ofdaHandler.ListConsents)
+	app.POST("/api/belvo/ofda/consents/{consent_id}/renew", ofdaHandler.RenewConsent)
+	app.GET("/api/belvo/ofda/reminders", ofdaHandler.ListReminders)
 
 	// Development/debugging routes
 	app.POST("/api/belvo/verify-data/{link_id}", belvoHandler.VerifyLinkData)
@@ -145,5 +413,58 @@ func RegisterRoutes(app *gofr.App) {
 
 	// AI Financial Coach API routes
 	app.POST("/api/ai/chat", aiHandler.Chat)
+	app.POST("/api/ai/chat/stream", api.ChatStreamRoute) // Served by ChatStreamMiddleware
 	app.POST("/api/ai/cache-context", aiHandler.CacheContextFromSummary)
+	app.GET("/api/ai/conversations", conversationHandler.ListConversations)
+	app.GET("/api/ai/conversations/{conversation_id}", conversationHandler.GetConversation)
+	app.PATCH("/api/ai/conversations/{conversation_id}", conversationHandler.RenameConversation)
+	app.DELETE("/api/ai/conversations/{conversation_id}", conversationHandler.DeleteConversation)
+}
+
+// openConversationStore connects to the conversation database. gofr's SQL datasource
+// registers the sqlite, postgres and mysql drivers. SQLite defaults to a file under data/.
+func openConversationStore(driver, dsn string) (*service.SQLConversationStore, error) {
+	if dsn == "" {
+		if driver != "sqlite" {
+			return nil, fmt.Errorf("CONVERSATION_DB_DSN is required for %s", driver)
+		}
+		dsn = "data/conversations.db"
+		if err := os.MkdirAll(filepath.Dir(dsn), 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(dsn), err)
+		}
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", driver, err)
+	}
+	store, err := service.NewSQLConversationStore(db, driver)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// llmSettingsFromEnv overrides settings with <prefix>_MODEL, <prefix>_TEMPERATURE and
+// <prefix>_MAX_TOKENS where they are set and valid
+func llmSettingsFromEnv(prefix string, settings service.LLMSettings) service.LLMSettings {
+	if model := os.Getenv(prefix + "_MODEL"); model != "" {
+		settings.Model = model
+	}
+	if value := os.Getenv(prefix + "_TEMPERATURE"); value != "" {
+		if temperature, err := strconv.ParseFloat(value, 64); err == nil && temperature >= 0 && temperature <= 2 {
+			settings.Temperature = temperature
+		} else {
+			fmt.Printf("⚠️ Invalid %s_TEMPERATURE %q, keeping %.1f\n", prefix, value, settings.Temperature)
+		}
+	}
+	if value := os.Getenv(prefix + "_MAX_TOKENS"); value != "" {
+		if maxTokens, err := strconv.Atoi(value); err == nil && maxTokens > 0 {
+			settings.MaxTokens = maxTokens
+		} else {
+			fmt.Printf("⚠️ Invalid %s_MAX_TOKENS %q, keeping %d\n", prefix, value, settings.MaxTokens)
+		}
+	}
+	return settings
 }
@@ -0,0 +1,538 @@
+package belvofake
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// Persona is a fixture customer: one link with its owner, accounts and financial history
+type Persona struct {
+	Link              models.BelvoLink
+	Owner             models.BelvoOwner
+	Accounts          []models.BelvoAccount
+	Transactions      []models.BelvoTransaction
+	Incomes           []models.BelvoIncome
+	RecurringExpenses []models.BelvoRecurringExpense
+	Investments       []models.BelvoInvestmentPortfolio
+	Loans             []models.BelvoLoan
+
+	spec personaSpec
+	now  time.Time
+}
+
+// Fixed link IDs for the default personas so they can be referenced from scripts and the frontend
+const (
+	SalariedLinkID   = "a1b2c3d4-1111-4111-8111-000000000001"
+	FreelancerLinkID = "a1b2c3d4-2222-4222-8222-000000000002"
+	StudentLinkID    = "a1b2c3d4-3333-4333-8333-000000000003"
+)
+
+// historyDays is how much transaction history each persona gets
+const historyDays = 180
+
+type incomeSpec struct {
+	description string
+	amount      float64
+	day         int
+}
+
+type recurringSpec struct {
+	description string
+	merchant    string
+	category    string
+	amount      float64
+	day         int
+}
+
+type variableSpec struct {
+	description string
+	merchant    string
+	category    string
+	amount      float64
+	everyDays   int
+}
+
+// transferSpec is a monthly move from checking to savings, which shows up as a
+// matching outflow/inflow pair between the persona's own accounts
+type transferSpec struct {
+	amount float64
+	day    int
+}
+
+type instrumentSpec struct {
+	instrumentType string
+	code           string
+	name           string
+	quantity       float64
+	price          float64
+}
+
+type portfolioSpec struct {
+	name          string
+	portfolioType string
+	instruments   []instrumentSpec
+}
+
+type loanSpec struct {
+	name              string
+	loanType          string
+	contractAmount    float64
+	outstanding       float64
+	monthlyPayment    float64
+	monthlyRate       float64 // In percent
+	installmentsTotal int
+	installmentsLeft  int
+}
+
+type personaSpec struct {
+	linkID          string
+	institution     string
+	ownerName       string
+	email           string
+	document        string
+	incomeType      string
+	incomes         []incomeSpec
+	recurring       []recurringSpec
+	variable        []variableSpec
+	savingsTransfer transferSpec
+	portfolios      []portfolioSpec
+	loans           []loanSpec
+	checkingOpening float64
+	savingsBalance  float64
+	creditLimit     float64
+	creditUsed      float64
+	creditRevolving float64 // Part of creditUsed carried over from unpaid statements
+}
+
+// DefaultPersonas returns the built-in fixture personas with history ending at now
+func DefaultPersonas(now time.Time) []*Persona {
+	specs := []personaSpec{
+		{
+			linkID:      SalariedLinkID,
+			institution: "erebor_br_retail",
+			ownerName:   "Ana Souza",
+			email:       "ana.souza@example.com",
+			document:    "123.456.789-01",
+			incomeType:  "SALARY",
+			incomes: []incomeSpec{
+				{description: "SALARIO ACME LTDA", amount: 8500, day: 5},
+			},
+			recurring: []recurringSpec{
+				{description: "ALUGUEL APTO", merchant: "Imobiliaria Lar", category: "Housing & Utilities", amount: 2400, day: 7},
+				{description: "ENEL ENERGIA", merchant: "Enel", category: "Housing & Utilities", amount: 210, day: 12},
+				{description: "NETFLIX.COM", merchant: "Netflix", category: "Subscriptions", amount: 55.9, day: 15},
+				{description: "SMART FIT", merchant: "Smart Fit", category: "Personal Shopping", amount: 119.9, day: 10},
+				{description: "FINANCIAMENTO VEICULO", merchant: "Erebor Financiamentos", category: "Credits & Loans", amount: 980, day: 20},
+			},
+			variable: []variableSpec{
+				{description: "PAO DE ACUCAR", merchant: "Pão de Açúcar", category: "Food & Groceries", amount: 310, everyDays: 7},
+				{description: "IFOOD *RESTAURANTE", merchant: "iFood", category: "Food & Groceries", amount: 68, everyDays: 3},
+				{description: "UBER *TRIP", merchant: "Uber", category: "Transport & Travel", amount: 27, everyDays: 2},
+			},
+			savingsTransfer: transferSpec{amount: 1000, day: 6},
+			portfolios: []portfolioSpec{
+				{name: "Renda Fixa", portfolioType: "FIXED_INCOME", instruments: []instrumentSpec{
+					{instrumentType: "BOND", code: "LFT-2029", name: "Tesouro Selic 2029", quantity: 1.2, price: 14500},
+					{instrumentType: "BOND", code: "CDB-EREBOR", name: "CDB Erebor 110% CDI", quantity: 1, price: 10000},
+				}},
+				{name: "Renda Variável", portfolioType: "EQUITY", instruments: []instrumentSpec{
+					{instrumentType: "ETF", code: "BOVA11", name: "iShares Ibovespa", quantity: 80, price: 128.5},
+					{instrumentType: "STOCK", code: "PETR4", name: "Petrobras PN", quantity: 100, price: 38.2},
+				}},
+			},
+			loans: []loanSpec{
+				{name: "Financiamento de veículo", loanType: "VEHICLE", contractAmount: 42000, outstanding: 18400, monthlyPayment: 980, monthlyRate: 0.79, installmentsTotal: 48, installmentsLeft: 20},
+			},
+			checkingOpening: 18500,
+			savingsBalance:  42000,
+			creditLimit:     12000,
+			creditUsed:      3150.75,
+		},
+		{
+			linkID:      FreelancerLinkID,
+			institution: "erebor_br_retail",
+			ownerName:   "Bruno Lima",
+			email:       "bruno.lima@example.com",
+			document:    "987.654.321-00",
+			incomeType:  "FREELANCE",
+			incomes: []incomeSpec{
+				{description: "PIX RECEBIDO CLIENTE A", amount: 4200, day: 3},
+				{description: "PIX RECEBIDO CLIENTE B", amount: 2800, day: 18},
+			},
+			recurring: []recurringSpec{
+				{description: "ALUGUEL COWORKING", merchant: "WeWork", category: "Business", amount: 890, day: 5},
+				{description: "ALUGUEL CASA", merchant: "Quinto Andar", category: "Housing & Utilities", amount: 1950, day: 10},
+				{description: "SPOTIFY", merchant: "Spotify", category: "Subscriptions", amount: 21.9, day: 20},
+			},
+			variable: []variableSpec{
+				{description: "CARREFOUR", merchant: "Carrefour", category: "Food & Groceries", amount: 420, everyDays: 10},
+				{description: "POSTO SHELL", merchant: "Shell", category: "Transport & Travel", amount: 230, everyDays: 9},
+				{description: "MERCADO LIVRE", merchant: "Mercado Livre", category: "Online Platforms & Leisure", amount: 180, everyDays: 14},
+			},
+			savingsTransfer: transferSpec{amount: 500, day: 20},
+			portfolios: []portfolioSpec{
+				{name: "Cripto", portfolioType: "CRYPTO", instruments: []instrumentSpec{
+					{instrumentType: "CRYPTO", code: "BTC", name: "Bitcoin", quantity: 0.02, price: 350000},
+				}},
+			},
+			checkingOpening: 9800,
+			savingsBalance:  6500,
+			creditLimit:     8000,
+			creditUsed:      5420.10,
+			creditRevolving: 3020.10,
+		},
+		{
+			linkID:      StudentLinkID,
+			institution: "erebor_br_retail",
+			ownerName:   "Carla Mendes",
+			email:       "carla.mendes@example.com",
+			document:    "111.222.333-44",
+			incomeType:  "SCHOLARSHIP",
+			incomes: []incomeSpec{
+				{description: "BOLSA CNPQ", amount: 2200, day: 1},
+			},
+			recurring: []recurringSpec{
+				{description: "REPUBLICA ALUGUEL", merchant: "República", category: "Housing & Utilities", amount: 900, day: 5},
+				{description: "BILHETE UNICO", merchant: "SPTrans", category: "Transport & Travel", amount: 150, day: 2},
+			},
+			variable: []variableSpec{
+				{description: "RESTAURANTE UNIVERSITARIO", merchant: "USP", category: "Food & Groceries", amount: 15, everyDays: 1},
+				{description: "LIVRARIA CULTURA", merchant: "Livraria Cultura", category: "Personal Shopping", amount: 85, everyDays: 21},
+			},
+			checkingOpening: 1200,
+			savingsBalance:  800,
+			creditLimit:     1500,
+			creditUsed:      320,
+		},
+	}
+
+	personas := make([]*Persona, 0, len(specs))
+	for _, spec := range specs {
+		personas = append(personas, buildPersona(spec, now))
+	}
+	return personas
+}
+
+// DefaultInstitutions returns the institutions listed by the fake server
+func DefaultInstitutions() []models.BelvoInstitution {
+	scraped := []string{"ACCOUNTS", "TRANSACTIONS", "OWNERS", "BALANCES", "INCOMES", "RECURRING_EXPENSES", "INVESTMENTS", "LOANS"}
+	openFinance := []string{"ACCOUNTS", "TRANSACTIONS", "OWNERS", "BALANCES", "LOANS", "CONSENTS"}
+	return []models.BelvoInstitution{
+		{ID: 1, Name: "erebor_br_retail", Type: "bank", Code: "erebor", DisplayName: "Erebor Brazil", CountryCode: "BR", CountryCodes: []string{"BR"}, Website: "https://erebor.example.com", PrimaryColor: "#056dae", Logo: "https://statics.belvo.io/widget/images/institutions/erebor.svg", IntegrationType: "credentials", Resources: scraped, Status: "healthy"},
+		{ID: 2, Name: "ofmockbank_br_retail", Type: "bank", Code: "ofmockbank", DisplayName: "Open Finance Mock Bank", CountryCode: "BR", CountryCodes: []string{"BR"}, Website: "https://ofmockbank.example.com", PrimaryColor: "#1f2a44", Logo: "https://statics.belvo.io/widget/images/institutions/ofmockbank.svg", IntegrationType: "openfinance", Resources: openFinance, Status: "healthy"},
+		{ID: 3, Name: "caixa_br_retail", Type: "bank", Code: "caixa", DisplayName: "Caixa Econômica", CountryCode: "BR", CountryCodes: []string{"BR"}, Website: "https://caixa.example.com", PrimaryColor: "#005ca9", Logo: "https://statics.belvo.io/widget/images/institutions/caixa.svg", IntegrationType: "openfinance", Resources: openFinance, Status: "down"},
+		{ID: 4, Name: "planet_mx_retail", Type: "bank", Code: "planet", DisplayName: "Planet Mexico", CountryCode: "MX", CountryCodes: []string{"MX"}, Website: "https://planet.example.com", PrimaryColor: "#f5a623", Logo: "https://statics.belvo.io/widget/images/institutions/planet.svg", IntegrationType: "credentials", Resources: scraped, Status: "healthy"},
+		{ID: 5, Name: "sat_mx_fiscal", Type: "fiscal", Code: "sat", DisplayName: "Servicio de Administración Tributaria", CountryCode: "MX", CountryCodes: []string{"MX"}, Website: "https://sat.example.com", PrimaryColor: "#6a1b31", Logo: "https://statics.belvo.io/widget/images/institutions/sat.svg", IntegrationType: "credentials", Resources: []string{"INVOICES", "TAX_RETURNS", "TAX_STATUS"}, Status: "healthy"},
+		{ID: 6, Name: "gringotts_co_retail", Type: "bank", Code: "gringotts", DisplayName: "Gringotts Colombia", CountryCode: "CO", CountryCodes: []string{"CO"}, Website: "https://gringotts.example.com", PrimaryColor: "#8b0000", Logo: "https://statics.belvo.io/widget/images/institutions/gringotts.svg", IntegrationType: "credentials", Resources: scraped, Status: "healthy"},
+	}
+}
+
+// buildPersona generates a deterministic financial history from a spec
+func buildPersona(spec personaSpec, now time.Time) *Persona {
+	now = now.UTC()
+	idPrefix := spec.linkID[len(spec.linkID)-8:]
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	start := today.AddDate(0, 0, -historyDays)
+	createdAt := models.BelvoTime(start)
+	collectedAt := models.BelvoTime(now)
+
+	institution := institutionByName(spec.institution)
+	link := models.BelvoLink{
+		ID:                  spec.linkID,
+		Institution:         spec.institution,
+		AccessMode:          "recurrent",
+		Status:              "valid",
+		CreatedAt:           start,
+		ExternalID:          strings.ToLower(strings.ReplaceAll(spec.ownerName, " ", "-")),
+		RefreshRate:         "24h",
+		FetchHistoricalData: true,
+	}
+
+	checking := models.BelvoAccount{
+		ID:          idPrefix + "-acc-checking",
+		Link:        spec.linkID,
+		Institution: institution,
+		CollectedAt: collectedAt,
+		CreatedAt:   createdAt,
+		Category:    "CHECKING_ACCOUNT",
+		Type:        "Conta corrente",
+		Number:      "0001-" + spec.linkID[len(spec.linkID)-4:],
+		Name:        "Conta corrente",
+		Currency:    "BRL",
+		BalanceType: "ASSET",
+	}
+	savings := models.BelvoAccount{
+		ID:          idPrefix + "-acc-savings",
+		Link:        spec.linkID,
+		Institution: institution,
+		CollectedAt: collectedAt,
+		CreatedAt:   createdAt,
+		Category:    "SAVINGS_ACCOUNT",
+		Type:        "Poupança",
+		Number:      "0002-" + spec.linkID[len(spec.linkID)-4:],
+		Name:        "Poupança",
+		Currency:    "BRL",
+		Balance:     models.BelvoBalance{Current: spec.savingsBalance, Available: spec.savingsBalance},
+		BalanceType: "ASSET",
+	}
+	creditCard := models.BelvoAccount{
+		ID:          idPrefix + "-acc-credit",
+		Link:        spec.linkID,
+		Institution: institution,
+		CollectedAt: collectedAt,
+		CreatedAt:   createdAt,
+		Category:    "CREDIT_CARD",
+		Type:        "Cartão de crédito",
+		Number:      "**** " + spec.linkID[len(spec.linkID)-4:],
+		Name:        "Cartão Platinum",
+		Currency:    "BRL",
+		Balance:     models.BelvoBalance{Current: spec.creditUsed, Available: spec.creditLimit - spec.creditUsed},
+		BalanceType: "LIABILITY",
+		CreditData: &models.BelvoCreditData{
+			CreditLimit:       spec.creditLimit,
+			CuttingDate:       time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC).Format("2006-01-02"),
+			NextPaymentDate:   time.Date(today.Year(), today.Month(), 10, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0).Format("2006-01-02"),
+			MinimumPayment:    roundCents(spec.creditUsed * 0.15),
+			NoInterestPayment: roundCents(spec.creditUsed - spec.creditRevolving),
+			LastPeriodBalance: spec.creditUsed,
+			InterestRate:      380, // Typical Brazilian revolving card rate
+		},
+	}
+
+	checkingRef := accountReference(checking)
+	savingsRef := accountReference(savings)
+
+	// Savings ends at spec.savingsBalance after the monthly transfers
+	savingsBalance := spec.savingsBalance
+	if spec.savingsTransfer.amount > 0 {
+		for dayIndex := 0; dayIndex <= historyDays; dayIndex++ {
+			if start.AddDate(0, 0, dayIndex).Day() == spec.savingsTransfer.day {
+				savingsBalance -= spec.savingsTransfer.amount
+			}
+		}
+	}
+
+	var transactions []models.BelvoTransaction
+	balance := spec.checkingOpening
+	addTransaction := func(accountRef map[string]interface{}, running *float64, date time.Time, description, merchant, category, txType string, amount float64) {
+		if txType == "INFLOW" {
+			*running += amount
+		} else {
+			*running -= amount
+		}
+
+		var merchantInfo *models.BelvoMerchant
+		if merchant != "" {
+			merchantInfo = &models.BelvoMerchant{Name: merchant}
+		}
+
+		transactions = append(transactions, models.BelvoTransaction{
+			ID:                     fmt.Sprintf("%s-tx-%05d", idPrefix, len(transactions)+1),
+			Account:                accountRef,
+			CollectedAt:            collectedAt,
+			CreatedAt:              models.BelvoTime(date),
+			ValueDate:              date.Format("2006-01-02"),
+			AccountingDate:         models.BelvoTime(date.Add(12 * time.Hour)),
+			Amount:                 roundCents(amount),
+			Balance:                roundCents(*running),
+			Currency:               "BRL",
+			Description:            description,
+			Merchant:               merchantInfo,
+			Category:               category,
+			Reference:              fmt.Sprintf("REF%08d", len(transactions)+1),
+			Type:                   txType,
+			Status:                 "PROCESSED",
+			InternalIdentification: fmt.Sprintf("%s-%d", idPrefix, len(transactions)+1),
+		})
+	}
+
+	for dayIndex := 0; dayIndex <= historyDays; dayIndex++ {
+		date := start.AddDate(0, 0, dayIndex)
+
+		for _, income := range spec.incomes {
+			if date.Day() == income.day {
+				addTransaction(checkingRef, &balance, date, income.description, "", "Income & Payments", "INFLOW", income.amount)
+			}
+		}
+		for _, expense := range spec.recurring {
+			if date.Day() == expense.day {
+				addTransaction(checkingRef, &balance, date, expense.description, expense.merchant, expense.category, "OUTFLOW", expense.amount)
+			}
+		}
+		for i, expense := range spec.variable {
+			if expense.everyDays > 0 && (dayIndex+i)%expense.everyDays == 0 {
+				addTransaction(checkingRef, &balance, date, expense.description, expense.merchant, expense.category, "OUTFLOW", expense.amount*variation(dayIndex+i))
+			}
+		}
+		if spec.savingsTransfer.amount > 0 && date.Day() == spec.savingsTransfer.day {
+			addTransaction(checkingRef, &balance, date, "TRANSF PARA POUPANCA", "", "Transfers", "OUTFLOW", spec.savingsTransfer.amount)
+			addTransaction(savingsRef, &savingsBalance, date, "TRANSF RECEBIDA CONTA CORRENTE", "", "Transfers", "INFLOW", spec.savingsTransfer.amount)
+		}
+	}
+
+	checking.Balance = models.BelvoBalance{Current: roundCents(balance), Available: roundCents(balance)}
+
+	monthlyIncome := 0.0
+	lastIncomeDescription := ""
+	for _, income := range spec.incomes {
+		monthlyIncome += income.amount
+		lastIncomeDescription = income.description
+	}
+
+	incomes := []models.BelvoIncome{
+		{
+			ID:                    idPrefix + "-income-1",
+			Account:               checking.ID,
+			CollectedAt:           now,
+			CreatedAt:             now,
+			IncomeType:            spec.incomeType,
+			IncomeSourceType:      "DEPOSIT",
+			Frequency:             "MONTHLY",
+			MonthlyAverage:        monthlyIncome,
+			Currency:              "BRL",
+			LastIncomeDescription: lastIncomeDescription,
+			LastIncomeDate:        today.Format("2006-01-02"),
+			StabilityCoefficient:  0.9,
+			Regularity:            "REGULAR",
+			LookbackPeriods:       historyDays / 30,
+			FullPeriods:           historyDays / 30,
+			PeriodsWithIncome:     historyDays / 30,
+			NumberOfIncomeStreams: len(spec.incomes),
+		},
+	}
+
+	var recurringExpenses []models.BelvoRecurringExpense
+	for i, expense := range spec.recurring {
+		recurringExpenses = append(recurringExpenses, models.BelvoRecurringExpense{
+			ID:                       fmt.Sprintf("%s-recurring-%d", idPrefix, i+1),
+			Account:                  checking.ID,
+			CollectedAt:              now,
+			CreatedAt:                now,
+			Frequency:                "MONTHLY",
+			AverageTransactionAmount: expense.amount,
+			MedianTransactionAmount:  expense.amount,
+			Category:                 expense.category,
+			Currency:                 "BRL",
+			PaymentType:              "NORMAL",
+			TransactionsMeanAmount:   expense.amount,
+		})
+	}
+
+	investments := []models.BelvoInvestmentPortfolio{}
+	for i, portfolio := range spec.portfolios {
+		investment := models.BelvoInvestmentPortfolio{
+			ID:          fmt.Sprintf("%s-portfolio-%d", idPrefix, i+1),
+			Link:        spec.linkID,
+			CollectedAt: collectedAt,
+			Name:        portfolio.name,
+			Type:        portfolio.portfolioType,
+			BalanceType: "ASSET",
+			Currency:    "BRL",
+		}
+		for _, instrument := range portfolio.instruments {
+			value := roundCents(instrument.quantity * instrument.price)
+			investment.Instruments = append(investment.Instruments, models.BelvoInvestmentInstrument{
+				Type:         instrument.instrumentType,
+				Code:         instrument.code,
+				PublicID:     instrument.code,
+				Name:         instrument.name,
+				Currency:     "BRL",
+				Quantity:     instrument.quantity,
+				Price:        instrument.price,
+				BalanceGross: value,
+				BalanceNet:   value,
+			})
+			investment.BalanceGross += value
+			investment.BalanceNet += value
+		}
+		investments = append(investments, investment)
+	}
+
+	loans := []models.BelvoLoan{}
+	for i, loan := range spec.loans {
+		loans = append(loans, models.BelvoLoan{
+			ID:                              fmt.Sprintf("%s-loan-%d", idPrefix, i+1),
+			Link:                            spec.linkID,
+			CollectedAt:                     collectedAt,
+			ContractNumber:                  fmt.Sprintf("CTR-%s-%d", idPrefix, i+1),
+			Name:                            loan.name,
+			Type:                            loan.loanType,
+			Currency:                        "BRL",
+			ContractAmount:                  loan.contractAmount,
+			OutstandingPrincipal:            loan.outstanding,
+			MonthlyPayment:                  loan.monthlyPayment,
+			InterestRates:                   []models.BelvoInterestRate{{Name: "CET", Type: "MONTHLY", Value: loan.monthlyRate}},
+			NumberOfInstallmentsTotal:       loan.installmentsTotal,
+			NumberOfInstallmentsOutstanding: loan.installmentsLeft,
+			NextPaymentDate:                 time.Date(today.Year(), today.Month(), 20, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0).Format("2006-01-02"),
+		})
+	}
+
+	return &Persona{
+		Link: link,
+		Owner: models.BelvoOwner{
+			ID:                     idPrefix + "-owner",
+			Link:                   spec.linkID,
+			CollectedAt:            collectedAt,
+			CreatedAt:              createdAt,
+			DisplayName:            spec.ownerName,
+			FullName:               spec.ownerName,
+			Email:                  spec.email,
+			PhoneNumber:            "+55 11 99999-0000",
+			Address:                "Av. Paulista, 1000 - São Paulo, SP",
+			InternalIdentification: spec.document,
+		},
+		Accounts:          []models.BelvoAccount{checking, savings, creditCard},
+		Transactions:      transactions,
+		Incomes:           incomes,
+		RecurringExpenses: recurringExpenses,
+		Investments:       investments,
+		Loans:             loans,
+		spec:              spec,
+		now:               now,
+	}
+}
+
+// withLink returns a persona with the same financial profile re-generated under a different link
+func (p *Persona) withLink(link models.BelvoLink) *Persona {
+	spec := p.spec
+	spec.linkID = link.ID
+	spec.institution = link.Institution
+
+	clone := buildPersona(spec, p.now)
+	clone.Link = link
+	return clone
+}
+
+func institutionByName(name string) models.BelvoInstitution {
+	for _, institution := range DefaultInstitutions() {
+		if institution.Name == name {
+			return institution
+		}
+	}
+	return models.BelvoInstitution{Name: name, Type: "bank"}
+}
+
+// accountReference is the account object Belvo embeds in each transaction
+func accountReference(account models.BelvoAccount) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       account.ID,
+		"link":     account.Link,
+		"name":     account.Name,
+		"category": account.Category,
+		"currency": account.Currency,
+	}
+}
+
+// variation returns a deterministic multiplier between 0.7 and 1.3 so spending isn't flat
+func variation(seed int) float64 {
+	return 0.7 + float64((seed*37)%61)/100.0
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,611 @@
+// Package belvofake implements an in-process fake of the Belvo HTTP API backed by
+// fixture personas, so the API and its handlers can run without network access.
+package belvofake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+const defaultPageSize = 100
+
+// Server is a fake Belvo API. It implements http.Handler.
+type Server struct {
+	mu           sync.RWMutex
+	secretID     string
+	secretKey    string
+	personas     map[string]*Persona // keyed by link ID
+	links        []models.BelvoLink
+	institutions []models.BelvoInstitution
+	linkCounter  int
+	sessions     map[string]string // pending MFA session → link ID
+	consents     []models.BelvoConsent
+	failures     map[string]*injectedFailure // path → failure to answer with
+}
+
+// injectedFailure is an error response served for the next remaining requests to a path
+type injectedFailure struct {
+	status    int
+	remaining int
+}
+
+// NewServer creates a fake Belvo server. When secretID and secretKey are empty, any
+// basic-auth credentials are accepted. With no personas, DefaultPersonas are used.
+func NewServer(secretID, secretKey string, personas ...*Persona) *Server {
+	if len(personas) == 0 {
+		personas = DefaultPersonas(time.Now())
+	}
+
+	s := &Server{
+		secretID:     secretID,
+		secretKey:    secretKey,
+		personas:     make(map[string]*Persona),
+		institutions: DefaultInstitutions(),
+		sessions:     make(map[string]string),
+		failures:     make(map[string]*injectedFailure),
+	}
+
+	for _, persona := range personas {
+		s.personas[persona.Link.ID] = persona
+		s.links = append(s.links, persona.Link)
+	}
+
+	return s
+}
+
+// Personas returns the fixture personas served by this fake, keyed by link ID
+func (s *Server) Personas() map[string]*Persona {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	personas := make(map[string]*Persona, len(s.personas))
+	for id, persona := range s.personas {
+		personas[id] = persona
+	}
+	return personas
+}
+
+// AddConsent registers an Open Finance consent, as the widget would after the user grants it
+func (s *Server) AddConsent(consent models.BelvoConsent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.consents = append(s.consents, consent)
+}
+
+// FailNext makes the next times requests to path (e.g. "/api/links/") fail with status,
+// the way Belvo answers when it throttles (429) or an institution is down (503).
+// Throttled responses carry "Retry-After: 0" so retries don't slow tests down.
+func (s *Server) FailNext(path string, status, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[path] = &injectedFailure{status: status, remaining: times}
+}
+
+// takeFailure returns the status to fail a request to path with, if one is pending
+func (s *Server) takeFailure(path string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failure, ok := s.failures[path]
+	if !ok || failure.remaining <= 0 {
+		return 0, false
+	}
+	failure.remaining--
+	return failure.status, true
+}
+
+// ServeHTTP routes requests to the fake Belvo endpoints
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api/token/" && r.Method == http.MethodPost {
+		s.handleToken(w, r)
+		return
+	}
+
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "authentication_failed", "Invalid credentials provided", "")
+		return
+	}
+
+	if status, ok := s.takeFailure(r.URL.Path); ok {
+		switch status {
+		case http.StatusTooManyRequests:
+			w.Header().Set("Retry-After", "0")
+			writeError(w, status, "too_many_requests", "Request was throttled", "")
+		case http.StatusServiceUnavailable:
+			writeError(w, status, "institution_unavailable", "The institution is temporarily unavailable", "")
+		default:
+			writeError(w, status, "unexpected_error", "Injected failure", "")
+		}
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == "/api/links/" && r.Method == http.MethodGet:
+		s.handleListLinks(w, r)
+	case path == "/api/links/" && r.Method == http.MethodPost:
+		s.handleCreateLink(w, r)
+	case path == "/api/links/" && r.Method == http.MethodPatch:
+		s.handleResumeLink(w, r)
+	case strings.HasPrefix(path, "/api/links/") && r.Method == http.MethodGet:
+		s.handleGetLink(w, strings.Trim(strings.TrimPrefix(path, "/api/links/"), "/"))
+	case strings.HasPrefix(path, "/api/links/") && r.Method == http.MethodPut:
+		s.handleRefreshLink(w, r, strings.Trim(strings.TrimPrefix(path, "/api/links/"), "/"))
+	case strings.HasPrefix(path, "/api/links/") && r.Method == http.MethodDelete:
+		s.handleDeleteLink(w, strings.Trim(strings.TrimPrefix(path, "/api/links/"), "/"))
+	case path == "/api/institutions/":
+		writePage(w, r, s.institutions)
+	case path == "/api/accounts/":
+		s.handleAccounts(w, r)
+	case path == "/api/transactions/":
+		s.handleTransactions(w, r)
+	case path == "/api/owners/":
+		s.handleOwners(w, r)
+	case path == "/api/incomes/":
+		s.handleIncomes(w, r)
+	case path == "/api/recurring-expenses/":
+		s.handleRecurringExpenses(w, r)
+	case path == "/investments/portfolios/":
+		s.handleInvestmentPortfolios(w, r)
+	case path == "/api/loans/":
+		s.handleLoans(w, r)
+	case path == "/api/consents/" && r.Method == http.MethodGet:
+		s.handleConsents(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("No fake endpoint for %s %s", r.Method, path), "")
+	}
+}
+
+// authorized checks basic auth against the configured secret
+func (s *Server) authorized(r *http.Request) bool {
+	id, key, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	if s.secretID == "" && s.secretKey == "" {
+		return true
+	}
+	return id == s.secretID && key == s.secretKey
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID       string `json:"id"`
+		Password string `json:"password"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	// Widget tokens may authenticate with basic auth instead of id/password in the body
+	if req.ID == "" {
+		req.ID, req.Password, _ = r.BasicAuth()
+	}
+	if s.secretID != "" && (req.ID != s.secretID || req.Password != s.secretKey) {
+		writeError(w, http.StatusUnauthorized, "authentication_failed", "Invalid credentials provided", "")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]string{
+		"access":  "fake-access-token",
+		"refresh": "fake-refresh-token",
+	})
+}
+
+func (s *Server) handleListLinks(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	links := append([]models.BelvoLink(nil), s.links...)
+	s.mu.RUnlock()
+
+	writePage(w, r, links)
+}
+
+func (s *Server) handleGetLink(w http.ResponseWriter, linkID string) {
+	persona, ok := s.persona(linkID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Link not found", "")
+		return
+	}
+	writeJSON(w, http.StatusOK, persona.Link)
+}
+
+// handleRefreshLink simulates an institution that always asks for a second factor:
+// without a token it answers 428 with a session to resume via PATCH /api/links/
+func (s *Server) handleRefreshLink(w http.ResponseWriter, r *http.Request, linkID string) {
+	persona, ok := s.persona(linkID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Link not found", "")
+		return
+	}
+
+	params := requestParams(r)
+	if params["token"] != "" {
+		writeJSON(w, http.StatusOK, s.touchLink(persona))
+		return
+	}
+
+	s.mu.Lock()
+	s.linkCounter++
+	session := fmt.Sprintf("fake-session-%d", s.linkCounter)
+	s.sessions[session] = linkID
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusPreconditionRequired, []models.LinkTokenSession{{
+		Code:      "token_required",
+		Message:   "A MFA token is required by the institution to login",
+		RequestID: fmt.Sprintf("fake-%d", time.Now().UnixNano()),
+		Session:   session,
+		Expiry:    600,
+		Link:      linkID,
+		TokenGenerationData: &models.TokenGenerationData{
+			Instructions: "Use any non-empty code, e.g. 123456",
+			Type:         "numeric",
+			ExpiresIn:    600,
+		},
+	}})
+}
+
+// handleResumeLink completes a pending MFA session created by handleRefreshLink
+func (s *Server) handleResumeLink(w http.ResponseWriter, r *http.Request) {
+	params := requestParams(r)
+	if params["session"] == "" {
+		writeError(w, http.StatusBadRequest, "required", "This field is required.", "session")
+		return
+	}
+	if params["token"] == "" {
+		writeError(w, http.StatusBadRequest, "required", "This field is required.", "token")
+		return
+	}
+
+	s.mu.Lock()
+	linkID, ok := s.sessions[params["session"]]
+	if ok && (params["link"] == "" || params["link"] == linkID) {
+		delete(s.sessions, params["session"])
+	}
+	s.mu.Unlock()
+
+	if !ok || (params["link"] != "" && params["link"] != linkID) {
+		writeError(w, http.StatusBadRequest, "session_expired", "The MFA session has expired or does not exist", "session")
+		return
+	}
+
+	persona, ok := s.persona(linkID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Link not found", "")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.touchLink(persona))
+}
+
+// handleDeleteLink removes a link and its persona data
+func (s *Server) handleDeleteLink(w http.ResponseWriter, linkID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.personas[linkID]; !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Link not found", "")
+		return
+	}
+
+	delete(s.personas, linkID)
+	for i, link := range s.links {
+		if link.ID == linkID {
+			s.links = append(s.links[:i], s.links[i+1:]...)
+			break
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// touchLink marks a link as freshly accessed and returns it
+func (s *Server) touchLink(persona *Persona) models.BelvoLink {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	persona.Link.Status = "valid"
+	persona.Link.LastAccessedAt = &now
+	for i := range s.links {
+		if s.links[i].ID == persona.Link.ID {
+			s.links[i] = persona.Link
+		}
+	}
+	return persona.Link
+}
+
+// handleCreateLink registers a new link backed by one of the existing personas
+func (s *Server) handleCreateLink(w http.ResponseWriter, r *http.Request) {
+	var req map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", "Malformed JSON body", "")
+		return
+	}
+
+	institution, _ := req["institution"].(string)
+	username, _ := req["username"].(string)
+	if institution == "" {
+		writeError(w, http.StatusBadRequest, "required", "This field is required.", "institution")
+		return
+	}
+	if username == "" {
+		writeError(w, http.StatusBadRequest, "required", "This field is required.", "username")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Pick a template persona deterministically from the username so repeated logins get the same data
+	template := s.personas[s.links[len(username)%len(s.links)].ID]
+
+	s.linkCounter++
+	linkID := fmt.Sprintf("f4ce11de-0000-4000-8000-%012d", s.linkCounter)
+	externalID, _ := req["external_id"].(string)
+	accessMode, _ := req["access_mode"].(string)
+	if accessMode == "" {
+		accessMode = "single"
+	}
+
+	persona := template.withLink(models.BelvoLink{
+		ID:                  linkID,
+		Institution:         institution,
+		AccessMode:          accessMode,
+		Status:              "valid",
+		CreatedAt:           time.Now().UTC(),
+		ExternalID:          externalID,
+		RefreshRate:         "24h",
+		FetchHistoricalData: true,
+	})
+
+	s.personas[linkID] = persona
+	s.links = append(s.links, persona.Link)
+
+	writeJSON(w, http.StatusCreated, persona.Link)
+}
+
+// handleAccounts supports both GET ?link= (paginated) and POST {"link": ...} (array) like Belvo
+func (s *Server) handleAccounts(w http.ResponseWriter, r *http.Request) {
+	persona, ok := s.personaFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		writePage(w, r, persona.Accounts)
+		return
+	}
+	writeJSON(w, http.StatusCreated, persona.Accounts)
+}
+
+func (s *Server) handleTransactions(w http.ResponseWriter, r *http.Request) {
+	params := requestParams(r)
+	persona, ok := s.personaFromParams(w, params)
+	if !ok {
+		return
+	}
+
+	// POST takes date_from/date_to; the GET list takes Belvo's accounting_date filters
+	dateFrom, _ := time.Parse("2006-01-02", firstNonEmpty(params["date_from"], params["accounting_date__gte"]))
+	dateTo, _ := time.Parse("2006-01-02", firstNonEmpty(params["date_to"], params["accounting_date__lte"]))
+
+	transactions := make([]models.BelvoTransaction, 0, len(persona.Transactions))
+	for _, transaction := range persona.Transactions {
+		if params["type"] != "" && transaction.Type != params["type"] {
+			continue
+		}
+		date := transaction.AccountingDate.Time()
+		if !dateFrom.IsZero() && date.Before(dateFrom) {
+			continue
+		}
+		if !dateTo.IsZero() && date.After(dateTo.Add(24*time.Hour-time.Nanosecond)) {
+			continue
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	if r.Method == http.MethodGet {
+		writePage(w, r, transactions)
+		return
+	}
+	writeJSON(w, http.StatusCreated, transactions)
+}
+
+func (s *Server) handleOwners(w http.ResponseWriter, r *http.Request) {
+	persona, ok := s.personaFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	owners := []models.BelvoOwner{persona.Owner}
+	if r.Method == http.MethodGet {
+		writePage(w, r, owners)
+		return
+	}
+	writeJSON(w, http.StatusCreated, owners)
+}
+
+func (s *Server) handleIncomes(w http.ResponseWriter, r *http.Request) {
+	persona, ok := s.personaFromRequest(w, r)
+	if !ok {
+		return
+	}
+	writePage(w, r, persona.Incomes)
+}
+
+func (s *Server) handleRecurringExpenses(w http.ResponseWriter, r *http.Request) {
+	persona, ok := s.personaFromRequest(w, r)
+	if !ok {
+		return
+	}
+	writePage(w, r, persona.RecurringExpenses)
+}
+
+func (s *Server) handleInvestmentPortfolios(w http.ResponseWriter, r *http.Request) {
+	persona, ok := s.personaFromRequest(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusCreated, persona.Investments)
+}
+
+func (s *Server) handleLoans(w http.ResponseWriter, r *http.Request) {
+	persona, ok := s.personaFromRequest(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusCreated, persona.Loans)
+}
+
+func (s *Server) handleConsents(w http.ResponseWriter, r *http.Request) {
+	linkID := r.URL.Query().Get("link")
+
+	s.mu.RLock()
+	consents := make([]models.BelvoConsent, 0, len(s.consents))
+	for _, consent := range s.consents {
+		if linkID == "" || consent.Link == linkID {
+			consents = append(consents, consent)
+		}
+	}
+	s.mu.RUnlock()
+
+	writePage(w, r, consents)
+}
+
+func (s *Server) persona(linkID string) (*Persona, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	persona, ok := s.personas[linkID]
+	return persona, ok
+}
+
+func (s *Server) personaFromRequest(w http.ResponseWriter, r *http.Request) (*Persona, bool) {
+	return s.personaFromParams(w, requestParams(r))
+}
+
+func (s *Server) personaFromParams(w http.ResponseWriter, params map[string]string) (*Persona, bool) {
+	linkID := params["link"]
+	if linkID == "" {
+		writeError(w, http.StatusBadRequest, "required", "This field is required.", "link")
+		return nil, false
+	}
+
+	persona, ok := s.persona(linkID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Link not found", "link")
+		return nil, false
+	}
+	return persona, true
+}
+
+// requestParams merges query parameters with string fields from a JSON body
+func requestParams(r *http.Request) map[string]string {
+	params := make(map[string]string)
+	for key, values := range r.URL.Query() {
+		if len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+
+	if r.Body == nil || r.Method == http.MethodGet {
+		return params
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err == nil {
+		for key, value := range body {
+			if str, ok := value.(string); ok {
+				params[key] = str
+			}
+		}
+	}
+	return params
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// writePage writes a Belvo-style paginated envelope honoring page and page_size
+func writePage[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	query := r.URL.Query()
+
+	pageSize, err := strconv.Atoi(query.Get("page_size"))
+	if err != nil || pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	start := (page - 1) * pageSize
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + pageSize
+	if end > len(items) {
+		end = len(items)
+	}
+
+	pageURL := func(p int) *string {
+		u := url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path}
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(p))
+		q.Set("page_size", strconv.Itoa(pageSize))
+		u.RawQuery = q.Encode()
+		str := u.String()
+		return &str
+	}
+
+	var next, previous *string
+	if end < len(items) {
+		next = pageURL(page + 1)
+	}
+	if page > 1 {
+		previous = pageURL(page - 1)
+	}
+
+	results := items[start:end]
+	if results == nil {
+		results = []T{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"count":    len(items),
+		"next":     next,
+		"previous": previous,
+		"results":  results,
+	})
+}
+
+// writeError writes an error in Belvo's list-of-errors format
+func writeError(w http.ResponseWriter, status int, code, message, field string) {
+	belvoError := models.BelvoError{
+		Code:      code,
+		Message:   message,
+		RequestID: fmt.Sprintf("fake-%d", time.Now().UnixNano()),
+		Field:     field,
+	}
+	writeJSON(w, status, []models.BelvoError{belvoError})
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
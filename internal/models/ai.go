@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // AIAnalysisRequest represents a request for AI financial analysis
 type AIAnalysisRequest struct {
@@ -12,6 +15,8 @@ type AIAnalysisRequest struct {
 	MonthlyBudget     float64            `json:"monthly_budget"`     // Monthly investment amount
 	Goals             []InvestmentGoal   `json:"goals"`
 	Language          string             `json:"language"` // "pt-BR", "en-US"
+	// Links the server ties the analysis to, for routing; never read from the body
+	SessionLinkIDs []string `json:"-"`
 }
 
 // InvestmentGoal represents user's investment objectives
@@ -45,6 +50,12 @@ type PortfolioRecommendation struct {
 	ExpectedRisk      float64               `json:"expected_risk"`
 	Rationale         string                `json:"rationale"`
 	MonthlyInvestment float64               `json:"monthly_investment"`
+	// Existing positions the recommendation starts from; CustomAllocations amounts then
+	// say where this month's investment goes to move them toward the template
+	CurrentAllocations []PortfolioAllocation `json:"current_allocations,omitempty"`
+	CurrentValue       float64               `json:"current_value"`
+	// Set when high-interest debt should be paid off before investing; MonthlyInvestment is then zero
+	DebtPaydownFirst bool `json:"debt_paydown_first,omitempty"`
 }
 
 // FinancialAnalysis represents detailed financial insights
@@ -54,6 +65,19 @@ type FinancialAnalysis struct {
 	InvestmentReadiness  InvestmentReadiness `json:"investment_readiness"`
 	MarketOpportunities  []MarketOpportunity `json:"market_opportunities"`
 	FinancialHealthScore float64             `json:"financial_health_score"` // 0-100
+	DebtAnalysis         DebtAnalysis        `json:"debt_analysis"`
+}
+
+// DebtAnalysis summarizes what the user owes and what it costs
+type DebtAnalysis struct {
+	TotalDebt             float64 `json:"total_debt"`
+	MonthlyDebtPayments   float64 `json:"monthly_debt_payments"`
+	DebtToIncomeRatio     float64 `json:"debt_to_income_ratio"`   // Monthly debt payments over monthly income
+	WeightedInterestRate  float64 `json:"weighted_interest_rate"` // Annual, weighted by interest-bearing balance
+	HighInterestDebt      float64 `json:"high_interest_debt"`     // Balance charging more than any portfolio template is expected to return
+	HighInterestThreshold float64 `json:"high_interest_threshold"`
+	PayDownFirst          bool    `json:"pay_down_first"` // Pay HighInterestDebt before investing
+	Debts                 []Debt  `json:"debts"`
 }
 
 // SurplusAnalysis breaks down available investment capacity
@@ -63,7 +87,9 @@ type SurplusAnalysis struct {
 	EmergencyFundTarget    float64 `json:"emergency_fund_target"`  // 3-6 months expenses
 	CurrentEmergencyFund   float64 `json:"current_emergency_fund"`
 	SafeInvestmentAmount   float64 `json:"safe_investment_amount"`
-	ConservativePercentage float64 `json:"conservative_percentage"` // % of surplus to invest
+	ConservativePercentage float64 `json:"conservative_percentage"`        // % of surplus to invest
+	EmergencyFundTrend     string  `json:"emergency_fund_trend,omitempty"` // "growing", "stable" or "shrinking"
+	EmergencyFundChange    float64 `json:"emergency_fund_monthly_change"`  // Observed monthly change of liquid balances
 }
 
 // SpendingPatterns analyzes user's spending behavior
@@ -186,16 +212,53 @@ type ScenarioImpact struct {
 
 // LLMRequest represents a request to the language model
 type LLMRequest struct {
-	Model       string       `json:"model"`
-	Messages    []LLMMessage `json:"messages"`
-	Temperature float64      `json:"temperature"`
-	MaxTokens   int          `json:"max_tokens"`
+	Model         string            `json:"model"`
+	Messages      []LLMMessage      `json:"messages"`
+	Temperature   float64           `json:"temperature"`
+	MaxTokens     int               `json:"max_tokens"`
+	Stream        bool              `json:"stream,omitempty"`
+	StreamOptions *LLMStreamOptions `json:"stream_options,omitempty"`
+	Tools         []LLMTool         `json:"tools,omitempty"`
+	ToolChoice    string            `json:"tool_choice,omitempty"` // "auto", or "none" to force a text answer
+}
+
+// LLMStreamOptions asks a streaming completion to report token usage in its last chunk
+type LLMStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // LLMMessage represents a message in the conversation
 type LLMMessage struct {
-	Role    string `json:"role"` // "system", "user", "assistant"
-	Content string `json:"content"`
+	Role       string        `json:"role"` // "system", "user", "assistant", "tool"
+	Content    string        `json:"content"`
+	ToolCalls  []LLMToolCall `json:"tool_calls,omitempty"`   // Tools an assistant message asks to run
+	ToolCallID string        `json:"tool_call_id,omitempty"` // The call a "tool" message answers
+}
+
+// LLMTool is a function the model may call, with its parameters as a JSON Schema
+type LLMTool struct {
+	Type     string      `json:"type"` // "function"
+	Function LLMFunction `json:"function"`
+}
+
+// LLMFunction describes a tool to the model
+type LLMFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// LLMToolCall is the model asking for a tool to be run
+type LLMToolCall struct {
+	ID       string          `json:"id"`
+	Type     string          `json:"type"` // "function"
+	Function LLMFunctionCall `json:"function"`
+}
+
+// LLMFunctionCall names the tool and carries its arguments as a JSON object
+type LLMFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 // LLMResponse represents the language model's response
@@ -225,16 +288,24 @@ type LLMUsage struct {
 // ChatRequest represents a request for conversational AI
 type ChatRequest struct {
 	Message        string             `json:"message"`
-	ConversationID string             `json:"conversation_id,omitempty"`
-	Language       string             `json:"language"` // "en" or "pt"
+	ConversationID string             `json:"conversation_id,omitempty"` // Continues a stored conversation; its history replaces ChatHistory
+	Language       string             `json:"language"`                  // "en" or "pt"
 	UserContext    *FinancialSummary  `json:"user_context,omitempty"`
 	MarketContext  *MarketDataSummary `json:"market_context,omitempty"`
 	ChatHistory    []LLMMessage       `json:"chat_history,omitempty"`
 	// Optional: if provided, backend will fetch real data from Belvo
-	CredentialMode string `json:"credential_mode,omitempty"` // "demo", "test", "custom"
-	LinkID         string `json:"link_id,omitempty"`
-	SecretID       string `json:"secret_id,omitempty"`
-	SecretKey      string `json:"secret_key,omitempty"`
+	CredentialMode string   `json:"credential_mode,omitempty"` // "demo", "test", "custom"
+	LinkID         string   `json:"link_id,omitempty"`
+	LinkIDs        []string `json:"link_ids,omitempty"` // Several links of one person, merged into one summary
+	// Summary window and currency when the backend fetches the data; zero values use the defaults
+	LookbackMonths    int    `json:"lookback_months,omitempty"`
+	ReportingCurrency string `json:"reporting_currency,omitempty"`
+	// Links the server ties the chat to (its conversation, the session's cached
+	// context), for routing; never read from the body
+	SessionLinkIDs []string `json:"-"`
+	// Reads the chat's transactions between two days with the session's credentials, for
+	// tools asking about days outside UserContext's window; nil without a session
+	FetchTransactions func(ctx context.Context, from, to time.Time) ([]BelvoTransaction, error) `json:"-"`
 }
 
 // ChatResponse represents the AI's conversational response
@@ -244,5 +315,26 @@ type ChatResponse struct {
 	Language       string    `json:"language"`
 	GeneratedAt    time.Time `json:"generated_at"`
 	TokensUsed     int       `json:"tokens_used,omitempty"`
+	Usage          *LLMUsage `json:"usage,omitempty"`
+	ToolsUsed      []string  `json:"tools_used,omitempty"` // Tools the model called to answer, in order
 	ShowDashboard  bool      `json:"show_dashboard,omitempty"`
 }
+
+// Conversation is a chat kept on the server, so clients don't resend the history
+type Conversation struct {
+	ID           string                `json:"id"`
+	Title        string                `json:"title"`
+	LinkID       string                `json:"link_id,omitempty"`
+	Language     string                `json:"language,omitempty"`
+	MessageCount int                   `json:"message_count"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+	Messages     []ConversationMessage `json:"messages,omitempty"` // Only set when a single conversation is fetched
+}
+
+// ConversationMessage is one turn of a stored conversation
+type ConversationMessage struct {
+	Role      string    `json:"role"` // "user" or "assistant"
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -40,6 +40,29 @@ func (bt BelvoTime) Time() time.Time {
 	return time.Time(bt)
 }
 
+// RequestCredentials are Belvo credentials as sent to the login endpoint and kept,
+// encrypted, in the caller's session. An empty Environment means the server's default.
+type RequestCredentials struct {
+	SecretID    string `json:"secret_id,omitempty"`
+	SecretKey   string `json:"secret_key,omitempty"`
+	Environment string `json:"environment,omitempty"` // "sandbox" or "production"
+}
+
+// HasSecrets reports whether both the secret ID and key were provided
+func (c RequestCredentials) HasSecrets() bool {
+	return c.SecretID != "" && c.SecretKey != ""
+}
+
+// Session is a logged-in API session. The Belvo secrets it was created with stay on
+// the server; clients only hold the signed token.
+type Session struct {
+	ID           string    `json:"id"`
+	SecretIDHint string    `json:"secret_id_hint"` // First characters of the Belvo secret ID
+	Environment  string    `json:"environment"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
 // BelvoCredentials holds the API credentials for Belvo
 type BelvoCredentials struct {
 	SecretID    string `json:"secret_id"`
@@ -83,18 +106,35 @@ type BelvoAccount struct {
 	PublicIdentificationName  string           `json:"public_identification_name,omitempty"`
 	PublicIdentificationValue string           `json:"public_identification_value,omitempty"`
 	BalanceType               string           `json:"balance_type"`
+	CreditData                *BelvoCreditData `json:"credit_data,omitempty"` // Credit cards only
 }
 
 // BelvoInstitution represents financial institution details
 type BelvoInstitution struct {
-	Name         string `json:"name"`
-	Type         string `json:"type"`
-	Code         string `json:"code,omitempty"`
-	DisplayName  string `json:"display_name,omitempty"`
-	CountryCode  string `json:"country_code,omitempty"`
-	Website      string `json:"website,omitempty"`
-	PrimaryColor string `json:"primary_color,omitempty"`
-	Logo         string `json:"logo,omitempty"`
+	ID              int      `json:"id,omitempty"`
+	Name            string   `json:"name"`
+	Type            string   `json:"type"`
+	Code            string   `json:"code,omitempty"`
+	DisplayName     string   `json:"display_name,omitempty"`
+	CountryCode     string   `json:"country_code,omitempty"`
+	CountryCodes    []string `json:"country_codes,omitempty"`
+	Website         string   `json:"website,omitempty"`
+	PrimaryColor    string   `json:"primary_color,omitempty"`
+	Logo            string   `json:"logo,omitempty"`
+	IconLogo        string   `json:"icon_logo,omitempty"`
+	TextLogo        string   `json:"text_logo,omitempty"`
+	IntegrationType string   `json:"integration_type,omitempty"` // "credentials" or "openfinance"
+	Resources       []string `json:"resources,omitempty"`        // e.g. ACCOUNTS, TRANSACTIONS, OWNERS
+	Status          string   `json:"status,omitempty"`           // "healthy" or "down"
+}
+
+// InstitutionCatalog is a filtered view of the cached institution list
+type InstitutionCatalog struct {
+	Institutions []BelvoInstitution `json:"institutions"`
+	Count        int                `json:"count"` // Institutions matching the filters
+	Total        int                `json:"total"` // Institutions in the cached list
+	CachedAt     time.Time          `json:"cached_at"`
+	ExpiresAt    time.Time          `json:"expires_at"`
 }
 
 // BelvoBalance represents account balance information
@@ -103,6 +143,18 @@ type BelvoBalance struct {
 	Available float64 `json:"available"`
 }
 
+// BelvoCreditData holds the statement details Belvo reports for credit card accounts
+type BelvoCreditData struct {
+	CreditLimit       float64 `json:"credit_limit"`
+	CuttingDate       string  `json:"cutting_date,omitempty"`
+	NextPaymentDate   string  `json:"next_payment_date,omitempty"`
+	MinimumPayment    float64 `json:"minimum_payment"`
+	NoInterestPayment float64 `json:"no_interest_payment"` // Paying this much avoids interest
+	LastPaymentDate   string  `json:"last_payment_date,omitempty"`
+	LastPeriodBalance float64 `json:"last_period_balance"`
+	InterestRate      float64 `json:"interest_rate"` // Annual, in percent
+}
+
 // BelvoTransaction represents a financial transaction
 type BelvoTransaction struct {
 	ID                     string                 `json:"id"`
@@ -206,20 +258,283 @@ type BelvoError struct {
 	Field     string `json:"field,omitempty"`
 }
 
+// LinkTokenSession is returned when an institution asks for a second factor (HTTP 428 token_required).
+// Send the session back with the user's token to resume the operation.
+type LinkTokenSession struct {
+	Code                string               `json:"code"`
+	Message             string               `json:"message"`
+	RequestID           string               `json:"request_id"`
+	Session             string               `json:"session"`
+	Expiry              int                  `json:"expiry"` // Seconds until the session expires
+	Link                string               `json:"link"`
+	TokenGenerationData *TokenGenerationData `json:"token_generation_data,omitempty"`
+}
+
+// TokenGenerationData describes how the user obtains the MFA token
+type TokenGenerationData struct {
+	Instructions string `json:"instructions"`
+	Type         string `json:"type"` // "numeric", "qr", ...
+	Value        string `json:"value,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+}
+
+// Link operation statuses
+const (
+	LinkStatusCompleted     = "completed"
+	LinkStatusTokenRequired = "token_required"
+	LinkStatusDeleted       = "deleted"
+)
+
+// LinkOperationResult is the outcome of a link lifecycle operation (resume, refresh, delete)
+type LinkOperationResult struct {
+	Status        string            `json:"status"`
+	Link          *BelvoLink        `json:"link,omitempty"`
+	TokenRequired *LinkTokenSession `json:"token_required,omitempty"`
+}
+
+// LinkRefreshRequest holds the optional credentials sent when refreshing a link
+type LinkRefreshRequest struct {
+	Password     string `json:"password,omitempty"`
+	Token        string `json:"token,omitempty"`
+	UsernameType string `json:"username_type,omitempty"`
+}
+
+// Belvo webhook types and codes handled by the webhook receiver
+const (
+	WebhookTypeTransactions = "TRANSACTIONS"
+	WebhookTypeLinks        = "LINKS"
+
+	WebhookCodeHistoricalUpdate         = "historical_update"
+	WebhookCodeNewTransactionsAvailable = "new_transactions_available"
+	WebhookCodeTokenRequired            = "token_required"
+	WebhookCodeInvalid                  = "invalid"
+)
+
+// BelvoWebhook represents a webhook notification sent by Belvo
+type BelvoWebhook struct {
+	WebhookID   string                 `json:"webhook_id"`
+	WebhookType string                 `json:"webhook_type"`
+	WebhookCode string                 `json:"webhook_code"`
+	LinkID      string                 `json:"link_id"`
+	RequestID   string                 `json:"request_id"`
+	ExternalID  string                 `json:"external_id"`
+	Data        map[string]interface{} `json:"data"`
+}
+
+// WebhookEvent records a received webhook and what was done with it
+type WebhookEvent struct {
+	ReceivedAt time.Time    `json:"received_at"`
+	Webhook    BelvoWebhook `json:"webhook"`
+	Action     string       `json:"action"` // "refreshed", "invalidated", "ignored"
+	Detail     string       `json:"detail,omitempty"`
+	Accounts   []string     `json:"-"` // Belvo accounts whose cached context the webhook touched
+}
+
+// Open Finance consent statuses, as tracked locally
+const (
+	ConsentStatusActive   = "active"
+	ConsentStatusExpiring = "expiring"
+	ConsentStatusExpired  = "expired"
+	ConsentStatusRevoked  = "revoked"
+)
+
+// OFDAConsentRequest holds the consent fields the Open Finance widget requires
+type OFDAConsentRequest struct {
+	CustomerID  string   `json:"customer_id"`
+	CPF         string   `json:"cpf"`
+	FullName    string   `json:"full_name"`
+	Permissions []string `json:"permissions,omitempty"`
+	TermsURL    string   `json:"terms_and_conditions_url"`
+	Institution string   `json:"institution,omitempty"`
+	CompanyName string   `json:"company_name,omitempty"`
+}
+
+// BelvoConsent represents an entry of Belvo's consents resource
+type BelvoConsent struct {
+	ID             string     `json:"id"`
+	Link           string     `json:"link"`
+	Status         string     `json:"status"`
+	Permissions    []string   `json:"permissions"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpirationDate *time.Time `json:"expiration_date"`
+}
+
+// OFDAConsent is an Open Finance consent granted through the widget and tracked until it expires
+type OFDAConsent struct {
+	ConsentID     string     `json:"consent_id"`
+	LinkID        string     `json:"link_id"`
+	CustomerID    string     `json:"customer_id"`
+	Owner         string     `json:"owner"` // Credential scope of the Belvo account that granted it
+	Institution   string     `json:"institution,omitempty"`
+	Permissions   []string   `json:"permissions"`
+	Status        string     `json:"status"`
+	GrantedAt     time.Time  `json:"granted_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	ExpirySource  string     `json:"expiry_source"` // "belvo" or "estimated"
+	DaysLeft      int        `json:"days_left"`
+	RemindersSent []int      `json:"reminders_sent,omitempty"` // Days-before-expiry thresholds already reminded
+	RenewedAt     *time.Time `json:"renewed_at,omitempty"`
+}
+
+// ConsentReminder asks the user to renew a consent before it expires
+type ConsentReminder struct {
+	ConsentID  string    `json:"consent_id"`
+	LinkID     string    `json:"link_id"`
+	CustomerID string    `json:"customer_id"`
+	Owner      string    `json:"owner"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	DaysLeft   int       `json:"days_left"`
+	CreatedAt  time.Time `json:"created_at"`
+	Message    string    `json:"message"`
+}
+
 // FinancialSummary represents processed financial data for AI analysis
 type FinancialSummary struct {
-	UserID                  string                  `json:"user_id"`
-	GeneratedAt             time.Time               `json:"generated_at"`
-	MonthlyIncome           float64                 `json:"monthly_income"`
-	MonthlyFixedExpenses    float64                 `json:"monthly_fixed_expenses"`
-	MonthlyVariableExpenses float64                 `json:"monthly_variable_expenses"`
-	MonthlySurplus          float64                 `json:"monthly_surplus"`
-	TotalBalance            float64                 `json:"total_balance"`
-	Accounts                []BelvoAccount          `json:"accounts"`
-	RecentTransactions      []BelvoTransaction      `json:"recent_transactions"`
-	IncomeStreams           []BelvoIncome           `json:"income_streams"`
-	RecurringExpenses       []BelvoRecurringExpense `json:"recurring_expenses"`
-	Currency                string                  `json:"currency"`
+	UserID                  string                     `json:"user_id"`
+	GeneratedAt             time.Time                  `json:"generated_at"`
+	MonthlyIncome           float64                    `json:"monthly_income"`
+	MonthlyFixedExpenses    float64                    `json:"monthly_fixed_expenses"`
+	MonthlyVariableExpenses float64                    `json:"monthly_variable_expenses"`
+	MonthlySurplus          float64                    `json:"monthly_surplus"`
+	TotalBalance            float64                    `json:"total_balance"`     // Checking and savings balances only
+	TotalLiabilities        float64                    `json:"total_liabilities"` // Credit card and loan balances owed
+	AvailableCredit         float64                    `json:"available_credit"`  // Unused credit card limits, not counted as cash
+	NetWorth                float64                    `json:"net_worth"`
+	Accounts                []BelvoAccount             `json:"accounts"`
+	RecentTransactions      []BelvoTransaction         `json:"recent_transactions"`
+	IncomeStreams           []BelvoIncome              `json:"income_streams"`
+	RecurringExpenses       []BelvoRecurringExpense    `json:"recurring_expenses"`
+	Currency                string                     `json:"currency"`                     // Reporting currency every total is normalized to
+	FXRates                 []FXRate                   `json:"fx_rates,omitempty"`           // Rates used to convert amounts held in other currencies
+	LinkIDs                 []string                   `json:"link_ids,omitempty"`           // Links merged into a consolidated summary
+	TransfersExcluded       int                        `json:"transfers_excluded,omitempty"` // Internal transfer transactions left out of the totals
+	Investments             []BelvoInvestmentPortfolio `json:"investments,omitempty"`
+	Holdings                []InvestmentHolding        `json:"holdings,omitempty"`       // Instruments across all portfolios
+	TotalInvested           float64                    `json:"total_invested,omitempty"` // Market value of all holdings
+	EmergencyFundTrend      *BalanceTrend              `json:"emergency_fund_trend,omitempty"`
+	Loans                   []BelvoLoan                `json:"loans,omitempty"`
+	Debts                   []Debt                     `json:"debts,omitempty"` // Loans and revolving card balances
+	Period                  *SummaryPeriod             `json:"period,omitempty"`
+	MonthlyBreakdown        []MonthlyBreakdown         `json:"monthly_breakdown,omitempty"`
+}
+
+// SummaryPeriod is the window a summary's monthly averages were computed over
+type SummaryPeriod struct {
+	LookbackMonths int       `json:"lookback_months"` // Requested window
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	DataFrom       time.Time `json:"data_from"`      // Earliest transaction inside the window
+	MonthsOfData   float64   `json:"months_of_data"` // Span the averages are divided by
+	Transactions   int       `json:"transactions"`
+	Confidence     string    `json:"confidence"` // "high", "medium" or "low"
+	ConfidenceNote string    `json:"confidence_note,omitempty"`
+}
+
+// MonthlyBreakdown totals one calendar month of a summary's transactions
+type MonthlyBreakdown struct {
+	Month        string  `json:"month"` // YYYY-MM
+	Income       float64 `json:"income"`
+	Expenses     float64 `json:"expenses"`
+	Net          float64 `json:"net"`
+	Transactions int     `json:"transactions"`
+	Partial      bool    `json:"partial"` // The window or the history covers only part of the month
+}
+
+// BelvoLoan represents a loan from Belvo's loans resource
+type BelvoLoan struct {
+	ID                              string                 `json:"id"`
+	Link                            string                 `json:"link"`
+	Account                         map[string]interface{} `json:"account,omitempty"` // Set when the loan is also listed as an account
+	CollectedAt                     BelvoTime              `json:"collected_at"`
+	ContractNumber                  string                 `json:"contract_number,omitempty"`
+	Name                            string                 `json:"name"`
+	Type                            string                 `json:"type"` // e.g. "PERSONAL", "MORTGAGE", "VEHICLE", "PAYROLL"
+	Currency                        string                 `json:"currency"`
+	ContractAmount                  float64                `json:"contract_amount"`
+	OutstandingPrincipal            float64                `json:"outstanding_principal"`
+	MonthlyPayment                  float64                `json:"monthly_payment"`
+	InterestRates                   []BelvoInterestRate    `json:"interest_rate_data"`
+	NumberOfInstallmentsTotal       int                    `json:"number_of_installments_total"`
+	NumberOfInstallmentsOutstanding int                    `json:"number_of_installments_outstanding"`
+	NextPaymentDate                 string                 `json:"next_payment_date,omitempty"`
+}
+
+// BelvoInterestRate is one of the rates charged on a loan
+type BelvoInterestRate struct {
+	Name  string  `json:"name,omitempty"`
+	Type  string  `json:"type"`  // "MONTHLY" or "YEARLY"
+	Value float64 `json:"value"` // In percent
+}
+
+// Debt is a loan or credit card balance normalized for analysis
+type Debt struct {
+	Source                string  `json:"source"` // "loan" or "credit_card"
+	ID                    string  `json:"id"`
+	Name                  string  `json:"name"`
+	Type                  string  `json:"type"`
+	Currency              string  `json:"currency"`
+	OutstandingPrincipal  float64 `json:"outstanding_principal"`
+	InterestBearing       float64 `json:"interest_bearing"`     // Part of the balance that accrues interest
+	AnnualInterestRate    float64 `json:"annual_interest_rate"` // Decimal, e.g. 0.12 for 12%
+	MonthlyPayment        float64 `json:"monthly_payment"`
+	InstallmentsRemaining int     `json:"installments_remaining,omitempty"`
+}
+
+// BalanceBreakdown splits account balances by what the money actually is
+type BalanceBreakdown struct {
+	LiquidAssets       float64 `json:"liquid_assets"`       // Checking and savings
+	InvestmentAccounts float64 `json:"investment_accounts"` // Investment and pension fund accounts
+	OtherAssets        float64 `json:"other_assets"`        // Asset accounts of any other category
+	Liabilities        float64 `json:"liabilities"`         // Credit card and loan balances owed
+	AvailableCredit    float64 `json:"available_credit"`    // Unused credit card limits
+	NetWorth           float64 `json:"net_worth"`
+}
+
+// BelvoInvestmentPortfolio represents a portfolio from Belvo's investments/portfolios resource
+type BelvoInvestmentPortfolio struct {
+	ID           string                      `json:"id"`
+	Link         string                      `json:"link"`
+	CollectedAt  BelvoTime                   `json:"collected_at"`
+	Name         string                      `json:"name"`
+	Type         string                      `json:"type"` // e.g. "FIXED_INCOME", "EQUITY", "FUND", "CRYPTO"
+	BalanceType  string                      `json:"balance_type"`
+	Currency     string                      `json:"currency"`
+	BalanceGross float64                     `json:"balance_gross"`
+	BalanceNet   float64                     `json:"balance_net"`
+	Instruments  []BelvoInvestmentInstrument `json:"instruments"`
+}
+
+// BelvoInvestmentInstrument represents one position inside a Belvo investment portfolio
+type BelvoInvestmentInstrument struct {
+	Type                    string   `json:"type"` // e.g. "BOND", "STOCK", "ETF", "FUND", "CRYPTO"
+	Code                    string   `json:"code"` // Ticker or internal code
+	PublicID                string   `json:"public_id"`
+	Name                    string   `json:"name"`
+	Currency                string   `json:"currency"`
+	Quantity                float64  `json:"quantity"`
+	Price                   float64  `json:"price"`
+	OpenPrice               *float64 `json:"open_price"`
+	AverageAcquisitionPrice *float64 `json:"average_acquisition_price"`
+	Profit                  *float64 `json:"profit"`
+	BalanceGross            float64  `json:"balance_gross"`
+	BalanceNet              float64  `json:"balance_net"`
+}
+
+// InvestmentHolding is a position the user already owns, mapped onto the asset
+// types and symbols used by the portfolio templates
+type InvestmentHolding struct {
+	Symbol         string    `json:"symbol"`
+	Name           string    `json:"name"`
+	Type           AssetType `json:"type"`
+	InstrumentType string    `json:"instrument_type"` // Belvo's instrument type
+	PortfolioID    string    `json:"portfolio_id"`
+	Quantity       float64   `json:"quantity"`
+	Price          float64   `json:"price"`
+	MarketValue    float64   `json:"market_value"`
+	Currency       string    `json:"currency"`
+	// Set when the position is held in a currency other than the summary's
+	OriginalCurrency    string  `json:"original_currency,omitempty"`
+	OriginalMarketValue float64 `json:"original_market_value,omitempty"`
 }
 
 // CreateLinkRequest represents the request to create a Belvo link
@@ -248,3 +563,77 @@ type CreateLinkResponse struct {
 	RefreshRate         string     `json:"refresh_rate"`
 	FetchHistoricalData bool       `json:"fetch_historical_data"`
 }
+
+// TransactionSyncState is the locally stored transaction history of a link and its
+// high-water marks, used to fetch only new transactions on the next sync
+type TransactionSyncState struct {
+	LinkID             string             `json:"link_id"`
+	LastAccountingDate time.Time          `json:"last_accounting_date"` // Latest accounting_date seen
+	LastCollectedAt    time.Time          `json:"last_collected_at"`    // Latest collected_at seen
+	LastSyncedAt       time.Time          `json:"last_synced_at"`
+	HistoryFrom        time.Time          `json:"history_from"` // Start of the stored history
+	Transactions       []BelvoTransaction `json:"transactions"`
+}
+
+// TransactionSyncResult reports what a sync fetched and changed
+type TransactionSyncResult struct {
+	LinkID             string    `json:"link_id"`
+	FullSync           bool      `json:"full_sync"` // True when no history was stored yet
+	DateFrom           time.Time `json:"date_from"`
+	DateTo             time.Time `json:"date_to"`
+	Fetched            int       `json:"fetched"`
+	Added              int       `json:"added"`
+	Updated            int       `json:"updated"`
+	Total              int       `json:"total"`
+	LastAccountingDate time.Time `json:"last_accounting_date"`
+	LastCollectedAt    time.Time `json:"last_collected_at"`
+	SyncedAt           time.Time `json:"synced_at"`
+}
+
+// BelvoBalanceSnapshot represents one entry of Belvo's balances resource
+type BelvoBalanceSnapshot struct {
+	ID             string                 `json:"id"`
+	Account        map[string]interface{} `json:"account"`
+	ValueDate      string                 `json:"value_date"`
+	CurrentBalance float64                `json:"current_balance"`
+	CollectedAt    BelvoTime              `json:"collected_at"`
+}
+
+// BalancePoint is an account balance at the end of a day, week or month
+type BalancePoint struct {
+	Date    time.Time `json:"date"`
+	Balance float64   `json:"balance"`
+}
+
+// AccountBalanceSeries is the balance history of one account
+type AccountBalanceSeries struct {
+	AccountID   string         `json:"account_id"`
+	AccountName string         `json:"account_name"`
+	Category    string         `json:"category"`
+	BalanceType string         `json:"balance_type"` // "ASSET" or "LIABILITY"
+	Currency    string         `json:"currency"`
+	Source      string         `json:"source"` // "balances", "transactions" or "current_balance"
+	Points      []BalancePoint `json:"points"`
+}
+
+// BalanceHistory holds the balance series of every account of a link
+type BalanceHistory struct {
+	LinkID      string                 `json:"link_id"`
+	From        time.Time              `json:"from"`
+	To          time.Time              `json:"to"`
+	Granularity string                 `json:"granularity"` // "daily", "weekly" or "monthly"
+	Accounts    []AccountBalanceSeries `json:"accounts"`
+	NetWorth    []BalancePoint         `json:"net_worth"` // Assets minus liabilities
+}
+
+// BalanceTrend summarizes how a balance moved over a period
+type BalanceTrend struct {
+	From          time.Time      `json:"from"`
+	To            time.Time      `json:"to"`
+	StartBalance  float64        `json:"start_balance"`
+	EndBalance    float64        `json:"end_balance"`
+	Change        float64        `json:"change"`
+	MonthlyChange float64        `json:"monthly_change"`
+	Direction     string         `json:"direction"` // "growing", "stable" or "shrinking"
+	Points        []BalancePoint `json:"points"`    // Month-end balances
+}
@@ -52,6 +52,9 @@ type AssetPerformance struct {
 	AnnualizedReturn      float64   `json:"annualized_return"`
 	Volatility            float64   `json:"volatility"`
 	LastUpdated           time.Time `json:"last_updated"`
+	// Set when prices were converted from the currency the asset trades in
+	OriginalCurrency string  `json:"original_currency,omitempty"`
+	OriginalPrice    float64 `json:"original_price,omitempty"`
 }
 
 // BrazilianRates represents Brazilian economic rates from Central Bank
@@ -87,7 +90,7 @@ type PortfolioAllocation struct {
 	Name       string    `json:"name"`
 	Type       AssetType `json:"type"`
 	Percentage float64   `json:"percentage"` // 0.0 to 1.0
-	Amount     float64   `json:"amount"`     // Amount in BRL
+	Amount     float64   `json:"amount"`     // Amount in the reporting currency
 }
 
 // PortfolioTemplate represents predefined portfolio allocations
@@ -132,6 +135,17 @@ type MarketDataSummary struct {
 	CryptoData     []CryptoData       `json:"crypto_data"`
 	LastUpdated    time.Time          `json:"last_updated"`
 	DataSources    []string           `json:"data_sources"`
+	Currency       string             `json:"currency,omitempty"` // Currency asset prices are reported in
+	FXRates        []FXRate           `json:"fx_rates,omitempty"`
+}
+
+// FXRate is how many units of Quote one unit of Base buys
+type FXRate struct {
+	Base   string    `json:"base"`
+	Quote  string    `json:"quote"`
+	Rate   float64   `json:"rate"`
+	Date   time.Time `json:"date"`
+	Source string    `json:"source"` // "bcb_ptax", "banxico_fix", "banrep_trm" or "local"
 }
 
 // Default asset definitions for our system
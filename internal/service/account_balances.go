@@ -0,0 +1,81 @@
+package service
+
+import (
+	"math"
+
+	"ai-financial-coach/internal/models"
+)
+
+// AccountClass groups Belvo account categories by how their balance counts towards net worth
+type AccountClass string
+
+const (
+	AccountClassLiquid     AccountClass = "liquid"     // Checking and savings: money that can be spent today
+	AccountClassInvestment AccountClass = "investment" // Investment and pension fund accounts
+	AccountClassCredit     AccountClass = "credit"     // Credit cards: the balance is owed, the limit is not cash
+	AccountClassLoan       AccountClass = "loan"       // Loans: the balance is outstanding principal
+	AccountClassOther      AccountClass = "other"
+)
+
+// ClassifyAccount maps an account's Belvo category onto an AccountClass
+func ClassifyAccount(account models.BelvoAccount) AccountClass {
+	switch account.Category {
+	case "CHECKING_ACCOUNT", "SAVINGS_ACCOUNT":
+		return AccountClassLiquid
+	case "INVESTMENT_ACCOUNT", "PENSION_FUND_ACCOUNT":
+		return AccountClassInvestment
+	case "CREDIT_CARD":
+		return AccountClassCredit
+	case "LOAN_ACCOUNT":
+		return AccountClassLoan
+	}
+	return AccountClassOther
+}
+
+// IsLiabilityAccount reports whether an account's balance is money owed rather than owned.
+// Categories Belvo doesn't document fall back to the account's balance_type.
+func IsLiabilityAccount(account models.BelvoAccount) bool {
+	switch ClassifyAccount(account) {
+	case AccountClassCredit, AccountClassLoan:
+		return true
+	case AccountClassOther:
+		return account.BalanceType == "LIABILITY"
+	}
+	return false
+}
+
+// SummarizeBalances classifies account balances into liquid assets, other assets,
+// liabilities and available credit. Current balances are used throughout: on a credit
+// card Available is the unused limit, which is credit to draw on, not cash.
+func SummarizeBalances(accounts []models.BelvoAccount) models.BalanceBreakdown {
+	var breakdown models.BalanceBreakdown
+
+	for _, account := range accounts {
+		switch ClassifyAccount(account) {
+		case AccountClassLiquid:
+			breakdown.LiquidAssets += account.Balance.Current
+		case AccountClassInvestment:
+			breakdown.InvestmentAccounts += account.Balance.Current
+		case AccountClassCredit:
+			// Institutions report the amount owed with either sign
+			breakdown.Liabilities += math.Abs(account.Balance.Current)
+			breakdown.AvailableCredit += math.Max(account.Balance.Available, 0)
+		case AccountClassLoan:
+			breakdown.Liabilities += math.Abs(account.Balance.Current)
+		default:
+			if account.BalanceType == "LIABILITY" {
+				breakdown.Liabilities += math.Abs(account.Balance.Current)
+			} else {
+				breakdown.OtherAssets += account.Balance.Current
+			}
+		}
+	}
+
+	breakdown.LiquidAssets = roundCents(breakdown.LiquidAssets)
+	breakdown.InvestmentAccounts = roundCents(breakdown.InvestmentAccounts)
+	breakdown.OtherAssets = roundCents(breakdown.OtherAssets)
+	breakdown.Liabilities = roundCents(breakdown.Liabilities)
+	breakdown.AvailableCredit = roundCents(breakdown.AvailableCredit)
+	breakdown.NetWorth = roundCents(breakdown.LiquidAssets + breakdown.InvestmentAccounts + breakdown.OtherAssets - breakdown.Liabilities)
+	return breakdown
+}
@@ -1,12 +1,10 @@
 package service
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"math"
-	"net/http"
 	"strings"
 	"time"
 
@@ -15,30 +13,56 @@ import (
 
 // AIService handles AI-powered financial analysis
 type AIService struct {
-	httpClient    *http.Client
-	openAIAPIKey  string
-	openAIBaseURL string
-	model         string
+	llm           *LLMConfig            // nil runs chat in mock mode and summaries from templates
+	customerLLM   map[string]*LLMConfig // Per-customer overrides, keyed by link ID
 	marketService *MarketService
-	belvoService  *BelvoService
+	belvoService  BelvoClient
 }
 
-// NewAIService creates a new AIService instance
-func NewAIService(openAIAPIKey string, marketService *MarketService, belvoService *BelvoService) *AIService {
+// NewAIService creates a new AIService instance. A nil llm disables the language model.
+func NewAIService(llm *LLMConfig, marketService *MarketService, belvoService BelvoClient) *AIService {
 	return &AIService{
-		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
-		},
-		openAIAPIKey:  openAIAPIKey,
-		openAIBaseURL: "https://api.openai.com/v1",
-		model:         "gpt-4o-mini",
+		llm:           llm,
+		customerLLM:   make(map[string]*LLMConfig),
 		marketService: marketService,
 		belvoService:  belvoService,
 	}
 }
 
+// SetCustomerLLM routes a customer's completions to their own provider, e.g. a
+// self-hosted model for customers whose data must not leave our infrastructure
+func (ai *AIService) SetCustomerLLM(linkID string, llm *LLMConfig) {
+	ai.customerLLM[linkID] = llm
+}
+
+// ErrConflictingLLMRoutes reports a request about links routed to different models
+var ErrConflictingLLMRoutes = errors.New("links are routed to different language models; ask about them separately")
+
+// llmFor returns the config for a request about the given links: the override they
+// share, otherwise the default. Links routed to different overrides are an error rather
+// than a reason to pick one, so a customer's data never reaches a model it isn't
+// routed to. Nil means no model is configured.
+func (ai *AIService) llmFor(linkIDs ...string) (*LLMConfig, error) {
+	var routed *LLMConfig
+	routedLinkID := ""
+	for _, linkID := range linkIDs {
+		llm, ok := ai.customerLLM[linkID]
+		if !ok {
+			continue
+		}
+		if routed != nil && llm != routed {
+			return nil, fmt.Errorf("%w: %s and %s", ErrConflictingLLMRoutes, routedLinkID, linkID)
+		}
+		routed, routedLinkID = llm, linkID
+	}
+	if routed != nil {
+		return routed, nil
+	}
+	return ai.llm, nil
+}
+
 // AnalyzeFinancialProfile performs comprehensive AI analysis
-func (ai *AIService) AnalyzeFinancialProfile(request *models.AIAnalysisRequest) (*models.AIAnalysisResponse, error) {
+func (ai *AIService) AnalyzeFinancialProfile(ctx context.Context, request *models.AIAnalysisRequest) (*models.AIAnalysisResponse, error) {
 	// 1. Calculate portfolio recommendation
 	portfolio, err := ai.calculatePortfolioRecommendation(request)
 	if err != nil {
@@ -46,7 +70,11 @@ func (ai *AIService) AnalyzeFinancialProfile(request *models.AIAnalysisRequest)
 	}
 
 	// 2. Generate projections
-	projections, err := ai.calculateProjections(portfolio, request.InvestmentHorizon, request.MonthlyBudget)
+	monthlyBudget := request.MonthlyBudget
+	if portfolio.DebtPaydownFirst {
+		monthlyBudget = 0 // The budget goes to debt, so only existing holdings grow
+	}
+	projections, err := ai.calculateProjections(portfolio, request.InvestmentHorizon, monthlyBudget)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate projections: %w", err)
 	}
@@ -70,7 +98,7 @@ func (ai *AIService) AnalyzeFinancialProfile(request *models.AIAnalysisRequest)
 	}
 
 	// 6. Generate AI summary
-	summary, err := ai.generateAISummary(request, portfolio, projections, analysis)
+	summary, err := ai.generateAISummary(ctx, request, portfolio, projections, analysis)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate AI summary: %w", err)
 	}
@@ -103,6 +131,11 @@ func (ai *AIService) calculatePortfolioRecommendation(request *models.AIAnalysis
 	// Localize template name
 	template.Name = ai.localizeTemplateName(template.Name, request.Language)
 
+	// Allocation amounts are in the summary's reporting currency
+	if request.FinancialSummary.Currency != "" {
+		template.Currency = request.FinancialSummary.Currency
+	}
+
 	// Calculate safe monthly investment amount
 	surplus := request.FinancialSummary.MonthlySurplus
 	safeInvestmentPercentage := 0.8 // Invest 80% of surplus by default
@@ -126,16 +159,130 @@ func (ai *AIService) calculatePortfolioRecommendation(request *models.AIAnalysis
 
 	rationale := ai.localizeRationale(request.RiskProfile, request.Language)
 
+	// Paying down debt that costs more than any template returns beats investing
+	debt := AnalyzeDebt(request.FinancialSummary)
+	if debt.PayDownFirst {
+		monthlyInvestment = 0
+	}
+
+	// Start from what the user already owns instead of an empty portfolio
+	currentAllocations, currentValue := currentAllocationsFromHoldings(template, request.FinancialSummary.Holdings)
+	customAllocations := contributionAllocations(template, currentAllocations, currentValue, monthlyInvestment)
+	if currentValue > 0 {
+		rationale += ai.localizeHoldingsNote(currentValue, request.Language)
+	}
+	if debt.PayDownFirst {
+		rationale += ai.localizeDebtNote(debt.HighInterestDebt, request.Language)
+	}
+
 	return &models.PortfolioRecommendation{
-		Template:          template,
-		CustomAllocations: template.Allocations,
-		ExpectedReturn:    expectedReturn,
-		ExpectedRisk:      template.MaxDrawdown,
-		MonthlyInvestment: monthlyInvestment,
-		Rationale:         rationale,
+		Template:           template,
+		CustomAllocations:  customAllocations,
+		ExpectedReturn:     expectedReturn,
+		ExpectedRisk:       template.MaxDrawdown,
+		MonthlyInvestment:  monthlyInvestment,
+		Rationale:          rationale,
+		CurrentAllocations: currentAllocations,
+		CurrentValue:       currentValue,
+		DebtPaydownFirst:   debt.PayDownFirst,
 	}, nil
 }
 
+// currentAllocationsFromHoldings groups existing holdings under the template's symbols.
+// A holding without an exact symbol match joins the template allocation of the same
+// asset type (stocks count towards the equity ETF); anything else keeps its own symbol.
+func currentAllocationsFromHoldings(template models.PortfolioTemplate, holdings []models.InvestmentHolding) ([]models.PortfolioAllocation, float64) {
+	if len(holdings) == 0 {
+		return nil, 0
+	}
+
+	bucketFor := func(holding models.InvestmentHolding) models.PortfolioAllocation {
+		for _, allocation := range template.Allocations {
+			if allocation.Symbol == holding.Symbol {
+				return models.PortfolioAllocation{Symbol: allocation.Symbol, Name: allocation.Symbol, Type: allocation.Type}
+			}
+		}
+		holdingType := holding.Type
+		if holdingType == models.AssetTypeEquity {
+			holdingType = models.AssetTypeETF
+		}
+		for _, allocation := range template.Allocations {
+			if allocation.Type == holdingType {
+				return models.PortfolioAllocation{Symbol: allocation.Symbol, Name: allocation.Symbol, Type: allocation.Type}
+			}
+		}
+		return models.PortfolioAllocation{Symbol: holding.Symbol, Name: holding.Name, Type: holding.Type}
+	}
+
+	var allocations []models.PortfolioAllocation
+	index := make(map[string]int)
+	total := 0.0
+	for _, holding := range holdings {
+		bucket := bucketFor(holding)
+		i, ok := index[bucket.Symbol]
+		if !ok {
+			i = len(allocations)
+			index[bucket.Symbol] = i
+			allocations = append(allocations, bucket)
+		}
+		allocations[i].Amount += holding.MarketValue
+		total += holding.MarketValue
+	}
+
+	for i := range allocations {
+		allocations[i].Percentage = allocations[i].Amount / total
+	}
+	return allocations, total
+}
+
+// contributionAllocations splits the monthly investment across the template. Money goes
+// to the most underweight allocations first; once every allocation has reached its
+// target share of the grown portfolio, the rest follows the template percentages.
+func contributionAllocations(template models.PortfolioTemplate, current []models.PortfolioAllocation, currentValue, monthlyInvestment float64) []models.PortfolioAllocation {
+	held := make(map[string]float64, len(current))
+	for _, allocation := range current {
+		held[allocation.Symbol] = allocation.Amount
+	}
+
+	targetTotal := currentValue + monthlyInvestment
+	gaps := make([]float64, len(template.Allocations))
+	totalGap := 0.0
+	for i, allocation := range template.Allocations {
+		gaps[i] = math.Max(0, allocation.Percentage*targetTotal-held[allocation.Symbol])
+		totalGap += gaps[i]
+	}
+
+	allocations := make([]models.PortfolioAllocation, len(template.Allocations))
+	for i, allocation := range template.Allocations {
+		allocations[i] = allocation
+		switch {
+		case monthlyInvestment <= 0:
+			allocations[i].Amount = 0
+		case totalGap >= monthlyInvestment:
+			allocations[i].Amount = monthlyInvestment * gaps[i] / totalGap
+		default:
+			allocations[i].Amount = gaps[i] + (monthlyInvestment-totalGap)*allocation.Percentage
+		}
+	}
+	return allocations
+}
+
+// localizeHoldingsNote mentions existing investments in the rationale
+func (ai *AIService) localizeHoldingsNote(currentValue float64, language string) string {
+	if language == "en-US" {
+		return fmt.Sprintf(", starting from your current investments of R$ %.2f", currentValue)
+	}
+	return fmt.Sprintf(", partindo dos seus investimentos atuais de R$ %.2f", currentValue)
+}
+
+// localizeDebtNote explains why nothing is invested until high-interest debt is paid
+func (ai *AIService) localizeDebtNote(highInterestDebt float64, language string) string {
+	if language == "en-US" {
+		return fmt.Sprintf(". Pay off the R$ %.2f of high-interest debt before investing", highInterestDebt)
+	}
+	return fmt.Sprintf(". Quite os R$ %.2f de dívidas com juros altos antes de investir", highInterestDebt)
+}
+
 // localizeTemplateName translates portfolio template names
 func (ai *AIService) localizeTemplateName(name, language string) string {
 	if language == "en-US" {
@@ -205,6 +352,7 @@ func (ai *AIService) calculateProjections(portfolio *models.PortfolioRecommendat
 
 	monthlyRate := portfolio.ExpectedReturn / 12
 	totalMonths := years * 12
+	initialAmount := portfolio.CurrentValue // Existing holdings grow alongside the contributions
 
 	var projections []models.ProjectionPoint
 	var totalContributed, totalValue float64
@@ -212,14 +360,15 @@ func (ai *AIService) calculateProjections(portfolio *models.PortfolioRecommendat
 	for month := 1; month <= totalMonths; month++ {
 		totalContributed += monthlyContribution
 
-		// Future Value of Annuity: FV = P * (((1+r)^n - 1) / r)
+		// Future Value of Annuity: FV = P * (((1+r)^n - 1) / r), plus the initial amount compounded
 		if monthlyRate > 0 {
-			totalValue = monthlyContribution * ((math.Pow(1+monthlyRate, float64(month)) - 1) / monthlyRate)
+			totalValue = monthlyContribution*((math.Pow(1+monthlyRate, float64(month))-1)/monthlyRate) +
+				initialAmount*math.Pow(1+monthlyRate, float64(month))
 		} else {
-			totalValue = totalContributed
+			totalValue = totalContributed + initialAmount
 		}
 
-		gains := totalValue - totalContributed
+		gains := totalValue - totalContributed - initialAmount
 		monthlyReturn := 0.0
 		if month > 1 {
 			monthlyReturn = monthlyRate * 100
@@ -238,14 +387,14 @@ func (ai *AIService) calculateProjections(portfolio *models.PortfolioRecommendat
 	return &models.PortfolioProjection{
 		Years:               years,
 		MonthlyContribution: monthlyContribution,
-		InitialAmount:       0,
+		InitialAmount:       initialAmount,
 		ExpectedReturn:      portfolio.ExpectedReturn,
 		Allocations:         portfolio.CustomAllocations,
 		Projections:         projections,
 		TotalFinalValue:     totalValue,
 		TotalContributed:    totalContributed,
-		TotalGains:          totalValue - totalContributed,
-		Currency:            "BRL",
+		TotalGains:          totalValue - totalContributed - initialAmount,
+		Currency:            portfolio.Template.Currency,
 	}, nil
 }
 
@@ -266,12 +415,16 @@ func (ai *AIService) analyzeFinancialData(summary *models.FinancialSummary, mark
 	// Identify market opportunities
 	opportunities := ai.identifyMarketOpportunities(marketData)
 
+	// Analyze debt
+	debtAnalysis := AnalyzeDebt(summary)
+
 	return &models.FinancialAnalysis{
 		SurplusAnalysis:      *surplusAnalysis,
 		SpendingPatterns:     *spendingPatterns,
 		InvestmentReadiness:  *readiness,
 		MarketOpportunities:  opportunities,
 		FinancialHealthScore: healthScore,
+		DebtAnalysis:         *debtAnalysis,
 	}, nil
 }
 
@@ -327,9 +480,14 @@ func (ai *AIService) analyzeSurplus(summary *models.FinancialSummary) *models.Su
 	availableForInvestment := summary.MonthlySurplus
 	if summary.TotalBalance < emergencyTarget {
 		availableForInvestment = math.Max(0, summary.MonthlySurplus*0.5) // 50% until emergency fund is built
+
+		// What the balances actually did beats the estimated surplus
+		if trend := summary.EmergencyFundTrend; trend != nil && trend.MonthlyChange < availableForInvestment {
+			availableForInvestment = math.Max(0, trend.MonthlyChange)
+		}
 	}
 
-	return &models.SurplusAnalysis{
+	analysis := &models.SurplusAnalysis{
 		MonthlySurplus:         summary.MonthlySurplus,
 		RecommendedInvestment:  0.8, // 80% of surplus
 		EmergencyFundTarget:    emergencyTarget,
@@ -337,6 +495,11 @@ func (ai *AIService) analyzeSurplus(summary *models.FinancialSummary) *models.Su
 		SafeInvestmentAmount:   availableForInvestment,
 		ConservativePercentage: 0.6,
 	}
+	if trend := summary.EmergencyFundTrend; trend != nil {
+		analysis.EmergencyFundTrend = trend.Direction
+		analysis.EmergencyFundChange = trend.MonthlyChange
+	}
+	return analysis
 }
 
 // analyzeSpendingPatterns analyzes user spending behavior
@@ -372,14 +535,26 @@ func (ai *AIService) assessInvestmentReadiness(summary *models.FinancialSummary,
 		level = "somewhat_ready"
 	}
 
+	keyFactors := []string{
+		fmt.Sprintf("Renda mensal: R$ %.2f", summary.MonthlyIncome),
+		fmt.Sprintf("Sobra mensal: R$ %.2f", summary.MonthlySurplus),
+		fmt.Sprintf("Reserva atual: R$ %.2f", summary.TotalBalance),
+	}
+	if trend := summary.EmergencyFundTrend; trend != nil {
+		keyFactors = append(keyFactors, fmt.Sprintf("Evolução da reserva: %s (R$ %+.2f/mês)", localizeTrendDirection(trend.Direction), trend.MonthlyChange))
+	}
+	if period := summary.Period; period != nil && period.Confidence != "high" {
+		keyFactors = append(keyFactors, fmt.Sprintf("Histórico de %.1f meses: médias com confiança %s", period.MonthsOfData, localizeConfidence(period.Confidence)))
+	}
+	if len(summary.Debts) > 0 {
+		debt := AnalyzeDebt(summary)
+		keyFactors = append(keyFactors, fmt.Sprintf("Dívidas: R$ %.2f (%.0f%% da renda em parcelas, custo médio de %.1f%% a.a.)", debt.TotalDebt, debt.DebtToIncomeRatio*100, debt.WeightedInterestRate*100))
+	}
+
 	return &models.InvestmentReadiness{
 		Score:          score,
 		ReadinessLevel: level,
-		KeyFactors: []string{
-			fmt.Sprintf("Renda mensal: R$ %.2f", summary.MonthlyIncome),
-			fmt.Sprintf("Sobra mensal: R$ %.2f", summary.MonthlySurplus),
-			fmt.Sprintf("Reserva atual: R$ %.2f", summary.TotalBalance),
-		},
+		KeyFactors:     keyFactors,
 		ImprovementAreas: []string{
 			"Construir reserva de emergência",
 			"Reduzir gastos desnecessários",
@@ -389,6 +564,30 @@ func (ai *AIService) assessInvestmentReadiness(summary *models.FinancialSummary,
 	}
 }
 
+// localizeConfidence translates a summary confidence level to Portuguese
+func localizeConfidence(confidence string) string {
+	switch confidence {
+	case "high":
+		return "alta"
+	case "medium":
+		return "média"
+	default:
+		return "baixa"
+	}
+}
+
+// localizeTrendDirection translates a balance trend direction to Portuguese
+func localizeTrendDirection(direction string) string {
+	switch direction {
+	case "growing":
+		return "crescendo"
+	case "shrinking":
+		return "diminuindo"
+	default:
+		return "estável"
+	}
+}
+
 // identifyMarketOpportunities analyzes current market conditions
 func (ai *AIService) identifyMarketOpportunities(marketData *models.MarketDataSummary) []models.MarketOpportunity {
 	var opportunities []models.MarketOpportunity
@@ -424,6 +623,18 @@ func (ai *AIService) generateRecommendations(request *models.AIAnalysisRequest,
 	var recommendations []models.ActionRecommendation
 	language := request.Language
 
+	// High-interest debt comes before everything else
+	if analysis.DebtAnalysis.PayDownFirst {
+		recommendations = append(recommendations, models.ActionRecommendation{
+			Priority:    "immediate",
+			Action:      ai.localizeText("pay_high_interest_debt", language),
+			Description: ai.localizeDescription("pay_high_interest_debt_desc", language, analysis.DebtAnalysis.HighInterestDebt, analysis.DebtAnalysis.HighInterestThreshold*100),
+			Impact:      "high",
+			Effort:      "moderate",
+			Timeline:    ai.localizeText("3_6_months", language),
+		})
+	}
+
 	// Emergency fund recommendation
 	if analysis.SurplusAnalysis.CurrentEmergencyFund < analysis.SurplusAnalysis.EmergencyFundTarget {
 		recommendations = append(recommendations, models.ActionRecommendation{
@@ -437,7 +648,7 @@ func (ai *AIService) generateRecommendations(request *models.AIAnalysisRequest,
 	}
 
 	// Investment start recommendation
-	if analysis.InvestmentReadiness.ReadinessLevel != "not_ready" {
+	if analysis.InvestmentReadiness.ReadinessLevel != "not_ready" && !portfolio.DebtPaydownFirst {
 		recommendations = append(recommendations, models.ActionRecommendation{
 			Priority:    "short_term",
 			Action:      ai.localizeText("start_investments", language),
@@ -469,6 +680,8 @@ func (ai *AIService) localizeText(key, language string) string {
 			return "Build Emergency Fund"
 		case "start_investments":
 			return "Start Investments"
+		case "pay_high_interest_debt":
+			return "Pay Off High-Interest Debt"
 		case "diversify_portfolio":
 			return "Diversify Portfolio"
 		case "diversify_portfolio_desc":
@@ -489,6 +702,8 @@ func (ai *AIService) localizeText(key, language string) string {
 		return "Construir Reserva de Emergência"
 	case "start_investments":
 		return "Iniciar Investimentos"
+	case "pay_high_interest_debt":
+		return "Quitar Dívidas com Juros Altos"
 	case "diversify_portfolio":
 		return "Diversificar Portfolio"
 	case "diversify_portfolio_desc":
@@ -518,6 +733,11 @@ func (ai *AIService) localizeDescription(key, language string, params ...interfa
 				return fmt.Sprintf("Start investing $%.2f monthly with %s profile", params[0], params[1])
 			}
 			return "Start investing monthly with recommended profile"
+		case "pay_high_interest_debt_desc":
+			if len(params) >= 2 {
+				return fmt.Sprintf("Pay off $%.2f of debt charging more than %.0f%% a year before investing", params[0], params[1])
+			}
+			return "Pay off high-interest debt before investing"
 		default:
 			return key
 		}
@@ -534,6 +754,11 @@ func (ai *AIService) localizeDescription(key, language string, params ...interfa
 			return fmt.Sprintf("Comece investindo R$ %.2f mensalmente no perfil %s", params[0], params[1])
 		}
 		return "Comece investindo mensalmente no perfil recomendado"
+	case "pay_high_interest_debt_desc":
+		if len(params) >= 2 {
+			return fmt.Sprintf("Quite R$ %.2f em dívidas com juros acima de %.0f%% ao ano antes de investir", params[0], params[1])
+		}
+		return "Quite as dívidas com juros altos antes de investir"
 	default:
 		return key
 	}
@@ -572,27 +797,30 @@ func (ai *AIService) assessRisks(portfolio *models.PortfolioRecommendation, summ
 }
 
 // generateAISummary creates a comprehensive AI-generated summary
-func (ai *AIService) generateAISummary(request *models.AIAnalysisRequest, portfolio *models.PortfolioRecommendation, projections *models.PortfolioProjection, analysis *models.FinancialAnalysis) (string, error) {
-	if ai.openAIAPIKey == "" {
-		// Return a template summary if no API key
+func (ai *AIService) generateAISummary(ctx context.Context, request *models.AIAnalysisRequest, portfolio *models.PortfolioRecommendation, projections *models.PortfolioProjection, analysis *models.FinancialAnalysis) (string, error) {
+	llm, err := ai.llmFor(analysisLinkIDs(request)...)
+	if err != nil {
+		return "", err
+	}
+	if llm == nil {
+		// Return a template summary if no model is configured
 		return ai.generateTemplateSummary(request, portfolio, projections, analysis), nil
 	}
 
 	prompt := ai.buildAIPrompt(request, portfolio, projections, analysis)
 
-	llmRequest := models.LLMRequest{
-		Model:       ai.model,
-		Temperature: 0.7,
-		MaxTokens:   500,
-		Messages: []models.LLMMessage{
-			{Role: "system", Content: ai.getSystemPrompt(request.Language)},
-			{Role: "user", Content: prompt},
-		},
-	}
+	llmRequest := llm.Request(LLMUseSummary, []models.LLMMessage{
+		{Role: "system", Content: ai.getSystemPrompt(request.Language)},
+		{Role: "user", Content: prompt},
+	})
 
-	response, err := ai.callOpenAI(llmRequest)
+	response, err := llm.Provider.Complete(ctx, llmRequest)
+	if ctx.Err() != nil {
+		return "", ctx.Err() // The caller went away; don't build a fallback nobody will read
+	}
 	if err != nil {
 		// Fallback to template summary
+		fmt.Printf("⚠️ %s summary failed, using the template: %v\n", llm.Provider.Name(), err)
 		return ai.generateTemplateSummary(request, portfolio, projections, analysis), nil
 	}
 
@@ -603,42 +831,22 @@ func (ai *AIService) generateAISummary(request *models.AIAnalysisRequest, portfo
 	return ai.generateTemplateSummary(request, portfolio, projections, analysis), nil
 }
 
-// callOpenAI makes a request to OpenAI API
-func (ai *AIService) callOpenAI(request models.LLMRequest) (*models.LLMResponse, error) {
-	jsonData, err := json.Marshal(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", ai.openAIBaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+ai.openAIAPIKey)
-
-	resp, err := ai.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OpenAI API error: status %d", resp.StatusCode)
-	}
+// analysisLinkIDs returns the links an analysis is about, for picking the customer's provider
+func analysisLinkIDs(request *models.AIAnalysisRequest) []string {
+	linkIDs := append([]string{request.UserID}, request.SessionLinkIDs...)
+	return append(linkIDs, summaryLinkIDs(request.FinancialSummary)...)
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+// summaryLinkIDs returns every link a summary's data came from, as far as it tells
+func summaryLinkIDs(summary *models.FinancialSummary) []string {
+	if summary == nil {
+		return nil
 	}
-
-	var response models.LLMResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	linkIDs := append(strings.Split(summary.UserID, ","), summary.LinkIDs...)
+	for _, account := range summary.Accounts {
+		linkIDs = append(linkIDs, account.Link)
 	}
-
-	return &response, nil
+	return linkIDs
 }
 
 // getSystemPrompt returns the system prompt for the AI coach
@@ -691,6 +899,7 @@ SITUAÇÃO FINANCEIRA:
 - Gastos totais: R$ %.2f  
 - Sobra mensal: R$ %.2f
 - Reserva atual: R$ %.2f
+- Dívidas: R$ %.2f (parcelas de %.0f%% da renda, juros médios de %.1f%% ao ano)
 - Saúde financeira: %.0f/100
 
 RECOMENDAÇÃO DE PORTFOLIO:
@@ -709,6 +918,9 @@ Forneça uma análise personalizada e recomendações práticas.`,
 		request.FinancialSummary.MonthlyFixedExpenses+request.FinancialSummary.MonthlyVariableExpenses,
 		request.FinancialSummary.MonthlySurplus,
 		request.FinancialSummary.TotalBalance,
+		analysis.DebtAnalysis.TotalDebt,
+		analysis.DebtAnalysis.DebtToIncomeRatio*100,
+		analysis.DebtAnalysis.WeightedInterestRate*100,
 		analysis.FinancialHealthScore,
 		portfolio.Template.RiskLevel,
 		portfolio.MonthlyInvestment,
@@ -746,6 +958,9 @@ func (ai *AIService) generateTemplateSummary(request *models.AIAnalysisRequest,
 		projections.TotalFinalValue,
 		projections.TotalGains,
 		func() string {
+			if analysis.DebtAnalysis.PayDownFirst {
+				return fmt.Sprintf("Quite os R$ %.2f de dívidas com juros altos antes de investir", analysis.DebtAnalysis.HighInterestDebt)
+			}
 			if analysis.SurplusAnalysis.CurrentEmergencyFund < analysis.SurplusAnalysis.EmergencyFundTarget {
 				return "Construa sua reserva de emergência primeiro"
 			}
@@ -754,7 +969,11 @@ func (ai *AIService) generateTemplateSummary(request *models.AIAnalysisRequest,
 }
 
 // GenerateWhatIfScenario creates scenario analysis
-func (ai *AIService) GenerateWhatIfScenario(baseRequest *models.AIAnalysisRequest, scenarioParams *models.ScenarioParameters) (*models.WhatIfScenario, error) {
+func (ai *AIService) GenerateWhatIfScenario(ctx context.Context, baseRequest *models.AIAnalysisRequest, scenarioParams *models.ScenarioParameters) (*models.WhatIfScenario, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Create modified request for scenario
 	modifiedRequest := *baseRequest
 	modifiedRequest.RiskProfile = scenarioParams.RiskLevel
@@ -794,12 +1013,127 @@ func (ai *AIService) GenerateWhatIfScenario(baseRequest *models.AIAnalysisReques
 }
 
 // Chat handles conversational AI interactions with financial context
-func (ai *AIService) Chat(request *models.ChatRequest) (*models.ChatResponse, error) {
-	if ai.openAIAPIKey == "" {
-		// Fallback mode when OpenAI API key is not configured
-		return ai.generateMockChatResponse(request), nil
+func (ai *AIService) Chat(ctx context.Context, request *models.ChatRequest) (*models.ChatResponse, error) {
+	// Generate conversation ID if not provided
+	conversationID := request.ConversationID
+	if conversationID == "" {
+		conversationID = NewConversationID()
+	}
+
+	llm, err := ai.llmFor(chatLinkIDs(request)...)
+	if err != nil {
+		return nil, err
+	}
+	if llm == nil {
+		// Fallback mode when no language model is configured
+		response := ai.generateMockChatResponse(request)
+		response.ConversationID = conversationID
+		return response, nil
+	}
+
+	// Call the language model, running the tools it asks for
+	response, err := ai.completeChat(ctx, llm, request, func(llmRequest models.LLMRequest) (*models.LLMResponse, error) {
+		return llm.Provider.Complete(ctx, llmRequest)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response.ConversationID = conversationID
+
+	// Check if user is asking for dashboard
+	response.ShowDashboard = ai.shouldShowDashboard(request.Message, request.Language)
+	return response, nil
+}
+
+// ChatStream answers like Chat but hands each piece of the answer to onDelta as the
+// model generates it. Without a model the mock answer is streamed word by word, so the
+// stream can be exercised offline.
+func (ai *AIService) ChatStream(ctx context.Context, request *models.ChatRequest, onDelta func(string) error) (*models.ChatResponse, error) {
+	conversationID := request.ConversationID
+	if conversationID == "" {
+		conversationID = NewConversationID()
 	}
 
+	llm, err := ai.llmFor(chatLinkIDs(request)...)
+	if err != nil {
+		return nil, err
+	}
+	if llm == nil {
+		response := ai.generateMockChatResponse(request)
+		for _, word := range strings.SplitAfter(response.Message, " ") {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if err := onDelta(word); err != nil {
+				return nil, err
+			}
+		}
+		response.ConversationID = conversationID
+		return response, nil
+	}
+
+	response, err := ai.completeChat(ctx, llm, request, func(llmRequest models.LLMRequest) (*models.LLMResponse, error) {
+		return llm.Provider.Stream(ctx, llmRequest, onDelta)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response.ConversationID = conversationID
+	response.ShowDashboard = ai.shouldShowDashboard(request.Message, request.Language)
+	return response, nil
+}
+
+// completeChat sends the chat to the model with the chat tools. While the model asks for
+// tools, they are run and their results sent back; after maxToolRounds it must answer.
+// complete is the provider's Complete or Stream, so text written before a tool call is
+// streamed too.
+func (ai *AIService) completeChat(ctx context.Context, llm *LLMConfig, request *models.ChatRequest, complete func(models.LLMRequest) (*models.LLMResponse, error)) (*models.ChatResponse, error) {
+	llmRequest := llm.Request(LLMUseChat, ai.chatMessages(request))
+	llmRequest.Tools = chatToolDefinitions()
+
+	var usage models.LLMUsage
+	var toolsUsed []string
+	for round := 0; ; round++ {
+		if round == maxToolRounds {
+			llmRequest.ToolChoice = "none"
+		}
+
+		response, err := complete(llmRequest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get AI response from %s: %w", llm.Provider.Name(), err)
+		}
+		if len(response.Choices) == 0 {
+			return nil, fmt.Errorf("no response from AI")
+		}
+		usage.PromptTokens += response.Usage.PromptTokens
+		usage.CompletionTokens += response.Usage.CompletionTokens
+		usage.TotalTokens += response.Usage.TotalTokens
+
+		message := response.Choices[0].Message
+		if len(message.ToolCalls) == 0 || round == maxToolRounds {
+			return &models.ChatResponse{
+				Message:     message.Content,
+				Language:    request.Language,
+				GeneratedAt: time.Now(),
+				TokensUsed:  usage.TotalTokens,
+				Usage:       &usage,
+				ToolsUsed:   toolsUsed,
+			}, nil
+		}
+
+		llmRequest.Messages = append(llmRequest.Messages, message)
+		for _, call := range message.ToolCalls {
+			llmRequest.Messages = append(llmRequest.Messages, ai.runChatTool(ctx, request, call))
+			toolsUsed = append(toolsUsed, call.Function.Name)
+		}
+	}
+}
+
+// chatMessages builds the conversation sent to the model: the coaching prompt, the
+// user's financial context, recent history and the new message
+func (ai *AIService) chatMessages(request *models.ChatRequest) []models.LLMMessage {
 	// Build the system prompt with financial coaching context
 	systemPrompt := ai.buildFinancialCoachSystemPrompt(request.Language)
 
@@ -832,38 +1166,15 @@ func (ai *AIService) Chat(request *models.ChatRequest) (*models.ChatResponse, er
 		Content: request.Message,
 	})
 
-	// Check if user is asking for dashboard
-	showDashboard := ai.shouldShowDashboard(request.Message, request.Language)
-
-	// Call OpenAI
-	llmRequest := models.LLMRequest{
-		Model:       ai.model,
-		Temperature: 0.7,
-		MaxTokens:   500,
-		Messages:    messages,
-	}
-
-	response, err := ai.callOpenAI(llmRequest)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get AI response: %w", err)
-	}
-
-	if len(response.Choices) == 0 {
-		return nil, fmt.Errorf("no response from AI")
-	}
-
-	// Generate conversation ID if not provided
-	conversationID := request.ConversationID
-	if conversationID == "" {
-		conversationID = fmt.Sprintf("chat_%d", time.Now().Unix())
-	}
+	return messages
+}
 
-	return &models.ChatResponse{
-		Message:       response.Choices[0].Message.Content,
-		ShowDashboard: showDashboard,
-		Language:      request.Language,
-		GeneratedAt:   time.Now(),
-	}, nil
+// chatLinkIDs returns the links a chat is about, for picking the customer's provider.
+// The links the server tied the chat to count whatever the request says.
+func chatLinkIDs(request *models.ChatRequest) []string {
+	linkIDs := append([]string{request.LinkID}, request.LinkIDs...)
+	linkIDs = append(linkIDs, request.SessionLinkIDs...)
+	return append(linkIDs, summaryLinkIDs(request.UserContext)...)
 }
 
 // buildFinancialCoachSystemPrompt creates the system prompt for financial coaching
@@ -881,6 +1192,7 @@ Diretrizes:
 - Mantenha respostas ≤ 300 palavras
 - Seja amigável mas profissional
 - Use dados fornecidos quando disponíveis
+- Use as ferramentas para consultar transações, gastos por categoria, cenários, desempenho de ativos e carteiras modelo; o contexto traz só uma amostra
 - Inclua 3 itens de ação curtos quando dar conselhos
 - Sempre inclua disclaimer sobre não ser consultor licenciado
 - Se perguntarem sobre dashboard, sugira que digitem "dashboard"
@@ -908,6 +1220,7 @@ Guidelines:
 - Keep responses ≤ 500 words when possible, unless user asks for details for transactions or things like that
 - Be friendly but professional
 - Use provided data when available
+- Call the tools to look up transactions, spending by category, what-if scenarios, asset performance and portfolio templates; the context only holds a sample
 - Include 3 short action items when giving advice, if you think it's relevant
 - Always include disclaimer about not being licensed advisor when giving advice
 
@@ -934,7 +1247,46 @@ func (ai *AIService) buildUserContextMessage(request *models.ChatRequest) string
 		context += fmt.Sprintf(", Accounts: %d accounts", len(request.UserContext.Accounts))
 		for i, account := range request.UserContext.Accounts {
 			if i < 3 { // Limit to first 3 accounts to avoid token overflow
-				context += fmt.Sprintf(" | %s: $%.2f", account.Name, account.Balance.Available)
+				context += fmt.Sprintf(" | %s (%s): $%.2f", account.Name, ClassifyAccount(account), account.Balance.Current)
+			}
+		}
+	}
+
+	// Add how much history the monthly figures are averaged over
+	if period := request.UserContext.Period; period != nil {
+		context += fmt.Sprintf(", Averaged Over: %.1f months of history (%s confidence)", period.MonthsOfData, period.Confidence)
+	}
+
+	// Add what is owed so card limits aren't mistaken for savings
+	if request.UserContext.TotalLiabilities > 0 || request.UserContext.AvailableCredit > 0 {
+		context += fmt.Sprintf(", Liabilities: $%.2f, Available Credit: $%.2f, Net Worth: $%.2f",
+			request.UserContext.TotalLiabilities,
+			request.UserContext.AvailableCredit,
+			request.UserContext.NetWorth,
+		)
+	}
+
+	// Add debts so advice accounts for what they cost
+	if len(request.UserContext.Debts) > 0 {
+		debt := AnalyzeDebt(request.UserContext)
+		context += fmt.Sprintf(", Debt: $%.2f (payments %.0f%% of income, weighted rate %.1f%%/year)",
+			debt.TotalDebt, debt.DebtToIncomeRatio*100, debt.WeightedInterestRate*100)
+		if debt.PayDownFirst {
+			context += fmt.Sprintf(", High-Interest Debt To Pay Before Investing: $%.2f", debt.HighInterestDebt)
+		}
+	}
+
+	// Add how checking and savings balances actually moved
+	if trend := request.UserContext.EmergencyFundTrend; trend != nil {
+		context += fmt.Sprintf(", Emergency Fund Trend: %s (%+.2f/month since %s)", trend.Direction, trend.MonthlyChange, trend.From.Format("2006-01-02"))
+	}
+
+	// Add existing investments so advice builds on what the user already owns
+	if len(request.UserContext.Holdings) > 0 {
+		context += fmt.Sprintf(", Current Investments: $%.2f", request.UserContext.TotalInvested)
+		for i, holding := range request.UserContext.Holdings {
+			if i < 5 {
+				context += fmt.Sprintf(" | %s (%s): $%.2f", holding.Name, holding.Symbol, holding.MarketValue)
 			}
 		}
 	}
@@ -952,7 +1304,7 @@ func (ai *AIService) buildUserContextMessage(request *models.ChatRequest) string
 			}
 		}
 		if transactionSample != "" {
-			context += fmt.Sprintf(", Sample Transactions: %s", transactionSample)
+			context += fmt.Sprintf(", Sample Transactions: %s (search_transactions finds the rest)", transactionSample)
 		}
 	}
 
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// Balance history granularities
+const (
+	GranularityDaily   = "daily"
+	GranularityWeekly  = "weekly"
+	GranularityMonthly = "monthly"
+)
+
+// maxBalanceHistoryDays bounds a single balance history request
+const maxBalanceHistoryDays = 2 * 366
+
+// GetBalances retrieves daily balance snapshots from Belvo's balances resource. Not every
+// institution supports it, so callers should be ready to fall back to transactions.
+func (bs *BelvoService) GetBalances(ctx context.Context, linkID string, dateFrom, dateTo time.Time) ([]models.BelvoBalanceSnapshot, error) {
+	endpoint := fmt.Sprintf("%s/api/balances/", bs.credentials.BaseURL)
+
+	reqBodyBytes, err := json.Marshal(map[string]interface{}{
+		"link":      linkID,
+		"date_from": dateFrom.Format("2006-01-02"),
+		"date_to":   dateTo.Format("2006-01-02"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(reqBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.SetBasicAuth(bs.credentials.SecretID, bs.credentials.SecretKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("accept", "application/json")
+
+	// POST is how Belvo reads these resources, so it is safe to retry
+	resp, err := bs.httpClient.Do(markIdempotentRead(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to retrieve balances: %w", NewBelvoAPIError(resp.StatusCode, body))
+	}
+
+	var balances []models.BelvoBalanceSnapshot
+	if err := json.Unmarshal(body, &balances); err != nil {
+		return nil, fmt.Errorf("failed to decode balances response: %w", err)
+	}
+
+	return balances, nil
+}
+
+// GetBalanceHistory builds a balance series per account between from and to. Days come
+// from Belvo's balances resource where available; the rest is rebuilt from the running
+// balance on each transaction, and accounts without either stay at their current balance.
+func (bs *BelvoService) GetBalanceHistory(ctx context.Context, linkID string, from, to time.Time, granularity string) (*models.BalanceHistory, error) {
+	from, to = calendarDay(from), calendarDay(to)
+	if to.Before(from) {
+		return nil, fmt.Errorf("from must not be after to")
+	}
+	if to.Sub(from) > maxBalanceHistoryDays*24*time.Hour {
+		return nil, fmt.Errorf("balance history is limited to %d days", maxBalanceHistoryDays)
+	}
+	if !IsValidGranularity(granularity) {
+		return nil, fmt.Errorf("granularity must be %s, %s or %s", GranularityDaily, GranularityWeekly, GranularityMonthly)
+	}
+
+	accounts, err := bs.GetAccounts(ctx, linkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounts: %w", err)
+	}
+
+	transactions, err := bs.GetStoredTransactions(ctx, linkID, &from, &to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	snapshots, err := bs.GetBalances(ctx, linkID, from, to)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		fmt.Printf("⚠️ Balances resource unavailable for link %s, rebuilding from transactions: %v\n", linkID, err)
+		snapshots = nil
+	}
+
+	history := &models.BalanceHistory{
+		LinkID:      linkID,
+		From:        from,
+		To:          to,
+		Granularity: granularity,
+	}
+
+	var netWorth []models.BalancePoint
+	for _, account := range accounts {
+		points, source := dailyAccountBalances(account, transactions, snapshots, from, to)
+
+		liability := IsLiabilityAccount(account)
+		if netWorth == nil {
+			netWorth = make([]models.BalancePoint, len(points))
+			for i, point := range points {
+				netWorth[i].Date = point.Date
+			}
+		}
+		for i, point := range points {
+			balance := point.Balance
+			if liability {
+				balance = -math.Abs(balance) // Amounts owed come with either sign
+			}
+			netWorth[i].Balance = roundCents(netWorth[i].Balance + balance)
+		}
+
+		history.Accounts = append(history.Accounts, models.AccountBalanceSeries{
+			AccountID:   account.ID,
+			AccountName: account.Name,
+			Category:    account.Category,
+			BalanceType: account.BalanceType,
+			Currency:    account.Currency,
+			Source:      source,
+			Points:      resampleBalances(points, granularity),
+		})
+	}
+	history.NetWorth = resampleBalances(netWorth, granularity)
+
+	return history, nil
+}
+
+// IsValidGranularity reports whether granularity is daily, weekly or monthly
+func IsValidGranularity(granularity string) bool {
+	switch granularity {
+	case GranularityDaily, GranularityWeekly, GranularityMonthly:
+		return true
+	}
+	return false
+}
+
+// dailyAccountBalances returns one end-of-day balance per day from from to to and the
+// source it came from. Balance snapshots win over balances derived from transactions.
+func dailyAccountBalances(account models.BelvoAccount, transactions []models.BelvoTransaction, snapshots []models.BelvoBalanceSnapshot, from, to time.Time) ([]models.BalancePoint, string) {
+	var accountTransactions []models.BelvoTransaction
+	for _, transaction := range transactions {
+		if transactionAccountID(transaction) == account.ID {
+			accountTransactions = append(accountTransactions, transaction)
+		}
+	}
+	sort.SliceStable(accountTransactions, func(a, b int) bool {
+		return accountTransactions[a].AccountingDate.Time().Before(accountTransactions[b].AccountingDate.Time())
+	})
+
+	known := make(map[time.Time]float64)
+	initial := account.Balance.Current
+	source := "current_balance"
+
+	if len(accountTransactions) > 0 {
+		source = "transactions"
+
+		// Balance before the first transaction is its running balance with the amount undone
+		first := accountTransactions[0]
+		initial = first.Balance + first.Amount
+		if first.Type == "INFLOW" {
+			initial = first.Balance - first.Amount
+		}
+
+		for _, transaction := range accountTransactions {
+			known[calendarDay(transaction.AccountingDate.Time())] = transaction.Balance // Last one of the day wins
+		}
+	}
+
+	for _, snapshot := range snapshots {
+		id, _ := snapshot.Account["id"].(string)
+		if id != account.ID {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", snapshot.ValueDate)
+		if err != nil {
+			continue
+		}
+		known[calendarDay(date)] = snapshot.CurrentBalance
+		source = "balances"
+	}
+
+	return fillDailyBalances(from, to, known, initial), source
+}
+
+// fillDailyBalances carries the last known balance forward across days without one
+func fillDailyBalances(from, to time.Time, known map[time.Time]float64, initial float64) []models.BalancePoint {
+	var points []models.BalancePoint
+	balance := initial
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if value, ok := known[day]; ok {
+			balance = value
+		}
+		points = append(points, models.BalancePoint{Date: day, Balance: roundCents(balance)})
+	}
+	return points
+}
+
+// resampleBalances keeps the last daily point of each week or month
+func resampleBalances(points []models.BalancePoint, granularity string) []models.BalancePoint {
+	if granularity == GranularityDaily || len(points) == 0 {
+		return points
+	}
+
+	period := func(t time.Time) string {
+		if granularity == GranularityWeekly {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}
+		return t.Format("2006-01")
+	}
+
+	var resampled []models.BalancePoint
+	for i, point := range points {
+		if i == len(points)-1 || period(points[i+1].Date) != period(point.Date) {
+			resampled = append(resampled, point)
+		}
+	}
+	return resampled
+}
+
+// liquidBalanceTrend follows the balance of checking and savings accounts, the money an
+// emergency fund is drawn from, across the transactions in a summary
+func liquidBalanceTrend(accounts []models.BelvoAccount, transactions []models.BelvoTransaction, from, to time.Time) *models.BalanceTrend {
+	from, to = calendarDay(from), calendarDay(to)
+
+	var total []models.BalancePoint
+	for _, account := range accounts {
+		if ClassifyAccount(account) != AccountClassLiquid {
+			continue
+		}
+		points, _ := dailyAccountBalances(account, transactions, nil, from, to)
+		if total == nil {
+			total = points
+			continue
+		}
+		for i := range total {
+			total[i].Balance = roundCents(total[i].Balance + points[i].Balance)
+		}
+	}
+	if len(total) < 2 {
+		return nil
+	}
+
+	start, end := total[0].Balance, total[len(total)-1].Balance
+	months := to.Sub(from).Hours() / 24 / 30.44
+	trend := &models.BalanceTrend{
+		From:          from,
+		To:            to,
+		StartBalance:  start,
+		EndBalance:    end,
+		Change:        roundCents(end - start),
+		MonthlyChange: roundCents((end - start) / math.Max(months, 1)),
+		Points:        resampleBalances(total, GranularityMonthly),
+	}
+
+	// Moves under 2% of the balance a month are noise, not a trend
+	threshold := math.Max(math.Abs(start), math.Abs(end)) * 0.02
+	switch {
+	case trend.MonthlyChange > threshold:
+		trend.Direction = "growing"
+	case trend.MonthlyChange < -threshold:
+		trend.Direction = "shrinking"
+	default:
+		trend.Direction = "stable"
+	}
+	return trend
+}
+
+// calendarDay returns t's calendar date at midnight UTC so days from different
+// time zones can be used as map keys
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
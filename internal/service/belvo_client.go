@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// BelvoClient is the set of Belvo operations used by the API handlers and AIService.
+// Every call that reaches Belvo takes a context so callers can cancel in-flight work.
+// BelvoService is the HTTP implementation; pointing it at the fake server in
+// internal/belvofake lets the whole API run without network access.
+type BelvoClient interface {
+	GetEnvironment() string
+	SetPageOptions(opts PageOptions)
+
+	GenerateAccessToken(ctx context.Context, scopes string) (map[string]interface{}, error)
+	GenerateOFDAWidgetToken(ctx context.Context, widgetRequest map[string]interface{}) (map[string]interface{}, error)
+	CreateOFDAWidgetToken(ctx context.Context, req OFDAWidgetRequest) (map[string]interface{}, error)
+	GetConsents(ctx context.Context, linkID string) ([]models.BelvoConsent, error)
+
+	IterateLinks(ctx context.Context, opts PageOptions) *PageIterator[models.BelvoLink]
+	GetLinks(ctx context.Context) ([]models.BelvoLink, error)
+	CheckLinkHasData(ctx context.Context, linkID string) bool
+	CreateLink(ctx context.Context, institution, username, password string) (*models.CreateLinkResponse, error)
+	CreateLinkWithCustomParams(ctx context.Context, linkRequest map[string]interface{}) (*models.BelvoLink, error)
+	ResumeLink(ctx context.Context, linkID, session, token string) (*models.LinkOperationResult, error)
+	RefreshLink(ctx context.Context, linkID string, refresh models.LinkRefreshRequest) (*models.LinkOperationResult, error)
+	DeleteLink(ctx context.Context, linkID string) (*models.LinkOperationResult, error)
+
+	IterateInstitutions(ctx context.Context, opts PageOptions) *PageIterator[models.BelvoInstitution]
+	GetInstitutions(ctx context.Context) ([]models.BelvoInstitution, error)
+	GetInstitutionCatalog(ctx context.Context, filter InstitutionFilter) (*models.InstitutionCatalog, error)
+	Ping(ctx context.Context) (int, error)
+
+	GetAccounts(ctx context.Context, linkID string) ([]models.BelvoAccount, error)
+	GetTransactions(ctx context.Context, linkID string, dateFrom, dateTo *time.Time) ([]models.BelvoTransaction, error)
+	SyncTransactions(ctx context.Context, linkID string) (*models.TransactionSyncResult, error)
+	StreamTransactions(ctx context.Context, linkID string, filter TransactionFilter, fn func(models.BelvoTransaction) error) (*TransactionStreamResult, error)
+	GetStoredTransactions(ctx context.Context, linkID string, dateFrom, dateTo *time.Time) ([]models.BelvoTransaction, error)
+	GetOwners(ctx context.Context, linkID string) ([]models.BelvoOwner, error)
+	IterateIncomes(ctx context.Context, linkID string, opts PageOptions) *PageIterator[models.BelvoIncome]
+	GetIncomes(ctx context.Context, linkID string) ([]models.BelvoIncome, error)
+	IterateRecurringExpenses(ctx context.Context, linkID string, opts PageOptions) *PageIterator[models.BelvoRecurringExpense]
+	GetRecurringExpenses(ctx context.Context, linkID string) ([]models.BelvoRecurringExpense, error)
+	GetInvestmentPortfolios(ctx context.Context, linkID string) ([]models.BelvoInvestmentPortfolio, error)
+	GetLoans(ctx context.Context, linkID string) ([]models.BelvoLoan, error)
+	GetBalances(ctx context.Context, linkID string, dateFrom, dateTo time.Time) ([]models.BelvoBalanceSnapshot, error)
+	GetBalanceHistory(ctx context.Context, linkID string, from, to time.Time, granularity string) (*models.BalanceHistory, error)
+
+	GetFinancialSummary(ctx context.Context, linkID string, opts SummaryOptions) (*models.FinancialSummary, error)
+	GetConsolidatedFinancialSummary(ctx context.Context, linkIDs []string, opts SummaryOptions) (*models.FinancialSummary, error)
+}
+
+// BelvoClientFactory builds a BelvoClient for a set of user-provided credentials
+type BelvoClientFactory func(secretID, secretKey, environment string) BelvoClient
+
+// NewBelvoClientFactory returns a factory that creates BelvoService clients.
+// When baseURL is non-empty it overrides the environment's default Belvo host.
+func NewBelvoClientFactory(baseURL string) BelvoClientFactory {
+	return func(secretID, secretKey, environment string) BelvoClient {
+		return NewBelvoServiceWithBaseURL(secretID, secretKey, environment, baseURL)
+	}
+}
+
+var _ BelvoClient = (*BelvoService)(nil)
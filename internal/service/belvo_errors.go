@@ -0,0 +1,134 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ai-financial-coach/internal/models"
+)
+
+// BelvoErrorClass groups Belvo failures by how callers should react to them
+type BelvoErrorClass string
+
+// Belvo error classes
+const (
+	BelvoErrorAuthFailure     BelvoErrorClass = "auth_failure"
+	BelvoErrorInvalidLink     BelvoErrorClass = "invalid_link"
+	BelvoErrorTokenRequired   BelvoErrorClass = "token_required"
+	BelvoErrorRateLimited     BelvoErrorClass = "rate_limited"
+	BelvoErrorInstitutionDown BelvoErrorClass = "institution_down"
+	BelvoErrorValidation      BelvoErrorClass = "validation"
+	BelvoErrorNotFound        BelvoErrorClass = "not_found"
+	BelvoErrorUnknown         BelvoErrorClass = "unknown"
+)
+
+// BelvoAPIError is a non-success response from Belvo with its parsed error bodies
+type BelvoAPIError struct {
+	StatusCode int                 `json:"status_code"`
+	Class      BelvoErrorClass     `json:"class"`
+	Errors     []models.BelvoError `json:"errors"`
+	RawBody    string              `json:"-"`
+}
+
+// Error implements the error interface
+func (e *BelvoAPIError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("Belvo API error (status %d, %s): %s", e.StatusCode, e.Class, e.RawBody)
+	}
+
+	first := e.Errors[0]
+	message := fmt.Sprintf("Belvo API error (status %d, %s): %s - %s", e.StatusCode, e.Class, first.Code, first.Message)
+	if first.Field != "" {
+		message += fmt.Sprintf(" (field: %s)", first.Field)
+	}
+	return message
+}
+
+// First returns the first Belvo error, or an empty one when the body could not be parsed
+func (e *BelvoAPIError) First() models.BelvoError {
+	if len(e.Errors) == 0 {
+		return models.BelvoError{}
+	}
+	return e.Errors[0]
+}
+
+// AsBelvoAPIError unwraps err looking for a BelvoAPIError
+func AsBelvoAPIError(err error) (*BelvoAPIError, bool) {
+	var apiErr *BelvoAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
+// NewBelvoAPIError parses a Belvo error body. Belvo usually answers with a list of
+// errors, but some endpoints return a single object or a {"detail": ...} message.
+func NewBelvoAPIError(statusCode int, body []byte) *BelvoAPIError {
+	apiErr := &BelvoAPIError{
+		StatusCode: statusCode,
+		RawBody:    strings.TrimSpace(string(body)),
+	}
+
+	var list []models.BelvoError
+	if err := json.Unmarshal(body, &list); err == nil {
+		apiErr.Errors = list
+	} else {
+		var single models.BelvoError
+		if err := json.Unmarshal(body, &single); err == nil && (single.Code != "" || single.Message != "") {
+			apiErr.Errors = []models.BelvoError{single}
+		} else {
+			var detail struct {
+				Detail string `json:"detail"`
+			}
+			if err := json.Unmarshal(body, &detail); err == nil && detail.Detail != "" {
+				apiErr.Errors = []models.BelvoError{{Message: detail.Detail}}
+			}
+		}
+	}
+
+	apiErr.Class = classifyBelvoError(statusCode, apiErr.First())
+	return apiErr
+}
+
+// classifyBelvoError maps a Belvo error code (falling back to the HTTP status) to a class
+func classifyBelvoError(statusCode int, belvoErr models.BelvoError) BelvoErrorClass {
+	switch belvoErr.Code {
+	case "authentication_failed", "unauthorized", "permission_denied", "not_authenticated":
+		return BelvoErrorAuthFailure
+	case "login_error", "invalid_link", "link_not_found", "session_expired", "invalid_credentials":
+		return BelvoErrorInvalidLink
+	case "token_required":
+		return BelvoErrorTokenRequired
+	case "too_many_requests", "too_many_sessions", "throttled":
+		return BelvoErrorRateLimited
+	case "institution_down", "institution_unavailable", "institution_inactive", "service_unavailable", "request_timeout", "unexpected_error":
+		return BelvoErrorInstitutionDown
+	case "does_not_exist", "not_found":
+		if belvoErr.Field == "link" {
+			return BelvoErrorInvalidLink
+		}
+		return BelvoErrorNotFound
+	case "required", "invalid", "null", "blank", "invalid_choice", "max_length", "min_length", "unsupported_operation":
+		return BelvoErrorValidation
+	}
+
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return BelvoErrorAuthFailure
+	case statusCode == http.StatusPreconditionRequired:
+		return BelvoErrorTokenRequired
+	case statusCode == http.StatusTooManyRequests:
+		return BelvoErrorRateLimited
+	case statusCode == http.StatusNotFound:
+		return BelvoErrorNotFound
+	case statusCode == http.StatusRequestTimeout || statusCode >= 500:
+		return BelvoErrorInstitutionDown
+	case statusCode == http.StatusBadRequest:
+		return BelvoErrorValidation
+	default:
+		return BelvoErrorUnknown
+	}
+}
@@ -2,10 +2,13 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"ai-financial-coach/internal/models"
@@ -15,40 +18,70 @@ import (
 type BelvoService struct {
 	credentials *models.BelvoCredentials
 	httpClient  *http.Client
+	pageOptions PageOptions
 }
 
 // NewBelvoService creates a new instance of BelvoService
 func NewBelvoService(secretID, secretKey, environment string) *BelvoService {
-	baseURL := "https://sandbox.belvo.com" // Default to sandbox
-	if environment == "production" {
-		baseURL = "https://api.belvo.com"
-	}
+	return NewBelvoServiceWithBaseURL(secretID, secretKey, environment, "")
+}
 
-	// Service created successfully
+// NewBelvoServiceWithBaseURL creates a BelvoService that talks to baseURL instead of
+// the environment's default host (e.g. a local fake Belvo server)
+func NewBelvoServiceWithBaseURL(secretID, secretKey, environment, baseURL string) *BelvoService {
+	if baseURL == "" {
+		baseURL = BaseURLForEnvironment(environment)
+	}
 
 	return &BelvoService{
 		credentials: &models.BelvoCredentials{
 			SecretID:    secretID,
 			SecretKey:   secretKey,
 			Environment: environment,
-			BaseURL:     baseURL,
-		},
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			BaseURL:     strings.TrimSuffix(baseURL, "/"),
 		},
+		httpClient:  BelvoHTTPClient(),
+		pageOptions: DefaultPageOptions,
 	}
 }
 
+// Belvo environments
+const (
+	EnvironmentSandbox    = "sandbox"
+	EnvironmentProduction = "production"
+)
+
+// IsKnownEnvironment reports whether environment is a Belvo environment
+func IsKnownEnvironment(environment string) bool {
+	return environment == EnvironmentSandbox || environment == EnvironmentProduction
+}
+
+// BaseURLForEnvironment returns the Belvo API host for an environment
+func BaseURLForEnvironment(environment string) string {
+	if environment == EnvironmentProduction {
+		return "https://api.belvo.com"
+	}
+	return "https://sandbox.belvo.com" // Default to sandbox
+}
+
 // GetEnvironment returns the current environment (sandbox/production)
 func (bs *BelvoService) GetEnvironment() string {
 	return bs.credentials.Environment
 }
 
+// SetPageOptions configures page size and max-pages cap for list endpoints
+func (bs *BelvoService) SetPageOptions(opts PageOptions) {
+	bs.pageOptions = opts.normalize()
+}
+
 // makeRequest performs authenticated HTTP requests to Belvo API
-func (bs *BelvoService) makeRequest(method, endpoint string, body []byte) (*http.Response, error) {
-	url := bs.credentials.BaseURL + endpoint
+func (bs *BelvoService) makeRequest(ctx context.Context, method, endpoint string, body []byte) (*http.Response, error) {
+	return bs.makeRequestURL(ctx, method, bs.credentials.BaseURL+endpoint, body)
+}
 
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+// makeRequestURL performs an authenticated request against an absolute URL (e.g. a pagination "next" link)
+func (bs *BelvoService) makeRequestURL(ctx context.Context, method, requestURL string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -64,7 +97,7 @@ func (bs *BelvoService) makeRequest(method, endpoint string, body []byte) (*http
 }
 
 // GenerateAccessToken creates a short-lived access token for Belvo Connect Widget
-func (bs *BelvoService) GenerateAccessToken(scopes string) (map[string]interface{}, error) {
+func (bs *BelvoService) GenerateAccessToken(ctx context.Context, scopes string) (map[string]interface{}, error) {
 	if scopes == "" {
 		// Minimal valid scopes for launching Connect and creating links
 		scopes = "read_institutions,write_links,read_links"
@@ -82,8 +115,8 @@ func (bs *BelvoService) GenerateAccessToken(scopes string) (map[string]interface
 	}
 
 	// Token endpoint expects id/password in body instead of Basic Auth
-	url := bs.credentials.BaseURL + "/api/token/"
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	tokenURL := bs.credentials.BaseURL + "/api/token/"
+	req, err := http.NewRequestWithContext(withTransportSecret(ctx, bs.credentials.SecretID), "POST", tokenURL, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create token request: %w", err)
 	}
@@ -101,7 +134,7 @@ func (bs *BelvoService) GenerateAccessToken(scopes string) (map[string]interface
 	}
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, string(respBody))
+		return nil, NewBelvoAPIError(resp.StatusCode, respBody)
 	}
 
 	// Response structure can evolve; return as generic map and let caller pick fields
@@ -113,46 +146,26 @@ func (bs *BelvoService) GenerateAccessToken(scopes string) (map[string]interface
 	return tokenResp, nil
 }
 
-// GetLinks returns existing Belvo links
-func (bs *BelvoService) GetLinks() ([]models.BelvoLink, error) {
-	resp, err := bs.makeRequest("GET", "/api/links/", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get links: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
-	}
+// IterateLinks returns an iterator over all Belvo links, one page at a time
+func (bs *BelvoService) IterateLinks(ctx context.Context, opts PageOptions) *PageIterator[models.BelvoLink] {
+	return newPageIterator[models.BelvoLink](ctx, bs, "/api/links/", opts)
+}
 
-	body, err := io.ReadAll(resp.Body)
+// GetLinks returns existing Belvo links across all pages
+func (bs *BelvoService) GetLinks(ctx context.Context) ([]models.BelvoLink, error) {
+	links, err := bs.IterateLinks(ctx, bs.pageOptions).All()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	var apiResponse models.BelvoAPIResponse
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	var links []models.BelvoLink
-	for _, result := range apiResponse.Results {
-		linkBytes, _ := json.Marshal(result)
-		var link models.BelvoLink
-		if err := json.Unmarshal(linkBytes, &link); err == nil {
-			links = append(links, link)
-		}
+		return nil, fmt.Errorf("failed to get links: %w", err)
 	}
 
 	return links, nil
 }
 
 // CheckLinkHasData checks if a link has actual financial data by testing accounts endpoint
-func (bs *BelvoService) CheckLinkHasData(linkID string) bool {
+func (bs *BelvoService) CheckLinkHasData(ctx context.Context, linkID string) bool {
 	endpoint := fmt.Sprintf("%s/api/accounts/?link=%s", bs.credentials.BaseURL, linkID)
 
-	req, err := http.NewRequest("GET", endpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return false
 	}
@@ -160,8 +173,7 @@ func (bs *BelvoService) CheckLinkHasData(linkID string) bool {
 	req.SetBasicAuth(bs.credentials.SecretID, bs.credentials.SecretKey)
 	req.Header.Set("accept", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := bs.httpClient.Do(req)
 	if err != nil {
 		return false
 	}
@@ -183,43 +195,24 @@ func (bs *BelvoService) CheckLinkHasData(linkID string) bool {
 	return len(result.Results) > 0
 }
 
-// GetInstitutions retrieves available financial institutions
-func (bs *BelvoService) GetInstitutions() ([]models.BelvoInstitution, error) {
-	resp, err := bs.makeRequest("GET", "/api/institutions/", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get institutions: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
-	}
+// IterateInstitutions returns an iterator over available financial institutions
+func (bs *BelvoService) IterateInstitutions(ctx context.Context, opts PageOptions) *PageIterator[models.BelvoInstitution] {
+	return newPageIterator[models.BelvoInstitution](ctx, bs, "/api/institutions/", opts)
+}
 
-	body, err := io.ReadAll(resp.Body)
+// GetInstitutions retrieves available financial institutions across all pages, reusing
+// the list cached within the institution cache TTL
+func (bs *BelvoService) GetInstitutions(ctx context.Context) ([]models.BelvoInstitution, error) {
+	institutions, _, _, err := bs.cachedInstitutions(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	var apiResponse models.BelvoAPIResponse
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	// Convert interface{} to BelvoInstitution
-	var institutions []models.BelvoInstitution
-	for _, result := range apiResponse.Results {
-		institutionBytes, _ := json.Marshal(result)
-		var institution models.BelvoInstitution
-		if err := json.Unmarshal(institutionBytes, &institution); err == nil {
-			institutions = append(institutions, institution)
-		}
+		return nil, fmt.Errorf("failed to get institutions: %w", err)
 	}
 
-	return institutions, nil
+	return append([]models.BelvoInstitution(nil), institutions...), nil
 }
 
 // CreateLink creates a new connection to a financial institution
-func (bs *BelvoService) CreateLink(institution, username, password string) (*models.CreateLinkResponse, error) {
+func (bs *BelvoService) CreateLink(ctx context.Context, institution, username, password string) (*models.CreateLinkResponse, error) {
 	linkRequest := models.CreateLinkRequest{
 		Institution:         institution,
 		Username:            username,
@@ -233,7 +226,7 @@ func (bs *BelvoService) CreateLink(institution, username, password string) (*mod
 		return nil, fmt.Errorf("failed to marshal link request: %w", err)
 	}
 
-	resp, err := bs.makeRequest("POST", "/api/links/", bodyBytes)
+	resp, err := bs.makeRequest(ctx, "POST", "/api/links/", bodyBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create link: %w", err)
 	}
@@ -245,11 +238,7 @@ func (bs *BelvoService) CreateLink(institution, username, password string) (*mod
 	}
 
 	if resp.StatusCode != http.StatusCreated {
-		var belvoError models.BelvoError
-		if json.Unmarshal(body, &belvoError) == nil {
-			return nil, fmt.Errorf("Belvo API error: %s - %s", belvoError.Code, belvoError.Message)
-		}
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, NewBelvoAPIError(resp.StatusCode, body)
 	}
 
 	var linkResponse models.CreateLinkResponse
@@ -261,7 +250,7 @@ func (bs *BelvoService) CreateLink(institution, username, password string) (*mod
 }
 
 // CreateLinkWithCustomParams creates a Belvo link with custom parameters (for Open Finance Brazil institutions)
-func (bs *BelvoService) CreateLinkWithCustomParams(linkRequest map[string]interface{}) (*models.BelvoLink, error) {
+func (bs *BelvoService) CreateLinkWithCustomParams(ctx context.Context, linkRequest map[string]interface{}) (*models.BelvoLink, error) {
 	endpoint := fmt.Sprintf("%s/api/links/", bs.credentials.BaseURL)
 
 	// Convert the map to JSON
@@ -270,7 +259,7 @@ func (bs *BelvoService) CreateLinkWithCustomParams(linkRequest map[string]interf
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -290,7 +279,7 @@ func (bs *BelvoService) CreateLinkWithCustomParams(linkRequest map[string]interf
 	}
 
 	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, NewBelvoAPIError(resp.StatusCode, body)
 	}
 
 	var link models.BelvoLink
@@ -301,8 +290,95 @@ func (bs *BelvoService) CreateLinkWithCustomParams(linkRequest map[string]interf
 	return &link, nil
 }
 
+// ResumeLink completes a pending MFA session by sending the user's token (PATCH /api/links/)
+func (bs *BelvoService) ResumeLink(ctx context.Context, linkID, session, token string) (*models.LinkOperationResult, error) {
+	payload := map[string]interface{}{
+		"session": session,
+		"token":   token,
+		"link":    linkID,
+	}
+	return bs.doLinkOperation(ctx, "PATCH", "/api/links/", payload)
+}
+
+// RefreshLink re-authenticates a link (PUT /api/links/{id}/) so Belvo refreshes its resources
+func (bs *BelvoService) RefreshLink(ctx context.Context, linkID string, refresh models.LinkRefreshRequest) (*models.LinkOperationResult, error) {
+	return bs.doLinkOperation(ctx, "PUT", "/api/links/"+url.PathEscape(linkID)+"/", refresh)
+}
+
+// DeleteLink removes a link and all of its data from Belvo (DELETE /api/links/{id}/)
+// along with any locally stored transactions
+func (bs *BelvoService) DeleteLink(ctx context.Context, linkID string) (*models.LinkOperationResult, error) {
+	result, err := bs.doLinkOperation(ctx, "DELETE", "/api/links/"+url.PathEscape(linkID)+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	bs.forgetTransactions(linkID)
+	return result, nil
+}
+
+// doLinkOperation runs a link lifecycle request and maps the response, including
+// the HTTP 428 token_required flow, into a LinkOperationResult
+func (bs *BelvoService) doLinkOperation(ctx context.Context, method, endpoint string, payload interface{}) (*models.LinkOperationResult, error) {
+	var bodyBytes []byte
+	if payload != nil {
+		var err error
+		bodyBytes, err = json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal link request: %w", err)
+		}
+	}
+
+	resp, err := bs.makeRequest(ctx, method, endpoint, bodyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s link: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusPreconditionRequired:
+		session, err := parseTokenSession(body)
+		if err != nil {
+			return nil, err
+		}
+		return &models.LinkOperationResult{Status: models.LinkStatusTokenRequired, TokenRequired: session}, nil
+
+	case http.StatusNoContent:
+		return &models.LinkOperationResult{Status: models.LinkStatusDeleted}, nil
+
+	case http.StatusOK, http.StatusCreated:
+		var link models.BelvoLink
+		if err := json.Unmarshal(body, &link); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal link response: %w", err)
+		}
+		return &models.LinkOperationResult{Status: models.LinkStatusCompleted, Link: &link}, nil
+
+	default:
+		return nil, NewBelvoAPIError(resp.StatusCode, body)
+	}
+}
+
+// parseTokenSession decodes a token_required response, which Belvo sends as a list of errors
+func parseTokenSession(body []byte) (*models.LinkTokenSession, error) {
+	var sessions []models.LinkTokenSession
+	if err := json.Unmarshal(body, &sessions); err == nil && len(sessions) > 0 {
+		return &sessions[0], nil
+	}
+
+	var session models.LinkTokenSession
+	if err := json.Unmarshal(body, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse token_required response: %w", err)
+	}
+	return &session, nil
+}
+
 // GetAccounts retrieves accounts for a specific link using POST request (as required by Belvo)
-func (bs *BelvoService) GetAccounts(linkID string) ([]models.BelvoAccount, error) {
+func (bs *BelvoService) GetAccounts(ctx context.Context, linkID string) ([]models.BelvoAccount, error) {
 	endpoint := fmt.Sprintf("%s/api/accounts/", bs.credentials.BaseURL)
 
 	// Belvo requires POST request with link in body
@@ -315,7 +391,7 @@ func (bs *BelvoService) GetAccounts(linkID string) ([]models.BelvoAccount, error
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(reqBodyBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(reqBodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -324,8 +400,8 @@ func (bs *BelvoService) GetAccounts(linkID string) ([]models.BelvoAccount, error
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("accept", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// POST is how Belvo reads these resources, so it is safe to retry
+	resp, err := bs.httpClient.Do(markIdempotentRead(req))
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -337,7 +413,7 @@ func (bs *BelvoService) GetAccounts(linkID string) ([]models.BelvoAccount, error
 	}
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, NewBelvoAPIError(resp.StatusCode, body)
 	}
 
 	// Debug: Log response details
@@ -360,7 +436,7 @@ func (bs *BelvoService) GetAccounts(linkID string) ([]models.BelvoAccount, error
 }
 
 // GetTransactions retrieves transactions for a specific link using POST request (as required by Belvo)
-func (bs *BelvoService) GetTransactions(linkID string, dateFrom, dateTo *time.Time) ([]models.BelvoTransaction, error) {
+func (bs *BelvoService) GetTransactions(ctx context.Context, linkID string, dateFrom, dateTo *time.Time) ([]models.BelvoTransaction, error) {
 	endpoint := fmt.Sprintf("%s/api/transactions/", bs.credentials.BaseURL)
 
 	// Belvo requires POST request with parameters in body
@@ -381,7 +457,7 @@ func (bs *BelvoService) GetTransactions(linkID string, dateFrom, dateTo *time.Ti
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(reqBodyBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(reqBodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -390,8 +466,8 @@ func (bs *BelvoService) GetTransactions(linkID string, dateFrom, dateTo *time.Ti
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("accept", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// POST is how Belvo reads these resources, so it is safe to retry
+	resp, err := bs.httpClient.Do(markIdempotentRead(req))
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -403,7 +479,7 @@ func (bs *BelvoService) GetTransactions(linkID string, dateFrom, dateTo *time.Ti
 	}
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, NewBelvoAPIError(resp.StatusCode, body)
 	}
 
 	// Belvo returns transactions directly as an array, not wrapped in "results"
@@ -415,196 +491,64 @@ func (bs *BelvoService) GetTransactions(linkID string, dateFrom, dateTo *time.Ti
 	return transactions, nil
 }
 
-// GetIncomes retrieves income information for a specific link
-func (bs *BelvoService) GetIncomes(linkID string) ([]models.BelvoIncome, error) {
-	endpoint := "/api/incomes/"
-	if linkID != "" {
-		endpoint += "?link=" + linkID
-	}
+// IterateIncomes returns an iterator over income information for a specific link
+func (bs *BelvoService) IterateIncomes(ctx context.Context, linkID string, opts PageOptions) *PageIterator[models.BelvoIncome] {
+	return newPageIterator[models.BelvoIncome](ctx, bs, linkFilteredEndpoint("/api/incomes/", linkID), opts)
+}
 
-	resp, err := bs.makeRequest("GET", endpoint, nil)
+// GetIncomes retrieves income information for a specific link across all pages
+func (bs *BelvoService) GetIncomes(ctx context.Context, linkID string) ([]models.BelvoIncome, error) {
+	incomes, err := bs.IterateIncomes(ctx, linkID, bs.pageOptions).All()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get incomes: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	var apiResponse models.BelvoAPIResponse
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	var incomes []models.BelvoIncome
-	for _, result := range apiResponse.Results {
-		incomeBytes, _ := json.Marshal(result)
-		var income models.BelvoIncome
-		if err := json.Unmarshal(incomeBytes, &income); err == nil {
-			incomes = append(incomes, income)
-		}
-	}
 
 	return incomes, nil
 }
 
-// GetRecurringExpenses retrieves recurring expense information for a specific link
-func (bs *BelvoService) GetRecurringExpenses(linkID string) ([]models.BelvoRecurringExpense, error) {
-	endpoint := "/api/recurring-expenses/"
-	if linkID != "" {
-		endpoint += "?link=" + linkID
-	}
+// IterateRecurringExpenses returns an iterator over recurring expenses for a specific link
+func (bs *BelvoService) IterateRecurringExpenses(ctx context.Context, linkID string, opts PageOptions) *PageIterator[models.BelvoRecurringExpense] {
+	return newPageIterator[models.BelvoRecurringExpense](ctx, bs, linkFilteredEndpoint("/api/recurring-expenses/", linkID), opts)
+}
 
-	resp, err := bs.makeRequest("GET", endpoint, nil)
+// GetRecurringExpenses retrieves recurring expense information for a specific link across all pages
+func (bs *BelvoService) GetRecurringExpenses(ctx context.Context, linkID string) ([]models.BelvoRecurringExpense, error) {
+	expenses, err := bs.IterateRecurringExpenses(ctx, linkID, bs.pageOptions).All()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get recurring expenses: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	var apiResponse models.BelvoAPIResponse
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	var expenses []models.BelvoRecurringExpense
-	for _, result := range apiResponse.Results {
-		expenseBytes, _ := json.Marshal(result)
-		var expense models.BelvoRecurringExpense
-		if err := json.Unmarshal(expenseBytes, &expense); err == nil {
-			expenses = append(expenses, expense)
-		}
-	}
 
 	return expenses, nil
 }
 
-// GetFinancialSummary aggregates all financial data into a summary for AI analysis
-func (bs *BelvoService) GetFinancialSummary(linkID string) (*models.FinancialSummary, error) {
-	fmt.Printf("🔍 Starting GetFinancialSummary for link: %s\n", linkID)
-
-	// Get all financial data in parallel
-	accounts, err := bs.GetAccounts(linkID)
-	if err != nil {
-		fmt.Printf("❌ Failed to get accounts: %v\n", err)
-		return nil, fmt.Errorf("failed to get accounts: %w", err)
+// linkFilteredEndpoint appends the link query filter when a link ID is given
+func linkFilteredEndpoint(endpoint, linkID string) string {
+	if linkID == "" {
+		return endpoint
 	}
-	fmt.Printf("✅ Retrieved %d accounts\n", len(accounts))
+	return endpoint + "?link=" + url.QueryEscape(linkID)
+}
 
-	// Get transactions for the last 90 days
-	dateTo := time.Now()
-	dateFrom := dateTo.AddDate(0, -3, 0) // 3 months ago
-	fmt.Printf("📅 Fetching transactions from %s to %s\n", dateFrom.Format("2006-01-02"), dateTo.Format("2006-01-02"))
-	transactions, err := bs.GetTransactions(linkID, &dateFrom, &dateTo)
-	if err != nil {
-		fmt.Printf("❌ Failed to get transactions: %v\n", err)
-		return nil, fmt.Errorf("failed to get transactions: %w", err)
-	}
-	fmt.Printf("✅ Retrieved %d transactions\n", len(transactions))
+// GetFinancialSummary aggregates all financial data over the lookback window into a summary for AI analysis
+func (bs *BelvoService) GetFinancialSummary(ctx context.Context, linkID string, opts SummaryOptions) (*models.FinancialSummary, error) {
+	fmt.Printf("🔍 Starting GetFinancialSummary for link: %s\n", linkID)
 
-	incomes, err := bs.GetIncomes(linkID)
+	opts, err := ValidateSummaryOptions(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get incomes: %w", err)
+		return nil, err
 	}
+	dateFrom, dateTo := summaryWindow(opts, time.Now())
 
-	recurringExpenses, err := bs.GetRecurringExpenses(linkID)
+	data, err := bs.fetchLinkData(ctx, linkID, dateFrom, dateTo)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get recurring expenses: %w", err)
-	}
-
-	// Calculate financial metrics
-	totalBalance := 0.0
-	for _, account := range accounts {
-		totalBalance += account.Balance.Available
-	}
-
-	monthlyIncome := 0.0
-	for _, income := range incomes {
-		monthlyIncome += income.MonthlyAverage
+		return nil, err
 	}
 
-	monthlyFixedExpenses := 0.0
-	monthlyVariableExpenses := 0.0
-	for _, expense := range recurringExpenses {
-		monthlyFixedExpenses += expense.AverageTransactionAmount
-	}
-
-	// Calculate income and expenses from transactions
-	totalInflow := 0.0
-	totalOutflow := 0.0
-
-	fmt.Printf("🔍 Processing %d transactions for link %s\n", len(transactions), linkID)
-
-	for _, transaction := range transactions {
-		if transaction.Type == "INFLOW" {
-			totalInflow += transaction.Amount
-		} else if transaction.Type == "OUTFLOW" {
-			totalOutflow += transaction.Amount
-		}
-	}
-
-	fmt.Printf("💰 Total inflow: %.2f, Total outflow: %.2f\n", totalInflow, totalOutflow)
-
-	// Convert to monthly averages (transactions are from last 3 months)
-	monthsOfData := 3.0
-	if len(transactions) > 0 {
-		// Calculate estimated monthly averages
-		monthlyIncomeFromTransactions := totalInflow / monthsOfData
-		monthlyExpensesFromTransactions := totalOutflow / monthsOfData
-
-		fmt.Printf("📊 Monthly income from transactions: %.2f\n", monthlyIncomeFromTransactions)
-		fmt.Printf("📊 Monthly expenses from transactions: %.2f\n", monthlyExpensesFromTransactions)
-
-		// Use transaction-based income if no formal income streams found
-		if monthlyIncome == 0 {
-			monthlyIncome = monthlyIncomeFromTransactions
-			fmt.Printf("✅ Using transaction-based income: %.2f\n", monthlyIncome)
-		}
-
-		// Add transaction-based expenses to variable expenses
-		monthlyVariableExpenses += monthlyExpensesFromTransactions
-		fmt.Printf("✅ Variable expenses set to: %.2f\n", monthlyVariableExpenses)
-	}
-
-	monthlySurplus := monthlyIncome - monthlyFixedExpenses - monthlyVariableExpenses
-
-	currency := "BRL" // Default for Brazil
-	if len(accounts) > 0 {
-		currency = accounts[0].Currency
-	}
-
-	return &models.FinancialSummary{
-		UserID:                  linkID, // Using linkID as user identifier for now
-		GeneratedAt:             time.Now(),
-		MonthlyIncome:           monthlyIncome,
-		MonthlyFixedExpenses:    monthlyFixedExpenses,
-		MonthlyVariableExpenses: monthlyVariableExpenses,
-		MonthlySurplus:          monthlySurplus,
-		TotalBalance:            totalBalance,
-		Accounts:                accounts,
-		RecentTransactions:      transactions,
-		IncomeStreams:           incomes,
-		RecurringExpenses:       recurringExpenses,
-		Currency:                currency,
-	}, nil
+	return buildFinancialSummary(ctx, linkID, []*linkFinancialData{data}, opts, dateFrom, dateTo) // Using linkID as user identifier for now
 }
 
 // GenerateOFDAWidgetToken generates a widget token for Open Finance Data Aggregation in Brazil
-func (bs *BelvoService) GenerateOFDAWidgetToken(widgetRequest map[string]interface{}) (map[string]interface{}, error) {
+func (bs *BelvoService) GenerateOFDAWidgetToken(ctx context.Context, widgetRequest map[string]interface{}) (map[string]interface{}, error) {
 	// Prepare the request body
 	reqBody, err := json.Marshal(widgetRequest)
 	if err != nil {
@@ -612,7 +556,7 @@ func (bs *BelvoService) GenerateOFDAWidgetToken(widgetRequest map[string]interfa
 	}
 
 	// Make request to Belvo's widget token endpoint
-	resp, err := bs.makeRequest("POST", "/api/token/", reqBody)
+	resp, err := bs.makeRequest(ctx, "POST", "/api/token/", reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create widget token request: %w", err)
 	}
@@ -624,7 +568,7 @@ func (bs *BelvoService) GenerateOFDAWidgetToken(widgetRequest map[string]interfa
 	}
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("widget token request failed with status %d: %s", resp.StatusCode, string(respBody))
+		return nil, NewBelvoAPIError(resp.StatusCode, respBody)
 	}
 
 	// Parse the response
@@ -637,7 +581,7 @@ func (bs *BelvoService) GenerateOFDAWidgetToken(widgetRequest map[string]interfa
 }
 
 // GetOwners retrieves owner information for a specific link
-func (bs *BelvoService) GetOwners(linkID string) ([]models.BelvoOwner, error) {
+func (bs *BelvoService) GetOwners(ctx context.Context, linkID string) ([]models.BelvoOwner, error) {
 	endpoint := fmt.Sprintf("%s/api/owners/", bs.credentials.BaseURL)
 
 	requestBody := map[string]interface{}{
@@ -649,7 +593,7 @@ func (bs *BelvoService) GetOwners(linkID string) ([]models.BelvoOwner, error) {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(reqBodyBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(reqBodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -658,19 +602,24 @@ func (bs *BelvoService) GetOwners(linkID string) ([]models.BelvoOwner, error) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("accept", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// POST is how Belvo reads these resources, so it is safe to retry
+	resp, err := bs.httpClient.Do(markIdempotentRead(req))
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("failed to retrieve owners: status %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to retrieve owners: %w", NewBelvoAPIError(resp.StatusCode, body))
 	}
 
 	var owners []models.BelvoOwner
-	if err := json.NewDecoder(resp.Body).Decode(&owners); err != nil {
+	if err := json.Unmarshal(body, &owners); err != nil {
 		return nil, fmt.Errorf("failed to decode owners response: %w", err)
 	}
 
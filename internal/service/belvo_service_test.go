@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ai-financial-coach/internal/belvofake"
+	"ai-financial-coach/internal/models"
+)
+
+// testTransportOptions retry quickly so failure cases don't slow the suite down
+var testTransportOptions = TransportOptions{
+	MaxAttempts:            3,
+	BaseDelay:              time.Millisecond,
+	MaxDelay:               5 * time.Millisecond,
+	MaxRetryAfter:          time.Second,
+	AttemptTimeout:         5 * time.Second,
+	MaxConcurrentPerSecret: 2,
+}
+
+// fakeBelvo starts a belvofake server and returns a BelvoService pointed at it with its
+// own retrying transport, and a counter of the requests that reached the fake
+func fakeBelvo(t *testing.T, fake *belvofake.Server, secretID, secretKey string) (*BelvoService, *int32) {
+	t.Helper()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fake.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	bs := NewBelvoServiceWithBaseURL(secretID, secretKey, EnvironmentSandbox, server.URL)
+	bs.httpClient = &http.Client{Transport: newBelvoTransport(http.DefaultTransport, testTransportOptions)}
+	return bs, &requests
+}
+
+func TestPageIteratorFollowsNextLinks(t *testing.T) {
+	fake := belvofake.NewServer("", "")
+	bs, requests := fakeBelvo(t, fake, "id", "key")
+
+	tests := []struct {
+		name          string
+		opts          PageOptions
+		wantLinks     int
+		wantPages     int
+		wantTruncated bool
+	}{
+		{name: "single page", opts: PageOptions{PageSize: 100}, wantLinks: 3, wantPages: 1},
+		{name: "one link per page", opts: PageOptions{PageSize: 1}, wantLinks: 3, wantPages: 3},
+		{name: "page cap", opts: PageOptions{PageSize: 1, MaxPages: 2}, wantLinks: 2, wantPages: 2, wantTruncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(requests, 0)
+			it := bs.IterateLinks(context.Background(), tt.opts)
+			links, err := it.All()
+			if tt.wantTruncated != errors.Is(err, ErrPageLimitReached) || (err != nil && !tt.wantTruncated) {
+				t.Fatalf("All() error = %v, want truncated %v", err, tt.wantTruncated)
+			}
+			if len(links) != tt.wantLinks {
+				t.Errorf("got %d links, want %d", len(links), tt.wantLinks)
+			}
+			if it.PagesRead() != tt.wantPages || int(atomic.LoadInt32(requests)) != tt.wantPages {
+				t.Errorf("read %d pages in %d requests, want %d", it.PagesRead(), atomic.LoadInt32(requests), tt.wantPages)
+			}
+			if it.Truncated() != tt.wantTruncated {
+				t.Errorf("Truncated() = %v, want %v", it.Truncated(), tt.wantTruncated)
+			}
+			if it.TotalCount() != 3 {
+				t.Errorf("TotalCount() = %d, want 3", it.TotalCount())
+			}
+		})
+	}
+}
+
+func TestPageIteratorStreamsEveryPage(t *testing.T) {
+	fake := belvofake.NewServer("", "")
+	bs, _ := fakeBelvo(t, fake, "id", "key")
+	persona := fake.Personas()[belvofake.SalariedLinkID]
+
+	it := newPageIterator[models.BelvoTransaction](context.Background(), bs, "/api/transactions/?link="+belvofake.SalariedLinkID, PageOptions{PageSize: 7})
+	seen := map[string]bool{}
+	err := it.Stream(func(transaction models.BelvoTransaction) error {
+		if seen[transaction.ID] {
+			t.Errorf("transaction %s streamed twice", transaction.ID)
+		}
+		seen[transaction.ID] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	if len(seen) != len(persona.Transactions) {
+		t.Errorf("streamed %d transactions, want %d", len(seen), len(persona.Transactions))
+	}
+	wantPages := (len(persona.Transactions) + 6) / 7
+	if it.PagesRead() != wantPages {
+		t.Errorf("PagesRead() = %d, want %d", it.PagesRead(), wantPages)
+	}
+}
+
+func TestPageIteratorStreamReleasesTransportSlot(t *testing.T) {
+	fake := belvofake.NewServer("", "")
+	bs, _ := fakeBelvo(t, fake, "id", "key")
+	opts := testTransportOptions
+	opts.MaxConcurrentPerSecret = 1
+	bs.httpClient = &http.Client{Transport: newBelvoTransport(http.DefaultTransport, opts)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// fn makes a request of its own, which can only get the one slot if the page's
+	// response was closed before fn was called
+	it := bs.IterateLinks(ctx, PageOptions{PageSize: 2})
+	err := it.Stream(func(models.BelvoLink) error {
+		_, err := bs.GetInstitutions(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+}
+
+func TestPageIteratorCountsSkippedRecords(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"count": 3, "next": null, "results": [{"id": "a"}, {"id": 42}, {"id": "c"}]}`))
+	}))
+	defer server.Close()
+	bs := NewBelvoServiceWithBaseURL("id", "key", EnvironmentSandbox, server.URL)
+	bs.httpClient = server.Client()
+
+	it := bs.IterateLinks(context.Background(), PageOptions{})
+	links, err := it.All()
+	if err != nil {
+		t.Fatalf("All() error = %v", err)
+	}
+	if len(links) != 2 || it.Skipped() != 1 {
+		t.Errorf("Next: got %d links and %d skipped, want 2 and 1", len(links), it.Skipped())
+	}
+
+	streamed := 0
+	it = bs.IterateLinks(context.Background(), PageOptions{})
+	if err := it.Stream(func(models.BelvoLink) error { streamed++; return nil }); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if streamed != 2 || it.Skipped() != 1 {
+		t.Errorf("Stream: got %d links and %d skipped, want 2 and 1", streamed, it.Skipped())
+	}
+}
+
+func TestPageIteratorRefusesForeignNextLink(t *testing.T) {
+	var foreignRequests int32
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&foreignRequests, 1)
+	}))
+	defer foreign.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"count": 2, "next": "` + foreign.URL + `/api/links/?page=2", "results": [{"id": "a"}]}`))
+	}))
+	defer server.Close()
+	bs := NewBelvoServiceWithBaseURL("id", "key", EnvironmentSandbox, server.URL)
+	bs.httpClient = server.Client()
+
+	if _, err := bs.IterateLinks(context.Background(), PageOptions{}).All(); err == nil {
+		t.Fatal("All() error = nil, want the foreign next link refused")
+	}
+	if got := atomic.LoadInt32(&foreignRequests); got != 0 {
+		t.Errorf("made %d requests to the foreign host, want 0", got)
+	}
+}
+
+func TestBelvoTransportRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		status       int
+		failures     int
+		call         func(ctx context.Context, bs *BelvoService) error
+		wantRequests int32
+		wantClass    BelvoErrorClass // Empty when the call should succeed
+	}{
+		{
+			name: "throttled list recovers", path: "/api/links/", status: http.StatusTooManyRequests, failures: 2,
+			call:         func(ctx context.Context, bs *BelvoService) error { _, err := bs.GetLinks(ctx); return err },
+			wantRequests: 3,
+		},
+		{
+			name: "throttled list gives up", path: "/api/links/", status: http.StatusTooManyRequests, failures: 5,
+			call:         func(ctx context.Context, bs *BelvoService) error { _, err := bs.GetLinks(ctx); return err },
+			wantRequests: 3, wantClass: BelvoErrorRateLimited,
+		},
+		{
+			name: "POST read is retried", path: "/api/accounts/", status: http.StatusServiceUnavailable, failures: 1,
+			call: func(ctx context.Context, bs *BelvoService) error {
+				_, err := bs.GetAccounts(ctx, belvofake.SalariedLinkID)
+				return err
+			},
+			wantRequests: 2,
+		},
+		{
+			name: "link creation is not retried", path: "/api/links/", status: http.StatusServiceUnavailable, failures: 1,
+			call: func(ctx context.Context, bs *BelvoService) error {
+				_, err := bs.CreateLinkWithCustomParams(ctx, map[string]interface{}{"institution": "erebor_br_retail", "username": "bnk100"})
+				return err
+			},
+			wantRequests: 1, wantClass: BelvoErrorInstitutionDown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := belvofake.NewServer("", "")
+			bs, requests := fakeBelvo(t, fake, "id", "key")
+			fake.FailNext(tt.path, tt.status, tt.failures)
+
+			err := tt.call(context.Background(), bs)
+			if got := atomic.LoadInt32(requests); got != tt.wantRequests {
+				t.Errorf("made %d requests, want %d", got, tt.wantRequests)
+			}
+			if tt.wantClass == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			apiErr, ok := AsBelvoAPIError(err)
+			if !ok {
+				t.Fatalf("error = %v, want a BelvoAPIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Class != tt.wantClass {
+				t.Errorf("got status %d class %s, want %d %s", apiErr.StatusCode, apiErr.Class, tt.status, tt.wantClass)
+			}
+		})
+	}
+}
+
+func TestBelvoTransportLimitsTokenRequestsPerSecret(t *testing.T) {
+	fake := belvofake.NewServer("", "")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	opts := testTransportOptions
+	opts.MaxConcurrentPerSecret = 1
+	client := &http.Client{Transport: newBelvoTransport(http.DefaultTransport, opts)}
+	newService := func(secretID string) *BelvoService {
+		bs := NewBelvoServiceWithBaseURL(secretID, "key", EnvironmentSandbox, server.URL)
+		bs.httpClient = client
+		return bs
+	}
+
+	// Hold secret a's only slot with a response that stays open
+	held, err := newService("a").makeRequest(context.Background(), "GET", "/api/institutions/", nil)
+	if err != nil {
+		t.Fatalf("makeRequest() error = %v", err)
+	}
+	defer held.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := newService("a").GenerateAccessToken(ctx, ""); err == nil {
+		t.Error("token request for secret a got a slot while a's only slot was held")
+	}
+	if _, err := newService("b").GenerateAccessToken(context.Background(), ""); err != nil {
+		t.Errorf("token request for secret b error = %v, want it to get b's own slot", err)
+	}
+}
+
+func TestBelvoRejectsWrongSecret(t *testing.T) {
+	fake := belvofake.NewServer("id", "key")
+	bs, requests := fakeBelvo(t, fake, "id", "wrong")
+
+	_, err := bs.GetLinks(context.Background())
+	apiErr, ok := AsBelvoAPIError(err)
+	if !ok {
+		t.Fatalf("error = %v, want a BelvoAPIError", err)
+	}
+	if apiErr.StatusCode != http.StatusUnauthorized || apiErr.Class != BelvoErrorAuthFailure {
+		t.Errorf("got status %d class %s, want 401 %s", apiErr.StatusCode, apiErr.Class, BelvoErrorAuthFailure)
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("made %d requests, want 1: auth failures must not be retried", got)
+	}
+}
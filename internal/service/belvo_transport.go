@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TransportOptions controls retries, backoff and concurrency for outbound Belvo calls
+type TransportOptions struct {
+	MaxAttempts            int           // Total attempts per request, including the first
+	BaseDelay              time.Duration // Backoff before the first retry; doubles on each attempt
+	MaxDelay               time.Duration // Upper bound for a single backoff
+	MaxRetryAfter          time.Duration // Upper bound honored for a Retry-After header
+	AttemptTimeout         time.Duration // Timeout for one attempt, including reading the body
+	MaxConcurrentPerSecret int           // Concurrent in-flight requests per Belvo secret ID
+}
+
+// DefaultTransportOptions keep sandbox throttling in check without slowing normal use
+var DefaultTransportOptions = TransportOptions{
+	MaxAttempts:            4,
+	BaseDelay:              500 * time.Millisecond,
+	MaxDelay:               8 * time.Second,
+	MaxRetryAfter:          30 * time.Second,
+	AttemptTimeout:         30 * time.Second,
+	MaxConcurrentPerSecret: 2,
+}
+
+// belvoClientTimeout bounds a whole call, including retries and backoff
+const belvoClientTimeout = 2 * time.Minute
+
+// sharedBelvoTransport carries all Belvo traffic so limits apply across services and handlers
+var sharedBelvoTransport = newBelvoTransport(http.DefaultTransport, DefaultTransportOptions)
+
+var sharedBelvoHTTPClient = &http.Client{
+	Transport: sharedBelvoTransport,
+	Timeout:   belvoClientTimeout,
+}
+
+// BelvoHTTPClient returns the shared HTTP client used for every Belvo request
+func BelvoHTTPClient() *http.Client {
+	return sharedBelvoHTTPClient
+}
+
+// ConfigureBelvoTransport replaces the retry and concurrency settings of the shared transport
+func ConfigureBelvoTransport(opts TransportOptions) {
+	sharedBelvoTransport.configure(opts)
+}
+
+type idempotentReadKey struct{}
+
+// markIdempotentRead flags a request as a safe-to-retry read. GET and HEAD are always
+// retried; Belvo's POST reads (accounts, transactions, owners) need this marker.
+func markIdempotentRead(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), idempotentReadKey{}, true))
+}
+
+// belvoTransport retries idempotent reads with exponential backoff and jitter, honors
+// Retry-After on 429/503, and limits concurrent requests per secret ID
+type belvoTransport struct {
+	base http.RoundTripper
+
+	mu         sync.Mutex
+	opts       TransportOptions
+	semaphores map[string]chan struct{}
+}
+
+func newBelvoTransport(base http.RoundTripper, opts TransportOptions) *belvoTransport {
+	t := &belvoTransport{base: base}
+	t.configure(opts)
+	return t
+}
+
+func (t *belvoTransport) configure(opts TransportOptions) {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = DefaultTransportOptions.BaseDelay
+	}
+	if opts.MaxDelay < opts.BaseDelay {
+		opts.MaxDelay = opts.BaseDelay
+	}
+	if opts.MaxRetryAfter <= 0 {
+		opts.MaxRetryAfter = DefaultTransportOptions.MaxRetryAfter
+	}
+	if opts.MaxConcurrentPerSecret <= 0 {
+		opts.MaxConcurrentPerSecret = 1
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.opts = opts
+	t.semaphores = make(map[string]chan struct{}) // In-flight requests keep releasing into their old slots
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *belvoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	opts := t.opts
+	t.mu.Unlock()
+
+	retryable := isIdempotentRead(req)
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		resp, err := t.attempt(req, opts)
+
+		if !retryable || attempt >= opts.MaxAttempts || ctx.Err() != nil {
+			return resp, err
+		}
+
+		var delay time.Duration
+		switch {
+		case err != nil:
+			delay = backoff(opts, attempt)
+			fmt.Printf("⏳ Belvo %s %s failed (%v), retrying in %s (attempt %d/%d)\n", req.Method, req.URL.Path, err, delay, attempt+1, opts.MaxAttempts)
+
+		case isRetryableStatus(resp.StatusCode):
+			delay = backoff(opts, attempt)
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok &&
+				(resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+				delay = min(retryAfter, opts.MaxRetryAfter)
+			}
+			fmt.Printf("⏳ Belvo %s %s returned %d, retrying in %s (attempt %d/%d)\n", req.Method, req.URL.Path, resp.StatusCode, delay, attempt+1, opts.MaxAttempts)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+
+		default:
+			return resp, nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// transportSecretKey tags the context of a request that sends its secret ID in the
+// body, such as a token request, with that ID
+type transportSecretKey struct{}
+
+// withTransportSecret marks requests made with ctx as made with secretID, for requests
+// whose credentials aren't in Basic auth
+func withTransportSecret(ctx context.Context, secretID string) context.Context {
+	return context.WithValue(ctx, transportSecretKey{}, secretID)
+}
+
+// requestSecretID returns the secret ID a request is made with: its Basic auth user,
+// or the one its context was tagged with
+func requestSecretID(req *http.Request) string {
+	if secretID, _, ok := req.BasicAuth(); ok && secretID != "" {
+		return secretID
+	}
+	secretID, _ := req.Context().Value(transportSecretKey{}).(string)
+	return secretID
+}
+
+// attempt sends one copy of req while holding a concurrency slot for its secret ID.
+// The slot and the attempt timeout are released when the response body is closed.
+// Requests made with no secret aren't limited, so they don't share one pool of slots.
+func (t *belvoTransport) attempt(req *http.Request, opts TransportOptions) (*http.Response, error) {
+	release := func() {}
+	if secretID := requestSecretID(req); secretID != "" {
+		slot := t.semaphore(secretID)
+		select {
+		case slot <- struct{}{}:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		release = func() { <-slot }
+	}
+
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if opts.AttemptTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.AttemptTimeout)
+	}
+
+	attemptReq := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			release()
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		attemptReq.Body = body
+	}
+
+	resp, err := t.base.RoundTrip(attemptReq)
+	if err != nil {
+		cancel()
+		release()
+		return nil, err
+	}
+
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: func() {
+		cancel()
+		release()
+	}}
+	return resp, nil
+}
+
+// semaphore returns the concurrency slots for a secret ID
+func (t *belvoTransport) semaphore(secretID string) chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	slot, ok := t.semaphores[secretID]
+	if !ok {
+		slot = make(chan struct{}, t.opts.MaxConcurrentPerSecret)
+		t.semaphores[secretID] = slot
+	}
+	return slot
+}
+
+// releasingBody runs release exactly once when the body is closed
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+func isIdempotentRead(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	marked, _ := req.Context().Value(idempotentReadKey{}).(bool)
+	return marked
+}
+
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns an exponential delay with jitter in [d/2, d]
+func backoff(opts TransportOptions, attempt int) time.Duration {
+	delay := opts.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > opts.MaxDelay {
+		delay = opts.MaxDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter accepts both delay-seconds and HTTP-date forms
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
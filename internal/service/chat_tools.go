@@ -0,0 +1,529 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// maxToolRounds bounds how many times the model may call tools for one answer; the
+// round after that has to answer with what it has
+const maxToolRounds = 4
+
+// Limits of search_transactions
+const (
+	defaultToolTransactions = 20
+	maxToolTransactions     = 100
+)
+
+// chatTool is a function the chat model can call to look up data it wasn't given in
+// the context message. Tools read the request's financial summary and market data, so
+// they see every transaction of the summary's window, not just the sample; transaction
+// tools asking about other days read them from Belvo when the request can.
+type chatTool struct {
+	name        string
+	description string
+	parameters  map[string]interface{}
+	run         func(ai *AIService, ctx context.Context, request *models.ChatRequest, args json.RawMessage) (interface{}, error)
+}
+
+// chatTools are offered to the model on every chat
+var chatTools = []chatTool{
+	{
+		name:        "search_transactions",
+		description: "Search the user's transactions by merchant or description, date range, amount, type and category. Returns the newest matches first.",
+		parameters: toolSchema(map[string]interface{}{
+			"merchant":   toolParam("string", "Text to find in the merchant name or description, ignoring case and accents"),
+			"from":       toolParam("string", "First day, YYYY-MM-DD"),
+			"to":         toolParam("string", "Last day, YYYY-MM-DD"),
+			"min_amount": toolParam("number", "Smallest amount, positive"),
+			"max_amount": toolParam("number", "Largest amount, positive"),
+			"type":       toolEnum("INFLOW or OUTFLOW", "INFLOW", "OUTFLOW"),
+			"category":   toolParam("string", "Belvo category, e.g. Food & Groceries"),
+			"limit":      toolParam("integer", fmt.Sprintf("How many transactions to return, default %d, at most %d", defaultToolTransactions, maxToolTransactions)),
+		}),
+		run: (*AIService).searchTransactionsTool,
+	},
+	{
+		name:        "get_spending_by_category",
+		description: "Total the user's spending (outflows) per category over a date range, largest first.",
+		parameters: toolSchema(map[string]interface{}{
+			"from": toolParam("string", "First day, YYYY-MM-DD"),
+			"to":   toolParam("string", "Last day, YYYY-MM-DD"),
+		}),
+		run: (*AIService).spendingByCategoryTool,
+	},
+	{
+		name:        "run_what_if",
+		description: "Project a portfolio for a monthly contribution, horizon and risk level, and compare it with investing the current surplus in a balanced portfolio for 10 years.",
+		parameters: toolSchema(map[string]interface{}{
+			"monthly_contribution": toolParam("number", "Amount invested every month"),
+			"investment_horizon":   toolParam("integer", "Years"),
+			"risk_level":           toolEnum("Portfolio risk level", "conservative", "balanced", "aggressive"),
+		}, "monthly_contribution", "investment_horizon", "risk_level"),
+		run: (*AIService).whatIfTool,
+	},
+	{
+		name:        "get_asset_performance",
+		description: "Current price, returns and volatility of an asset, e.g. BOVA11.SA, IVVB11.SA, BTC-USD, SELIC or CDI.",
+		parameters: toolSchema(map[string]interface{}{
+			"symbol": toolParam("string", "Asset symbol"),
+		}, "symbol"),
+		run: (*AIService).assetPerformanceTool,
+	},
+	{
+		name:        "get_portfolio_template",
+		description: "The model portfolio for a risk level: allocations, expected annual return and maximum drawdown.",
+		parameters: toolSchema(map[string]interface{}{
+			"risk_level": toolEnum("Portfolio risk level", "conservative", "balanced", "aggressive"),
+		}, "risk_level"),
+		run: (*AIService).portfolioTemplateTool,
+	},
+}
+
+// chatToolDefinitions describes chatTools to the model
+func chatToolDefinitions() []models.LLMTool {
+	tools := make([]models.LLMTool, 0, len(chatTools))
+	for _, tool := range chatTools {
+		tools = append(tools, models.LLMTool{
+			Type: "function",
+			Function: models.LLMFunction{
+				Name:        tool.name,
+				Description: tool.description,
+				Parameters:  tool.parameters,
+			},
+		})
+	}
+	return tools
+}
+
+// runChatTool runs a tool call and returns the "tool" message answering it. Failures are
+// reported to the model as the result, so it can correct its arguments or say it can't tell.
+func (ai *AIService) runChatTool(ctx context.Context, request *models.ChatRequest, call models.LLMToolCall) models.LLMMessage {
+	message := models.LLMMessage{Role: "tool", ToolCallID: call.ID}
+
+	result, err := ai.callChatTool(ctx, request, call)
+	if err != nil {
+		fmt.Printf("⚠️ Chat tool %s failed: %v\n", call.Function.Name, err)
+		result = map[string]interface{}{"error": err.Error()}
+	}
+
+	content, err := json.Marshal(result)
+	if err != nil {
+		content = []byte(fmt.Sprintf(`{"error": %q}`, err.Error()))
+	}
+	message.Content = string(content)
+	return message
+}
+
+func (ai *AIService) callChatTool(ctx context.Context, request *models.ChatRequest, call models.LLMToolCall) (interface{}, error) {
+	for _, tool := range chatTools {
+		if tool.name != call.Function.Name {
+			continue
+		}
+		args := json.RawMessage(call.Function.Arguments)
+		if strings.TrimSpace(call.Function.Arguments) == "" {
+			args = json.RawMessage("{}")
+		}
+		fmt.Printf("🛠️ Chat tool %s %s\n", tool.name, args)
+		return tool.run(ai, ctx, request, args)
+	}
+	return nil, fmt.Errorf("unknown tool %q", call.Function.Name)
+}
+
+// searchTransactionsArgs are the arguments of search_transactions
+type searchTransactionsArgs struct {
+	Merchant  string   `json:"merchant"`
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	MinAmount *float64 `json:"min_amount"`
+	MaxAmount *float64 `json:"max_amount"`
+	Type      string   `json:"type"`
+	Category  string   `json:"category"`
+	Limit     int      `json:"limit"`
+}
+
+// toolTransaction is the part of a transaction the model needs
+type toolTransaction struct {
+	Date        string  `json:"date"`
+	Description string  `json:"description"`
+	Merchant    string  `json:"merchant,omitempty"`
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+	Type        string  `json:"type"`
+	Category    string  `json:"category,omitempty"`
+	Account     string  `json:"account,omitempty"`
+}
+
+func (ai *AIService) searchTransactionsTool(ctx context.Context, request *models.ChatRequest, raw json.RawMessage) (interface{}, error) {
+	var args searchTransactionsArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	transactions, window, err := toolTransactions(ctx, request, args.From, args.To)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultToolTransactions
+	}
+	if limit > maxToolTransactions {
+		limit = maxToolTransactions
+	}
+	merchant := foldSearch(args.Merchant)
+
+	var matches []models.BelvoTransaction
+	totals := map[string]float64{}
+	for _, transaction := range transactions {
+		amount := math.Abs(transaction.Amount)
+		switch {
+		case merchant != "" && !strings.Contains(foldSearch(transactionMerchant(transaction)+" "+transaction.Description), merchant),
+			args.MinAmount != nil && amount < *args.MinAmount,
+			args.MaxAmount != nil && amount > *args.MaxAmount,
+			args.Type != "" && !strings.EqualFold(transaction.Type, args.Type),
+			args.Category != "" && !strings.EqualFold(transaction.Category, args.Category):
+			continue
+		}
+		matches = append(matches, transaction)
+		totals[transaction.Currency] += amount
+	}
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		return matches[a].AccountingDate.Time().After(matches[b].AccountingDate.Time())
+	})
+
+	listed := make([]toolTransaction, 0, limit)
+	for i, transaction := range matches {
+		if i == limit {
+			break
+		}
+		listed = append(listed, newToolTransaction(transaction))
+	}
+
+	return map[string]interface{}{
+		"window":       window,
+		"matches":      len(matches),
+		"total_amount": roundTotals(totals),
+		"transactions": listed,
+	}, nil
+}
+
+// toolCategorySpending is one row of get_spending_by_category
+type toolCategorySpending struct {
+	Category     string  `json:"category"`
+	Amount       float64 `json:"amount"`
+	Currency     string  `json:"currency"`
+	Percentage   float64 `json:"percentage"` // Of the spending in the same currency
+	Transactions int     `json:"transactions"`
+}
+
+func (ai *AIService) spendingByCategoryTool(ctx context.Context, request *models.ChatRequest, raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	transactions, window, err := toolTransactions(ctx, request, args.From, args.To)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := map[string]*toolCategorySpending{}
+	totals := map[string]float64{}
+	for _, transaction := range transactions {
+		if transaction.Type != "OUTFLOW" {
+			continue
+		}
+		category := transaction.Category
+		if category == "" {
+			category = "Uncategorized"
+		}
+		key := category + "|" + transaction.Currency
+		row, ok := byKey[key]
+		if !ok {
+			row = &toolCategorySpending{Category: category, Currency: transaction.Currency}
+			byKey[key] = row
+		}
+		row.Amount += math.Abs(transaction.Amount)
+		row.Transactions++
+		totals[transaction.Currency] += math.Abs(transaction.Amount)
+	}
+
+	categories := make([]toolCategorySpending, 0, len(byKey))
+	for _, row := range byKey {
+		if total := totals[row.Currency]; total > 0 {
+			row.Percentage = math.Round(row.Amount/total*1000) / 10
+		}
+		row.Amount = math.Round(row.Amount*100) / 100
+		categories = append(categories, *row)
+	}
+	sort.Slice(categories, func(a, b int) bool {
+		if categories[a].Amount != categories[b].Amount {
+			return categories[a].Amount > categories[b].Amount
+		}
+		return categories[a].Category < categories[b].Category
+	})
+
+	return map[string]interface{}{
+		"window":         window,
+		"categories":     categories,
+		"total_spending": roundTotals(totals),
+	}, nil
+}
+
+func (ai *AIService) whatIfTool(ctx context.Context, request *models.ChatRequest, raw json.RawMessage) (interface{}, error) {
+	var params models.ScenarioParameters
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if !isRiskLevel(params.RiskLevel) {
+		return nil, fmt.Errorf("risk_level must be conservative, balanced or aggressive")
+	}
+	if params.InvestmentHorizon <= 0 || params.InvestmentHorizon > 50 {
+		return nil, fmt.Errorf("investment_horizon must be between 1 and 50 years")
+	}
+	if params.MonthlyContribution < 0 {
+		return nil, fmt.Errorf("monthly_contribution can't be negative")
+	}
+	if request.UserContext == nil {
+		return nil, fmt.Errorf("no financial data for this user")
+	}
+
+	marketData := request.MarketContext
+	if marketData == nil {
+		marketData = &models.MarketDataSummary{} // Projections fall back to the templates' expected returns
+	}
+	baseRequest := &models.AIAnalysisRequest{
+		UserID:            request.UserContext.UserID,
+		FinancialSummary:  request.UserContext,
+		MarketData:        marketData,
+		RiskProfile:       "balanced",
+		InvestmentHorizon: 10,
+		MonthlyBudget:     math.Max(request.UserContext.MonthlySurplus, 0),
+		Language:          request.Language,
+	}
+	scenario, err := ai.GenerateWhatIfScenario(ctx, baseRequest, &params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop the month-by-month points; the totals are what the answer needs
+	scenario.Projections.Projections = nil
+	scenario.ComparisonBaseline.Projections = nil
+	if math.IsNaN(scenario.Impact.PercentageDifference) || math.IsInf(scenario.Impact.PercentageDifference, 0) {
+		scenario.Impact.PercentageDifference = 0 // The baseline projects nothing to compare with
+	}
+	return scenario, nil
+}
+
+func (ai *AIService) assetPerformanceTool(ctx context.Context, request *models.ChatRequest, raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		Symbol string `json:"symbol"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	symbol := strings.ToUpper(strings.TrimSpace(args.Symbol))
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+
+	// The market context is already priced in the summary's currency
+	if request.MarketContext != nil {
+		for _, asset := range request.MarketContext.Assets {
+			if strings.EqualFold(asset.Symbol, symbol) {
+				return asset, nil
+			}
+		}
+	}
+	if ai.marketService == nil {
+		return nil, fmt.Errorf("no market data for %s", symbol)
+	}
+	return ai.marketService.GetAssetPerformance(ctx, symbol)
+}
+
+func (ai *AIService) portfolioTemplateTool(ctx context.Context, request *models.ChatRequest, raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		RiskLevel string `json:"risk_level"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	for _, template := range models.DefaultPortfolioTemplates {
+		if template.RiskLevel == args.RiskLevel {
+			template.Name = ai.localizeTemplateName(template.Name, request.Language)
+			return template, nil
+		}
+	}
+	return nil, fmt.Errorf("no portfolio template for risk level %q", args.RiskLevel)
+}
+
+// toolWindow is the span of days a transaction tool's result covers
+type toolWindow struct {
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	Source string `json:"source"`         // "summary" or "belvo"
+	Note   string `json:"note,omitempty"` // Set when the requested days aren't all covered
+}
+
+// toolTransactions returns the transactions between from and to (YYYY-MM-DD, both
+// optional) and the window they cover. Days inside the summary's window come from the
+// summary; a range reaching outside it is read with request.FetchTransactions, or, when
+// the request can't read from Belvo, cut down to the summary's window.
+func toolTransactions(ctx context.Context, request *models.ChatRequest, from, to string) ([]models.BelvoTransaction, toolWindow, error) {
+	if request.UserContext == nil {
+		return nil, toolWindow{}, fmt.Errorf("no financial data for this user")
+	}
+
+	var dateFrom, dateTo *time.Time
+	for _, bound := range []struct {
+		name  string
+		value string
+		dest  **time.Time
+	}{{"from", from, &dateFrom}, {"to", to, &dateTo}} {
+		if bound.value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", bound.value)
+		if err != nil {
+			return nil, toolWindow{}, fmt.Errorf("%s must be a date as YYYY-MM-DD", bound.name)
+		}
+		*bound.dest = &date
+	}
+	if dateFrom != nil && dateTo != nil && dateTo.Before(*dateFrom) {
+		return nil, toolWindow{}, fmt.Errorf("to must not be before from")
+	}
+
+	// A summary without transactions or a period covers no days, so any range is outside it
+	summaryFrom, summaryTo, known := transactionWindow(request.UserContext)
+	outside := (dateFrom != nil && (!known || dateFrom.Before(startOfDay(summaryFrom)))) ||
+		(dateTo != nil && (!known || dateTo.After(startOfDay(summaryTo))))
+
+	if outside && request.FetchTransactions != nil {
+		fetchTo := time.Now()
+		if dateTo != nil {
+			fetchTo = *dateTo
+		}
+		fetchFrom := fetchTo.AddDate(0, -DefaultSummaryOptions.LookbackMonths, 0)
+		if dateFrom != nil {
+			fetchFrom = *dateFrom
+		} else if known {
+			fetchFrom = summaryFrom
+		}
+		transactions, err := request.FetchTransactions(ctx, fetchFrom, fetchTo)
+		if err != nil {
+			return nil, toolWindow{}, fmt.Errorf("failed to read transactions from %s to %s: %w", fetchFrom.Format("2006-01-02"), fetchTo.Format("2006-01-02"), err)
+		}
+		window := toolWindow{From: fetchFrom.Format("2006-01-02"), To: fetchTo.Format("2006-01-02"), Source: "belvo"}
+		return filterTransactionsByDate(transactions, &fetchFrom, &fetchTo), window, nil
+	}
+
+	window := toolWindow{Source: "summary"}
+	if known {
+		windowFrom, windowTo := summaryFrom, summaryTo
+		if dateFrom != nil && dateFrom.After(windowFrom) {
+			windowFrom = *dateFrom
+		}
+		if dateTo != nil && dateTo.Before(windowTo) {
+			windowTo = *dateTo
+		}
+		if !startOfDay(windowTo).Before(startOfDay(windowFrom)) {
+			window.From, window.To = windowFrom.Format("2006-01-02"), windowTo.Format("2006-01-02")
+		}
+	}
+	if outside {
+		window.Note = "no transactions are available for these days"
+		if known {
+			window.Note = fmt.Sprintf("only transactions from %s to %s are available", summaryFrom.Format("2006-01-02"), summaryTo.Format("2006-01-02"))
+		}
+	}
+	return filterTransactionsByDate(request.UserContext.RecentTransactions, dateFrom, dateTo), window, nil
+}
+
+// transactionWindow returns the days a summary's transactions cover: its period, or for a
+// summary without one, the span of its transactions
+func transactionWindow(summary *models.FinancialSummary) (time.Time, time.Time, bool) {
+	if summary.Period != nil && !summary.Period.From.IsZero() {
+		return summary.Period.From, summary.Period.To, true
+	}
+
+	var from, to time.Time
+	for _, transaction := range summary.RecentTransactions {
+		date := transaction.AccountingDate.Time()
+		if from.IsZero() || date.Before(from) {
+			from = date
+		}
+		if date.After(to) {
+			to = date
+		}
+	}
+	return from, to, !from.IsZero()
+}
+
+func newToolTransaction(transaction models.BelvoTransaction) toolTransaction {
+	account := ""
+	if name, ok := transaction.Account["name"].(string); ok {
+		account = name
+	}
+	return toolTransaction{
+		Date:        transaction.AccountingDate.Time().Format("2006-01-02"),
+		Description: transaction.Description,
+		Merchant:    transactionMerchant(transaction),
+		Amount:      transaction.Amount,
+		Currency:    transaction.Currency,
+		Type:        transaction.Type,
+		Category:    transaction.Category,
+		Account:     account,
+	}
+}
+
+func transactionMerchant(transaction models.BelvoTransaction) string {
+	if transaction.Merchant == nil {
+		return ""
+	}
+	return transaction.Merchant.Name
+}
+
+// roundTotals rounds per-currency totals to cents
+func roundTotals(totals map[string]float64) map[string]float64 {
+	rounded := make(map[string]float64, len(totals))
+	for currency, total := range totals {
+		rounded[currency] = math.Round(total*100) / 100
+	}
+	return rounded
+}
+
+func isRiskLevel(level string) bool {
+	return level == "conservative" || level == "balanced" || level == "aggressive"
+}
+
+// toolSchema builds a JSON Schema object from its properties and required names
+func toolSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func toolParam(kind, description string) map[string]interface{} {
+	return map[string]interface{}{"type": kind, "description": description}
+}
+
+func toolEnum(description string, values ...string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description, "enum": values}
+}
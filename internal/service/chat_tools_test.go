@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+func TestToolTransactionsWindow(t *testing.T) {
+	summary := &models.FinancialSummary{
+		Period: &models.SummaryPeriod{From: testWindowFrom, To: testWindowTo},
+		RecentTransactions: []models.BelvoTransaction{
+			testTransaction("groceries", "chk", 10, 300, "OUTFLOW", "Food & Groceries", "BRL"),
+		},
+	}
+	older := testTransaction("older", "chk", -60, 50, "OUTFLOW", "Shopping", "BRL")
+	fetch := func(ctx context.Context, from, to time.Time) ([]models.BelvoTransaction, error) {
+		return []models.BelvoTransaction{older}, nil
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		fetch    func(ctx context.Context, from, to time.Time) ([]models.BelvoTransaction, error)
+		want     toolWindow
+		wantIDs  []string
+	}{
+		{
+			name: "inside the summary", from: "2026-01-05", to: "2026-01-20", fetch: fetch,
+			want:    toolWindow{From: "2026-01-05", To: "2026-01-20", Source: "summary"},
+			wantIDs: []string{"groceries"},
+		},
+		{
+			name: "before the summary reads from Belvo", from: "2025-10-01", to: "2025-12-31", fetch: fetch,
+			want:    toolWindow{From: "2025-10-01", To: "2025-12-31", Source: "belvo"},
+			wantIDs: []string{"older"},
+		},
+		{
+			name: "before the summary without Belvo", from: "2025-10-01", to: "2026-01-20",
+			want:    toolWindow{From: "2026-01-01", To: "2026-01-20", Source: "summary", Note: "only transactions from 2026-01-01 to 2026-04-02 are available"},
+			wantIDs: []string{"groceries"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := &models.ChatRequest{UserContext: summary, FetchTransactions: tt.fetch}
+			transactions, window, err := toolTransactions(context.Background(), request, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("toolTransactions() error = %v", err)
+			}
+			if window != tt.want {
+				t.Errorf("window = %+v, want %+v", window, tt.want)
+			}
+			var ids []string
+			for _, transaction := range transactions {
+				ids = append(ids, transaction.ID)
+			}
+			if len(ids) != len(tt.wantIDs) || (len(ids) > 0 && ids[0] != tt.wantIDs[0]) {
+				t.Errorf("transactions = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
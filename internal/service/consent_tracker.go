@@ -0,0 +1,423 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// ConsentReminderDays are the days before expiry at which a renewal reminder is raised
+var ConsentReminderDays = []int{30, 7, 1}
+
+const (
+	// consentSessionTTL is how long a widget session waits for its callback
+	consentSessionTTL = time.Hour
+
+	// maxConsentReminders bounds the in-memory reminder log
+	maxConsentReminders = 200
+)
+
+// ConsentStore persists tracked Open Finance consents
+type ConsentStore interface {
+	Load() ([]models.OFDAConsent, error)
+	Save(consents []models.OFDAConsent) error
+}
+
+// FileConsentStore keeps every tracked consent in one JSON file
+type FileConsentStore struct {
+	path string
+}
+
+// NewFileConsentStore creates a file store at path, creating its directory if needed
+func NewFileConsentStore(path string) (*FileConsentStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create consent store directory: %w", err)
+	}
+	return &FileConsentStore{path: path}, nil
+}
+
+// Load implements ConsentStore
+func (fs *FileConsentStore) Load() ([]models.OFDAConsent, error) {
+	data, err := os.ReadFile(fs.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored consents: %w", err)
+	}
+
+	var consents []models.OFDAConsent
+	if err := json.Unmarshal(data, &consents); err != nil {
+		return nil, fmt.Errorf("failed to parse stored consents: %w", err)
+	}
+	return consents, nil
+}
+
+// Save implements ConsentStore. The file is replaced atomically like the transaction store.
+func (fs *FileConsentStore) Save(consents []models.OFDAConsent) error {
+	data, err := json.Marshal(consents)
+	if err != nil {
+		return fmt.Errorf("failed to marshal consents: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fs.path), ".consents-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write consents: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write consents: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), fs.path); err != nil {
+		return fmt.Errorf("failed to store consents: %w", err)
+	}
+	return nil
+}
+
+// ConsentSession is a widget flow waiting for its callback. The credentials are kept
+// sealed with the session key, so the callback can look the consent up with the same
+// Belvo account without holding the secret in the clear.
+type ConsentSession struct {
+	State          string                    `json:"state"`
+	Consent        models.OFDAConsentRequest `json:"-"`
+	RenewConsentID string                    `json:"renew_consent_id,omitempty"`
+	Owner          string                    `json:"-"` // Credential scope of the account that started the flow
+	Credentials    []byte                    `json:"-"` // Sealed by SessionManager.SealCredentials
+	CreatedAt      time.Time                 `json:"created_at"`
+	ExpiresAt      time.Time                 `json:"expires_at"`
+}
+
+// ConsentTracker follows Open Finance consents from the widget callback until they
+// expire and raises renewal reminders as the expiry approaches
+type ConsentTracker struct {
+	store ConsentStore
+
+	mu        sync.Mutex
+	consents  map[string]*models.OFDAConsent // keyed by consent ID
+	sessions  map[string]*ConsentSession     // keyed by state
+	reminders []models.ConsentReminder
+}
+
+// NewConsentTracker creates a tracker backed by store; a nil store keeps consents in memory only
+func NewConsentTracker(store ConsentStore) (*ConsentTracker, error) {
+	ct := &ConsentTracker{
+		store:    store,
+		consents: make(map[string]*models.OFDAConsent),
+		sessions: make(map[string]*ConsentSession),
+	}
+	if store == nil {
+		return ct, nil
+	}
+
+	consents, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range consents {
+		ct.consents[consents[i].ConsentID] = &consents[i]
+	}
+	return ct, nil
+}
+
+// StartSession registers a widget flow for owner and returns the state its callback
+// must carry. sealedCreds are the owner's credentials, already sealed.
+func (ct *ConsentTracker) StartSession(consent models.OFDAConsentRequest, renewConsentID, owner string, sealedCreds []byte) (*ConsentSession, error) {
+	state := make([]byte, 16)
+	if _, err := rand.Read(state); err != nil {
+		return nil, fmt.Errorf("failed to generate consent state: %w", err)
+	}
+
+	now := time.Now()
+	session := &ConsentSession{
+		State:          hex.EncodeToString(state),
+		Consent:        consent,
+		RenewConsentID: renewConsentID,
+		Owner:          owner,
+		Credentials:    sealedCreds,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(consentSessionTTL),
+	}
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	for key, pending := range ct.sessions {
+		if now.After(pending.ExpiresAt) {
+			delete(ct.sessions, key)
+		}
+	}
+	ct.sessions[session.State] = session
+	return session, nil
+}
+
+// Session returns the pending widget flow for state, if it hasn't expired
+func (ct *ConsentTracker) Session(state string) (*ConsentSession, bool) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	session, ok := ct.sessions[state]
+	if !ok || time.Now().After(session.ExpiresAt) {
+		return nil, false
+	}
+	return session, true
+}
+
+// EndSession drops a widget flow, e.g. when the user exits the widget
+func (ct *ConsentTracker) EndSession(state string) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	delete(ct.sessions, state)
+}
+
+// Complete records the consent a widget flow produced and ends the session. belvo is the
+// consent as Belvo reports it, when it could be fetched, and supplies the expiry date.
+// A renewal replaces the consent it renews.
+func (ct *ConsentTracker) Complete(state, linkID, consentID string, belvo *models.BelvoConsent) (*models.OFDAConsent, error) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	session, ok := ct.sessions[state]
+	if !ok || time.Now().After(session.ExpiresAt) {
+		return nil, fmt.Errorf("unknown or expired consent session")
+	}
+	delete(ct.sessions, state)
+
+	now := time.Now()
+	consent := &models.OFDAConsent{
+		ConsentID:   consentID,
+		LinkID:      linkID,
+		CustomerID:  session.Consent.CustomerID,
+		Owner:       session.Owner,
+		Institution: session.Consent.Institution,
+		Permissions: session.Consent.Permissions,
+		GrantedAt:   now,
+	}
+
+	if renewed, ok := ct.consents[session.RenewConsentID]; ok && session.RenewConsentID != "" && renewed.Owner == session.Owner {
+		consent.GrantedAt = renewed.GrantedAt
+		consent.RenewedAt = &now
+		if consent.LinkID == "" {
+			consent.LinkID = renewed.LinkID
+		}
+		if consent.ConsentID == "" {
+			consent.ConsentID = renewed.ConsentID
+		}
+		delete(ct.consents, session.RenewConsentID)
+	}
+	if consent.ConsentID == "" {
+		return nil, fmt.Errorf("consent_id is required")
+	}
+
+	if belvo != nil && len(belvo.Permissions) > 0 {
+		consent.Permissions = belvo.Permissions
+	}
+	expiresFrom := now
+	if consent.RenewedAt == nil && belvo != nil && !belvo.CreatedAt.IsZero() {
+		expiresFrom = belvo.CreatedAt
+		consent.GrantedAt = belvo.CreatedAt
+	}
+	consent.ExpiresAt, consent.ExpirySource = consentExpiry(belvo, expiresFrom)
+	if belvo != nil && consentRevoked(belvo.Status) {
+		consent.Status = models.ConsentStatusRevoked
+	}
+	refreshConsentStatus(consent, now)
+
+	ct.consents[consent.ConsentID] = consent
+	if err := ct.save(); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("🪪 Consent %s for link %s tracked until %s (%s)\n", consent.ConsentID, consent.LinkID, consent.ExpiresAt.Format("2006-01-02"), consent.ExpirySource)
+	copied := *consent
+	return &copied, nil
+}
+
+// Get returns one of owner's tracked consents with its status as of now. Consents of
+// other owners are reported as not tracked.
+func (ct *ConsentTracker) Get(owner, consentID string) (*models.OFDAConsent, bool) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	consent, ok := ct.consents[consentID]
+	if !ok || consent.Owner != owner {
+		return nil, false
+	}
+	refreshConsentStatus(consent, time.Now())
+	copied := *consent
+	return &copied, true
+}
+
+// List returns owner's tracked consents, soonest to expire first. A non-empty
+// customerID narrows them to that customer.
+func (ct *ConsentTracker) List(owner, customerID string) []models.OFDAConsent {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	now := time.Now()
+	consents := make([]models.OFDAConsent, 0, len(ct.consents))
+	for _, consent := range ct.consents {
+		if consent.Owner != owner || (customerID != "" && consent.CustomerID != customerID) {
+			continue
+		}
+		refreshConsentStatus(consent, now)
+		consents = append(consents, *consent)
+	}
+	sort.Slice(consents, func(a, b int) bool { return consents[a].ExpiresAt.Before(consents[b].ExpiresAt) })
+	return consents
+}
+
+// DueReminders raises a reminder for every active consent that crossed one of the
+// ConsentReminderDays thresholds since the last check. Each threshold fires once.
+func (ct *ConsentTracker) DueReminders(now time.Time) []models.ConsentReminder {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	var due []models.ConsentReminder
+	for _, consent := range ct.consents {
+		refreshConsentStatus(consent, now)
+		if consent.Status != models.ConsentStatusExpiring {
+			continue
+		}
+
+		threshold := 0
+		for _, days := range ConsentReminderDays {
+			if consent.DaysLeft <= days && !containsDay(consent.RemindersSent, days) {
+				threshold = days
+				consent.RemindersSent = append(consent.RemindersSent, days)
+			}
+		}
+		if threshold == 0 {
+			continue
+		}
+
+		due = append(due, models.ConsentReminder{
+			ConsentID:  consent.ConsentID,
+			LinkID:     consent.LinkID,
+			CustomerID: consent.CustomerID,
+			Owner:      consent.Owner,
+			ExpiresAt:  consent.ExpiresAt,
+			DaysLeft:   consent.DaysLeft,
+			CreatedAt:  now,
+			Message:    fmt.Sprintf("Open Finance consent for %s expires in %d day(s); renew it to keep your data up to date", consentInstitution(consent), consent.DaysLeft),
+		})
+	}
+	if len(due) == 0 {
+		return nil
+	}
+
+	sort.Slice(due, func(a, b int) bool { return due[a].DaysLeft < due[b].DaysLeft })
+	ct.reminders = append(ct.reminders, due...)
+	if len(ct.reminders) > maxConsentReminders {
+		ct.reminders = ct.reminders[len(ct.reminders)-maxConsentReminders:]
+	}
+	if err := ct.save(); err != nil {
+		fmt.Printf("⚠️ Failed to store consent reminders: %v\n", err)
+	}
+	return due
+}
+
+// Reminders returns the reminders raised for owner's consents, most recent first. A
+// non-empty customerID narrows them to that customer.
+func (ct *ConsentTracker) Reminders(owner, customerID string) []models.ConsentReminder {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	reminders := make([]models.ConsentReminder, 0, len(ct.reminders))
+	for i := len(ct.reminders) - 1; i >= 0; i-- {
+		reminder := ct.reminders[i]
+		if reminder.Owner == owner && (customerID == "" || reminder.CustomerID == customerID) {
+			reminders = append(reminders, reminder)
+		}
+	}
+	return reminders
+}
+
+// RunReminders checks for due reminders every interval until ctx is done
+func (ct *ConsentTracker) RunReminders(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, reminder := range ct.DueReminders(time.Now()) {
+			fmt.Printf("⏰ Consent %s (link %s) expires in %d day(s)\n", reminder.ConsentID, reminder.LinkID, reminder.DaysLeft)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// save writes every consent to the store; callers hold ct.mu
+func (ct *ConsentTracker) save() error {
+	if ct.store == nil {
+		return nil
+	}
+	consents := make([]models.OFDAConsent, 0, len(ct.consents))
+	for _, consent := range ct.consents {
+		consents = append(consents, *consent)
+	}
+	sort.Slice(consents, func(a, b int) bool { return consents[a].ConsentID < consents[b].ConsentID })
+	return ct.store.Save(consents)
+}
+
+// refreshConsentStatus updates DaysLeft and moves active consents to expiring and expired
+func refreshConsentStatus(consent *models.OFDAConsent, now time.Time) {
+	consent.DaysLeft = int(math.Ceil(consent.ExpiresAt.Sub(now).Hours() / 24))
+	if consent.Status == models.ConsentStatusRevoked {
+		return
+	}
+	switch {
+	case !now.Before(consent.ExpiresAt):
+		consent.Status = models.ConsentStatusExpired
+		consent.DaysLeft = 0
+	case consent.DaysLeft <= ConsentReminderDays[0]:
+		consent.Status = models.ConsentStatusExpiring
+	default:
+		consent.Status = models.ConsentStatusActive
+	}
+}
+
+// consentRevoked reports whether a Belvo consent status means the consent no longer grants access
+func consentRevoked(status string) bool {
+	switch strings.ToUpper(status) {
+	case "REVOKED", "REJECTED":
+		return true
+	}
+	return false
+}
+
+func consentInstitution(consent *models.OFDAConsent) string {
+	if consent.Institution != "" {
+		return consent.Institution
+	}
+	return "your bank"
+}
+
+func containsDay(days []int, day int) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// ErrConversationNotFound is returned for a conversation that doesn't exist or belongs
+// to another owner
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// ConversationStore is the repository of chat conversations. Owner separates the
+// conversations of different Belvo accounts (see CredentialScope); every lookup is
+// scoped to it. Conversations are returned without messages except by Get.
+type ConversationStore interface {
+	List(ctx context.Context, owner string) ([]models.Conversation, error)
+	Get(ctx context.Context, owner, id string) (*models.Conversation, error)
+	// Create stores a new conversation together with its first messages
+	Create(ctx context.Context, owner string, conversation *models.Conversation) error
+	AddMessages(ctx context.Context, owner, id string, messages ...models.ConversationMessage) error
+	Rename(ctx context.Context, owner, id, title string) error
+	Delete(ctx context.Context, owner, id string) error
+}
+
+// NewConversationID returns a random conversation ID
+func NewConversationID() string {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprintf("conv_%d", time.Now().UnixNano())
+	}
+	return "conv_" + hex.EncodeToString(id)
+}
+
+// MemoryConversationStore keeps conversations in memory; they are lost on restart
+type MemoryConversationStore struct {
+	mu            sync.RWMutex
+	conversations map[string]map[string]*models.Conversation // owner -> ID -> conversation
+}
+
+// NewMemoryConversationStore creates an empty in-memory store
+func NewMemoryConversationStore() *MemoryConversationStore {
+	return &MemoryConversationStore{
+		conversations: make(map[string]map[string]*models.Conversation),
+	}
+}
+
+// List implements ConversationStore, most recently updated first
+func (ms *MemoryConversationStore) List(ctx context.Context, owner string) ([]models.Conversation, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	conversations := make([]models.Conversation, 0, len(ms.conversations[owner]))
+	for _, conversation := range ms.conversations[owner] {
+		listed := *conversation
+		listed.Messages = nil
+		conversations = append(conversations, listed)
+	}
+	sort.Slice(conversations, func(a, b int) bool {
+		return conversations[a].UpdatedAt.After(conversations[b].UpdatedAt)
+	})
+	return conversations, nil
+}
+
+// Get implements ConversationStore
+func (ms *MemoryConversationStore) Get(ctx context.Context, owner, id string) (*models.Conversation, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	conversation, ok := ms.conversations[owner][id]
+	if !ok {
+		return nil, ErrConversationNotFound
+	}
+	found := *conversation
+	found.Messages = append([]models.ConversationMessage(nil), conversation.Messages...)
+	return &found, nil
+}
+
+// Create implements ConversationStore
+func (ms *MemoryConversationStore) Create(ctx context.Context, owner string, conversation *models.Conversation) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.conversations[owner] == nil {
+		ms.conversations[owner] = make(map[string]*models.Conversation)
+	}
+	if _, exists := ms.conversations[owner][conversation.ID]; exists {
+		return fmt.Errorf("conversation %s already exists", conversation.ID)
+	}
+
+	stored := *conversation
+	stored.Messages = append([]models.ConversationMessage(nil), conversation.Messages...)
+	stored.MessageCount = len(stored.Messages)
+	ms.conversations[owner][conversation.ID] = &stored
+	return nil
+}
+
+// AddMessages implements ConversationStore
+func (ms *MemoryConversationStore) AddMessages(ctx context.Context, owner, id string, messages ...models.ConversationMessage) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	conversation, ok := ms.conversations[owner][id]
+	if !ok {
+		return ErrConversationNotFound
+	}
+	conversation.Messages = append(conversation.Messages, messages...)
+	conversation.MessageCount = len(conversation.Messages)
+	conversation.UpdatedAt = time.Now()
+	return nil
+}
+
+// Rename implements ConversationStore
+func (ms *MemoryConversationStore) Rename(ctx context.Context, owner, id, title string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	conversation, ok := ms.conversations[owner][id]
+	if !ok {
+		return ErrConversationNotFound
+	}
+	conversation.Title = title
+	conversation.UpdatedAt = time.Now()
+	return nil
+}
+
+// Delete implements ConversationStore
+func (ms *MemoryConversationStore) Delete(ctx context.Context, owner, id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, ok := ms.conversations[owner][id]; !ok {
+		return ErrConversationNotFound
+	}
+	delete(ms.conversations[owner], id)
+	return nil
+}
+
+// conversationSchema creates the tables of SQLConversationStore. Times are Unix
+// milliseconds so the same statements work on SQLite, PostgreSQL and MySQL.
+var conversationSchema = []string{
+	`CREATE TABLE IF NOT EXISTS conversations (
+		id VARCHAR(64) NOT NULL PRIMARY KEY,
+		owner VARCHAR(64) NOT NULL,
+		title VARCHAR(255) NOT NULL,
+		link_id VARCHAR(64) NOT NULL,
+		language VARCHAR(8) NOT NULL,
+		created_at BIGINT NOT NULL,
+		updated_at BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS conversation_messages (
+		conversation_id VARCHAR(64) NOT NULL,
+		seq INTEGER NOT NULL,
+		role VARCHAR(16) NOT NULL,
+		content TEXT NOT NULL,
+		created_at BIGINT NOT NULL,
+		PRIMARY KEY (conversation_id, seq)
+	)`,
+}
+
+// SQLConversationStore keeps conversations in a SQL database through database/sql
+type SQLConversationStore struct {
+	db       *sql.DB
+	numbered bool // PostgreSQL takes $1, $2... instead of ?
+	rowLocks bool // SELECT ... FOR UPDATE works; SQLite locks the whole database on write instead
+}
+
+// NewSQLConversationStore creates the tables if needed. driver is the database/sql
+// driver name, used to pick the placeholder style.
+func NewSQLConversationStore(db *sql.DB, driver string) (*SQLConversationStore, error) {
+	store := &SQLConversationStore{
+		db:       db,
+		numbered: driver == "postgres" || driver == "pgx",
+		rowLocks: !strings.HasPrefix(driver, "sqlite"),
+	}
+	for _, statement := range conversationSchema {
+		if _, err := db.Exec(statement); err != nil {
+			return nil, fmt.Errorf("failed to create conversation tables: %w", err)
+		}
+	}
+	return store, nil
+}
+
+// List implements ConversationStore, most recently updated first
+func (ss *SQLConversationStore) List(ctx context.Context, owner string) ([]models.Conversation, error) {
+	rows, err := ss.db.QueryContext(ctx, ss.query(`
+		SELECT c.id, c.title, c.link_id, c.language, c.created_at, c.updated_at,
+			(SELECT COUNT(*) FROM conversation_messages m WHERE m.conversation_id = c.id)
+		FROM conversations c
+		WHERE c.owner = ?
+		ORDER BY c.updated_at DESC`), owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	defer rows.Close()
+
+	conversations := []models.Conversation{}
+	for rows.Next() {
+		var conversation models.Conversation
+		var createdAt, updatedAt int64
+		if err := rows.Scan(&conversation.ID, &conversation.Title, &conversation.LinkID, &conversation.Language, &createdAt, &updatedAt, &conversation.MessageCount); err != nil {
+			return nil, fmt.Errorf("failed to read conversation: %w", err)
+		}
+		conversation.CreatedAt, conversation.UpdatedAt = time.UnixMilli(createdAt), time.UnixMilli(updatedAt)
+		conversations = append(conversations, conversation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	return conversations, nil
+}
+
+// Get implements ConversationStore
+func (ss *SQLConversationStore) Get(ctx context.Context, owner, id string) (*models.Conversation, error) {
+	conversation := models.Conversation{ID: id}
+	var createdAt, updatedAt int64
+	err := ss.db.QueryRowContext(ctx, ss.query(`
+		SELECT title, link_id, language, created_at, updated_at
+		FROM conversations
+		WHERE id = ? AND owner = ?`), id, owner).
+		Scan(&conversation.Title, &conversation.LinkID, &conversation.Language, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrConversationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conversation: %w", err)
+	}
+	conversation.CreatedAt, conversation.UpdatedAt = time.UnixMilli(createdAt), time.UnixMilli(updatedAt)
+
+	rows, err := ss.db.QueryContext(ctx, ss.query(`
+		SELECT role, content, created_at
+		FROM conversation_messages
+		WHERE conversation_id = ?
+		ORDER BY seq`), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conversation messages: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var message models.ConversationMessage
+		var messageAt int64
+		if err := rows.Scan(&message.Role, &message.Content, &messageAt); err != nil {
+			return nil, fmt.Errorf("failed to read conversation message: %w", err)
+		}
+		message.CreatedAt = time.UnixMilli(messageAt)
+		conversation.Messages = append(conversation.Messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read conversation messages: %w", err)
+	}
+	conversation.MessageCount = len(conversation.Messages)
+	return &conversation, nil
+}
+
+// Create implements ConversationStore
+func (ss *SQLConversationStore) Create(ctx context.Context, owner string, conversation *models.Conversation) error {
+	return ss.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, ss.query(`
+			INSERT INTO conversations (id, owner, title, link_id, language, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`),
+			conversation.ID, owner, conversation.Title, conversation.LinkID, conversation.Language,
+			conversation.CreatedAt.UnixMilli(), conversation.UpdatedAt.UnixMilli())
+		if err != nil {
+			return fmt.Errorf("failed to create conversation: %w", err)
+		}
+		return ss.insertMessages(ctx, tx, conversation.ID, 0, conversation.Messages)
+	})
+}
+
+// AddMessages implements ConversationStore. The conversation row is locked before the
+// next seq is read, so concurrent turns of one conversation number their messages one
+// after the other instead of colliding.
+func (ss *SQLConversationStore) AddMessages(ctx context.Context, owner, id string, messages ...models.ConversationMessage) error {
+	return ss.inTx(ctx, func(tx *sql.Tx) error {
+		lock := `SELECT id FROM conversations WHERE id = ? AND owner = ?`
+		if ss.rowLocks {
+			lock += ` FOR UPDATE`
+		}
+		var locked string
+		err := tx.QueryRowContext(ctx, ss.query(lock), id, owner).Scan(&locked)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrConversationNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock conversation: %w", err)
+		}
+
+		// On SQLite this write is what takes the lock, so it comes before reading seq
+		if _, err := tx.ExecContext(ctx, ss.query(`UPDATE conversations SET updated_at = ? WHERE id = ?`), time.Now().UnixMilli(), id); err != nil {
+			return fmt.Errorf("failed to update conversation: %w", err)
+		}
+
+		var next int
+		if err := tx.QueryRowContext(ctx, ss.query(`SELECT COALESCE(MAX(seq) + 1, 0) FROM conversation_messages WHERE conversation_id = ?`), id).Scan(&next); err != nil {
+			return fmt.Errorf("failed to number conversation messages: %w", err)
+		}
+		return ss.insertMessages(ctx, tx, id, next, messages)
+	})
+}
+
+// Rename implements ConversationStore
+func (ss *SQLConversationStore) Rename(ctx context.Context, owner, id, title string) error {
+	result, err := ss.db.ExecContext(ctx, ss.query(`UPDATE conversations SET title = ?, updated_at = ? WHERE id = ? AND owner = ?`),
+		title, time.Now().UnixMilli(), id, owner)
+	if err != nil {
+		return fmt.Errorf("failed to rename conversation: %w", err)
+	}
+	return requireRow(result)
+}
+
+// Delete implements ConversationStore
+func (ss *SQLConversationStore) Delete(ctx context.Context, owner, id string) error {
+	return ss.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, ss.query(`DELETE FROM conversations WHERE id = ? AND owner = ?`), id, owner)
+		if err != nil {
+			return fmt.Errorf("failed to delete conversation: %w", err)
+		}
+		if err := requireRow(result); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, ss.query(`DELETE FROM conversation_messages WHERE conversation_id = ?`), id); err != nil {
+			return fmt.Errorf("failed to delete conversation messages: %w", err)
+		}
+		return nil
+	})
+}
+
+// insertMessages numbers messages from seq onwards
+func (ss *SQLConversationStore) insertMessages(ctx context.Context, tx *sql.Tx, id string, seq int, messages []models.ConversationMessage) error {
+	for i, message := range messages {
+		_, err := tx.ExecContext(ctx, ss.query(`
+			INSERT INTO conversation_messages (conversation_id, seq, role, content, created_at)
+			VALUES (?, ?, ?, ?, ?)`),
+			id, seq+i, message.Role, message.Content, message.CreatedAt.UnixMilli())
+		if err != nil {
+			return fmt.Errorf("failed to store conversation message: %w", err)
+		}
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction, committing when it succeeds
+func (ss *SQLConversationStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := ss.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// query rewrites ? placeholders as $1, $2... for PostgreSQL
+func (ss *SQLConversationStore) query(query string) string {
+	if !ss.numbered {
+		return query
+	}
+	var numbered strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			numbered.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		numbered.WriteRune(r)
+	}
+	return numbered.String()
+}
+
+// requireRow turns a statement that matched no row into ErrConversationNotFound. Updates
+// always set updated_at too, so MySQL, which counts changed rather than matched rows,
+// still reports the row.
+func requireRow(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrConversationNotFound
+	}
+	return nil
+}
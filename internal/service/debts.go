@@ -0,0 +1,208 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+
+	"ai-financial-coach/internal/models"
+)
+
+// GetLoans retrieves the loans a link's institution reports, with their outstanding
+// principal, interest rates and installments
+func (bs *BelvoService) GetLoans(ctx context.Context, linkID string) ([]models.BelvoLoan, error) {
+	endpoint := fmt.Sprintf("%s/api/loans/", bs.credentials.BaseURL)
+
+	reqBodyBytes, err := json.Marshal(map[string]interface{}{
+		"link": linkID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(reqBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.SetBasicAuth(bs.credentials.SecretID, bs.credentials.SecretKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("accept", "application/json")
+
+	// POST is how Belvo reads these resources, so it is safe to retry
+	resp, err := bs.httpClient.Do(markIdempotentRead(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to retrieve loans: %w", NewBelvoAPIError(resp.StatusCode, body))
+	}
+
+	var loans []models.BelvoLoan
+	if err := json.Unmarshal(body, &loans); err != nil {
+		return nil, fmt.Errorf("failed to decode loans response: %w", err)
+	}
+
+	return loans, nil
+}
+
+// debtsFromData normalizes loans and credit card balances into debts. Loan accounts
+// that the loans resource doesn't describe are kept with their balance only.
+func debtsFromData(accounts []models.BelvoAccount, loans []models.BelvoLoan) []models.Debt {
+	var debts []models.Debt
+	described := make(map[string]bool, len(loans))
+
+	for _, loan := range loans {
+		if id, _ := loan.Account["id"].(string); id != "" {
+			described[id] = true
+		}
+		principal := math.Abs(loan.OutstandingPrincipal)
+		if principal == 0 {
+			continue
+		}
+		debts = append(debts, models.Debt{
+			Source:                "loan",
+			ID:                    loan.ID,
+			Name:                  loan.Name,
+			Type:                  loan.Type,
+			Currency:              loan.Currency,
+			OutstandingPrincipal:  principal,
+			InterestBearing:       principal,
+			AnnualInterestRate:    annualInterestRate(loan.InterestRates),
+			MonthlyPayment:        loan.MonthlyPayment,
+			InstallmentsRemaining: loan.NumberOfInstallmentsOutstanding,
+		})
+	}
+
+	for _, account := range accounts {
+		balance := math.Abs(account.Balance.Current)
+		if balance == 0 {
+			continue
+		}
+		switch ClassifyAccount(account) {
+		case AccountClassCredit:
+			debt := models.Debt{
+				Source:               "credit_card",
+				ID:                   account.ID,
+				Name:                 account.Name,
+				Type:                 account.Category,
+				Currency:             account.Currency,
+				OutstandingPrincipal: balance,
+			}
+			// Only what is left after the no-interest payment revolves and accrues interest
+			if credit := account.CreditData; credit != nil {
+				debt.AnnualInterestRate = credit.InterestRate / 100
+				debt.MonthlyPayment = credit.MinimumPayment
+				if credit.NoInterestPayment > 0 {
+					debt.InterestBearing = roundCents(math.Max(0, balance-credit.NoInterestPayment))
+				}
+			}
+			debts = append(debts, debt)
+		case AccountClassLoan:
+			if described[account.ID] {
+				continue
+			}
+			debts = append(debts, models.Debt{
+				Source:               "loan",
+				ID:                   account.ID,
+				Name:                 account.Name,
+				Type:                 account.Category,
+				Currency:             account.Currency,
+				OutstandingPrincipal: balance,
+				InterestBearing:      balance,
+			})
+		}
+	}
+
+	return debts
+}
+
+// undescribedLoanPrincipal is the principal of loans that aren't also listed as accounts,
+// which account balances alone leave out of the liabilities
+func undescribedLoanPrincipal(accounts []models.BelvoAccount, loans []models.BelvoLoan) float64 {
+	known := make(map[string]bool, len(accounts))
+	for _, account := range accounts {
+		known[account.ID] = true
+	}
+
+	total := 0.0
+	for _, loan := range loans {
+		if id, _ := loan.Account["id"].(string); id != "" && known[id] {
+			continue
+		}
+		total += math.Abs(loan.OutstandingPrincipal)
+	}
+	return roundCents(total)
+}
+
+// annualInterestRate returns the highest rate on a loan as an annual decimal. Monthly
+// rates are compounded, the way Brazilian lenders quote their CET.
+func annualInterestRate(rates []models.BelvoInterestRate) float64 {
+	annual := 0.0
+	for _, rate := range rates {
+		value := rate.Value / 100
+		if strings.EqualFold(rate.Type, "MONTHLY") {
+			value = math.Pow(1+value, 12) - 1
+		}
+		annual = math.Max(annual, value)
+	}
+	return annual
+}
+
+// HighInterestThreshold is the best expected return among the portfolio templates.
+// Debt costing more than this is cheaper to pay off than to invest around.
+func HighInterestThreshold() float64 {
+	threshold := 0.0
+	for _, template := range models.DefaultPortfolioTemplates {
+		threshold = math.Max(threshold, template.ExpectedReturn)
+	}
+	return threshold
+}
+
+// AnalyzeDebt computes debt-to-income, the weighted cost of debt and whether
+// high-interest debt should be paid before investing
+func AnalyzeDebt(summary *models.FinancialSummary) *models.DebtAnalysis {
+	analysis := &models.DebtAnalysis{
+		HighInterestThreshold: HighInterestThreshold(),
+		Debts:                 summary.Debts,
+	}
+	if analysis.Debts == nil {
+		analysis.Debts = []models.Debt{}
+	}
+
+	weightedRate := 0.0
+	interestBearing := 0.0
+	for _, debt := range summary.Debts {
+		analysis.TotalDebt += debt.OutstandingPrincipal
+		analysis.MonthlyDebtPayments += debt.MonthlyPayment
+		weightedRate += debt.InterestBearing * debt.AnnualInterestRate
+		interestBearing += debt.InterestBearing
+		if debt.AnnualInterestRate > analysis.HighInterestThreshold {
+			analysis.HighInterestDebt += debt.InterestBearing
+		}
+	}
+
+	if interestBearing > 0 {
+		analysis.WeightedInterestRate = weightedRate / interestBearing
+	}
+	if summary.MonthlyIncome > 0 {
+		analysis.DebtToIncomeRatio = analysis.MonthlyDebtPayments / summary.MonthlyIncome
+	}
+	analysis.TotalDebt = roundCents(analysis.TotalDebt)
+	analysis.MonthlyDebtPayments = roundCents(analysis.MonthlyDebtPayments)
+	analysis.HighInterestDebt = roundCents(analysis.HighInterestDebt)
+	analysis.PayDownFirst = analysis.HighInterestDebt > 0
+	return analysis
+}
@@ -0,0 +1,345 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// transferMatchWindow is how far apart the two legs of an internal transfer may be booked
+const transferMatchWindow = 2 * 24 * time.Hour
+
+// transferCategory is the Belvo category assigned to transfers between accounts
+const transferCategory = "Transfers"
+
+// linkFinancialData is the raw Belvo data behind a financial summary for one link
+type linkFinancialData struct {
+	linkID            string
+	accounts          []models.BelvoAccount
+	transactions      []models.BelvoTransaction
+	incomes           []models.BelvoIncome
+	recurringExpenses []models.BelvoRecurringExpense
+	investments       []models.BelvoInvestmentPortfolio
+	loans             []models.BelvoLoan
+}
+
+// fetchLinkData loads accounts, transactions between dateFrom and dateTo, incomes,
+// recurring expenses, investment portfolios and loans for a link
+func (bs *BelvoService) fetchLinkData(ctx context.Context, linkID string, dateFrom, dateTo time.Time) (*linkFinancialData, error) {
+	accounts, err := bs.GetAccounts(ctx, linkID)
+	if err != nil {
+		fmt.Printf("❌ Failed to get accounts: %v\n", err)
+		return nil, fmt.Errorf("failed to get accounts: %w", err)
+	}
+	fmt.Printf("✅ Retrieved %d accounts for link %s\n", len(accounts), linkID)
+
+	fmt.Printf("📅 Fetching transactions from %s to %s\n", dateFrom.Format("2006-01-02"), dateTo.Format("2006-01-02"))
+	transactions, err := bs.GetStoredTransactions(ctx, linkID, &dateFrom, &dateTo)
+	if err != nil {
+		fmt.Printf("❌ Failed to get transactions: %v\n", err)
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+	fmt.Printf("✅ Retrieved %d transactions for link %s\n", len(transactions), linkID)
+
+	incomes, err := bs.GetIncomes(ctx, linkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incomes: %w", err)
+	}
+
+	recurringExpenses, err := bs.GetRecurringExpenses(ctx, linkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recurring expenses: %w", err)
+	}
+
+	// Many institutions don't expose investments, so a failure here isn't fatal
+	investments, err := bs.GetInvestmentPortfolios(ctx, linkID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		fmt.Printf("⚠️ No investment portfolios for link %s: %v\n", linkID, err)
+		investments = nil
+	}
+
+	loans, err := bs.GetLoans(ctx, linkID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		fmt.Printf("⚠️ No loans for link %s: %v\n", linkID, err)
+		loans = nil
+	}
+
+	return &linkFinancialData{
+		linkID:            linkID,
+		accounts:          accounts,
+		transactions:      transactions,
+		incomes:           incomes,
+		recurringExpenses: recurringExpenses,
+		investments:       investments,
+		loans:             loans,
+	}, nil
+}
+
+// GetConsolidatedFinancialSummary merges the data of several links belonging to one
+// person into a single summary. Transfers between their own accounts are excluded so
+// money moved from one bank to another isn't counted as both income and expense.
+func (bs *BelvoService) GetConsolidatedFinancialSummary(ctx context.Context, linkIDs []string, opts SummaryOptions) (*models.FinancialSummary, error) {
+	linkIDs = NormalizeLinkIDs(linkIDs)
+	if len(linkIDs) == 0 {
+		return nil, fmt.Errorf("at least one link ID is required")
+	}
+	opts, err := ValidateSummaryOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	dateFrom, dateTo := summaryWindow(opts, time.Now())
+	fmt.Printf("🔍 Starting consolidated financial summary for %d links: %s\n", len(linkIDs), strings.Join(linkIDs, ", "))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Links are fetched in parallel; the shared transport caps concurrency per secret
+	results := make([]*linkFinancialData, len(linkIDs))
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i, linkID := range linkIDs {
+		wg.Add(1)
+		go func(i int, linkID string) {
+			defer wg.Done()
+			data, err := bs.fetchLinkData(ctx, linkID, dateFrom, dateTo)
+			if err != nil {
+				// Report the failure that stopped the others, not their cancellations
+				once.Do(func() {
+					firstErr = fmt.Errorf("link %s: %w", linkID, err)
+					cancel()
+				})
+				return
+			}
+			results[i] = data
+		}(i, linkID)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	summary, err := buildFinancialSummary(ctx, strings.Join(linkIDs, ","), results, opts, dateFrom, dateTo)
+	if err != nil {
+		return nil, err
+	}
+	summary.LinkIDs = linkIDs
+	return summary, nil
+}
+
+// buildFinancialSummary merges per-link data, deduping accounts shared by several links,
+// looks up the FX rates it needs and runs it through DefaultSummaryEngine
+func buildFinancialSummary(ctx context.Context, userID string, links []*linkFinancialData, opts SummaryOptions, from, to time.Time) (*models.FinancialSummary, error) {
+	input := SummaryInput{
+		UserID:            userID,
+		LookbackMonths:    opts.LookbackMonths,
+		From:              from,
+		To:                to,
+		ReportingCurrency: opts.ReportingCurrency,
+	}
+
+	seenAccounts := make(map[string]bool)
+	for _, data := range links {
+		for _, account := range data.accounts {
+			if seenAccounts[account.ID] {
+				continue
+			}
+			seenAccounts[account.ID] = true
+			input.Accounts = append(input.Accounts, account)
+		}
+		input.Transactions = append(input.Transactions, data.transactions...)
+		input.Incomes = append(input.Incomes, data.incomes...)
+		input.RecurringExpenses = append(input.RecurringExpenses, data.recurringExpenses...)
+		input.Investments = append(input.Investments, data.investments...)
+		input.Loans = append(input.Loans, data.loans...)
+	}
+
+	if err := LoadFXRates(ctx, &input); err != nil {
+		return nil, err
+	}
+	return DefaultSummaryEngine.Build(input), nil
+}
+
+// LoadFXRates fills in input.FXRates for every currency in the input other than the
+// reporting currency, defaulting the reporting currency first if it is empty
+func LoadFXRates(ctx context.Context, input *SummaryInput) error {
+	if input.ReportingCurrency == "" {
+		input.ReportingCurrency = defaultReportingCurrency(input.Accounts)
+	}
+	foreign := foreignCurrencies(input)
+	if len(foreign) == 0 {
+		return nil
+	}
+
+	fmt.Printf("💱 Converting %s amounts to %s\n", strings.Join(foreign, ", "), input.ReportingCurrency)
+	rates, err := SharedFXService().Rates(ctx, append(foreign, input.ReportingCurrency)...)
+	if err != nil {
+		return fmt.Errorf("failed to get FX rates: %w", err)
+	}
+	input.FXRates = rates
+	return nil
+}
+
+// foreignCurrencies lists the currencies in the input other than the reporting currency
+func foreignCurrencies(input *SummaryInput) []string {
+	seen := map[string]bool{strings.ToUpper(input.ReportingCurrency): true, "": true}
+	var foreign []string
+	add := func(currency string) {
+		currency = strings.ToUpper(currency)
+		if !seen[currency] {
+			seen[currency] = true
+			foreign = append(foreign, currency)
+		}
+	}
+
+	for _, account := range input.Accounts {
+		add(account.Currency)
+	}
+	for _, transaction := range input.Transactions {
+		add(transaction.Currency)
+	}
+	for _, income := range input.Incomes {
+		add(income.Currency)
+	}
+	for _, expense := range input.RecurringExpenses {
+		add(expense.Currency)
+	}
+	for _, portfolio := range input.Investments {
+		add(portfolio.Currency)
+		for _, instrument := range portfolio.Instruments {
+			add(instrument.Currency)
+		}
+	}
+	for _, loan := range input.Loans {
+		add(loan.Currency)
+	}
+	sort.Strings(foreign)
+	return foreign
+}
+
+// internalTransfers finds pairs of transactions that move money between the user's own
+// accounts: an outflow and an inflow of the same amount and currency on two different
+// known accounts, booked within transferMatchWindow, with at least one leg categorized
+// as a transfer. It returns the indexes of both legs of every pair.
+func internalTransfers(accounts []models.BelvoAccount, transactions []models.BelvoTransaction) map[int]bool {
+	ownAccounts := make(map[string]bool, len(accounts))
+	for _, account := range accounts {
+		ownAccounts[account.ID] = true
+	}
+
+	var outflows, inflows []int
+	for i, transaction := range transactions {
+		if !ownAccounts[transactionAccountID(transaction)] {
+			continue
+		}
+		switch transaction.Type {
+		case "OUTFLOW":
+			outflows = append(outflows, i)
+		case "INFLOW":
+			inflows = append(inflows, i)
+		}
+	}
+
+	byDate := func(indexes []int) {
+		sort.SliceStable(indexes, func(a, b int) bool {
+			return transactions[indexes[a]].AccountingDate.Time().Before(transactions[indexes[b]].AccountingDate.Time())
+		})
+	}
+	byDate(outflows)
+	byDate(inflows)
+
+	excluded := make(map[int]bool)
+	for _, out := range outflows {
+		sent := transactions[out]
+		best := -1
+		var bestGap time.Duration
+		for _, in := range inflows {
+			if excluded[in] {
+				continue
+			}
+			received := transactions[in]
+			if !isTransferPair(sent, received) {
+				continue
+			}
+			gap := absDuration(received.AccountingDate.Time().Sub(sent.AccountingDate.Time()))
+			if best == -1 || gap < bestGap {
+				best, bestGap = in, gap
+			}
+		}
+		if best != -1 {
+			excluded[out] = true
+			excluded[best] = true
+		}
+	}
+
+	return excluded
+}
+
+// withoutIndexes returns the transactions whose index isn't in excluded
+func withoutIndexes(transactions []models.BelvoTransaction, excluded map[int]bool) []models.BelvoTransaction {
+	kept := make([]models.BelvoTransaction, 0, len(transactions)-len(excluded))
+	for i, transaction := range transactions {
+		if !excluded[i] {
+			kept = append(kept, transaction)
+		}
+	}
+	return kept
+}
+
+// isTransferPair reports whether sent and received look like two legs of one transfer
+func isTransferPair(sent, received models.BelvoTransaction) bool {
+	if transactionAccountID(sent) == transactionAccountID(received) {
+		return false
+	}
+	if sent.Currency != received.Currency || math.Abs(sent.Amount-received.Amount) > 0.01 {
+		return false
+	}
+	if sent.Category != transferCategory && received.Category != transferCategory {
+		return false
+	}
+	gap := absDuration(received.AccountingDate.Time().Sub(sent.AccountingDate.Time()))
+	return gap <= transferMatchWindow
+}
+
+// transactionAccountID returns the ID of the account a transaction was booked on
+func transactionAccountID(transaction models.BelvoTransaction) string {
+	id, _ := transaction.Account["id"].(string)
+	return id
+}
+
+// NormalizeLinkIDs trims, drops empty and duplicate IDs and sorts the rest so the
+// same set of links always produces the same summary ID
+func NormalizeLinkIDs(linkIDs []string) []string {
+	seen := make(map[string]bool, len(linkIDs))
+	var normalized []string
+	for _, linkID := range linkIDs {
+		linkID = strings.TrimSpace(linkID)
+		if linkID == "" || seen[linkID] {
+			continue
+		}
+		seen[linkID] = true
+		normalized = append(normalized, linkID)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
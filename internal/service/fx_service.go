@@ -0,0 +1,467 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// FX rate sources
+const (
+	FXSourcePTAX    = "bcb_ptax"    // Banco Central do Brasil PTAX, BRL per USD
+	FXSourceBanxico = "banxico_fix" // Banco de México FIX, MXN per USD
+	FXSourceTRM     = "banrep_trm"  // Colombian TRM, COP per USD
+	FXSourceLocal   = "local"       // Fixed stand-in rates, no network
+)
+
+// fxCacheTTL is how long a fetched rate is reused; the official rates change once a day
+const fxCacheTTL = time.Hour
+
+// DefaultLocalFXRates are the stand-in rates, in units per US dollar, for the currencies
+// of the countries Belvo covers. They are only approximations, for offline development
+// and for when the official sources can't be reached.
+var DefaultLocalFXRates = map[string]float64{
+	"USD": 1,
+	"BRL": 5.40,
+	"MXN": 18.30,
+	"COP": 4150,
+}
+
+// IsSupportedCurrency reports whether amounts can be converted to and from currency
+func IsSupportedCurrency(currency string) bool {
+	_, ok := DefaultLocalFXRates[strings.ToUpper(currency)]
+	return ok
+}
+
+// SupportedCurrencies lists the currencies amounts can be reported in
+func SupportedCurrencies() []string {
+	currencies := make([]string, 0, len(DefaultLocalFXRates))
+	for currency := range DefaultLocalFXRates {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// FXRateProvider quotes currencies against the US dollar
+type FXRateProvider interface {
+	// USDRate returns how many units of currency one US dollar buys
+	USDRate(ctx context.Context, currency string) (*models.FXRate, error)
+}
+
+// LocalFXProvider serves fixed rates without touching the network
+type LocalFXProvider struct {
+	rates map[string]float64
+}
+
+// NewLocalFXProvider creates a provider for the given units-per-dollar rates
+func NewLocalFXProvider(rates map[string]float64) *LocalFXProvider {
+	return &LocalFXProvider{rates: rates}
+}
+
+// USDRate returns the stand-in rate for currency
+func (p *LocalFXProvider) USDRate(ctx context.Context, currency string) (*models.FXRate, error) {
+	rate, ok := p.rates[currency]
+	if !ok {
+		return nil, fmt.Errorf("no local rate for %s", currency)
+	}
+	return &models.FXRate{Base: "USD", Quote: currency, Rate: rate, Date: time.Now(), Source: FXSourceLocal}, nil
+}
+
+// PTAXProvider fetches the BRL closing rate from the Central Bank's PTAX service
+type PTAXProvider struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewPTAXProvider creates a PTAXProvider for the public olinda endpoint
+func NewPTAXProvider() *PTAXProvider {
+	return &PTAXProvider{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		baseURL:    "https://olinda.bcb.gov.br/olinda/servico/PTAX/versao/v1/odata",
+	}
+}
+
+// ptaxResponse is the OData payload of CotacaoDolarPeriodo
+type ptaxResponse struct {
+	Value []struct {
+		BuyRate  float64 `json:"cotacaoCompra"`
+		SellRate float64 `json:"cotacaoVenda"`
+		QuotedAt string  `json:"dataHoraCotacao"`
+	} `json:"value"`
+}
+
+// USDRate returns the latest PTAX sell rate. The last week is requested so weekends
+// and holidays still find the previous business day's rate.
+func (p *PTAXProvider) USDRate(ctx context.Context, currency string) (*models.FXRate, error) {
+	if currency != "BRL" {
+		return nil, fmt.Errorf("PTAX only quotes BRL, not %s", currency)
+	}
+
+	now := time.Now()
+	endpoint := fmt.Sprintf("%s/CotacaoDolarPeriodo(dataInicial=@dataInicial,dataFinalCotacao=@dataFinalCotacao)?@dataInicial='%s'&@dataFinalCotacao='%s'&$orderby=dataHoraCotacao%%20desc&$top=1&$format=json",
+		p.baseURL, now.AddDate(0, 0, -7).Format("01-02-2006"), now.Format("01-02-2006"))
+
+	body, err := fetchFXBody(ctx, p.httpClient, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch PTAX rate: %w", err)
+	}
+
+	var payload ptaxResponse
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to decode PTAX response: %w", err)
+	}
+	if len(payload.Value) == 0 || payload.Value[0].SellRate <= 0 {
+		return nil, fmt.Errorf("PTAX returned no rate for the last week")
+	}
+
+	quote := payload.Value[0]
+	date, err := time.Parse("2006-01-02 15:04:05.000", quote.QuotedAt)
+	if err != nil {
+		date = now
+	}
+	return &models.FXRate{Base: "USD", Quote: "BRL", Rate: quote.SellRate, Date: date, Source: FXSourcePTAX}, nil
+}
+
+// BanxicoProvider fetches the MXN FIX rate from Banco de México's SIE API, which
+// requires a free API token
+type BanxicoProvider struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+}
+
+// NewBanxicoProvider creates a BanxicoProvider authenticated with token
+func NewBanxicoProvider(token string) *BanxicoProvider {
+	return &BanxicoProvider{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		baseURL:    "https://www.banxico.org.mx/SieAPIRest/service/v1",
+		token:      token,
+	}
+}
+
+// banxicoResponse is the SIE payload for a series' latest observation
+type banxicoResponse struct {
+	BMX struct {
+		Series []struct {
+			ID   string `json:"idSerie"`
+			Data []struct {
+				Date  string `json:"fecha"`
+				Value string `json:"dato"`
+			} `json:"datos"`
+		} `json:"series"`
+	} `json:"bmx"`
+}
+
+// USDRate returns the latest FIX rate (series SF43718)
+func (p *BanxicoProvider) USDRate(ctx context.Context, currency string) (*models.FXRate, error) {
+	if currency != "MXN" {
+		return nil, fmt.Errorf("Banxico only quotes MXN, not %s", currency)
+	}
+
+	body, err := fetchFXBody(ctx, p.httpClient, p.baseURL+"/series/SF43718/datos/oportuno", map[string]string{"Bmx-Token": p.token})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Banxico FIX rate: %w", err)
+	}
+
+	var payload banxicoResponse
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to decode Banxico response: %w", err)
+	}
+	if len(payload.BMX.Series) == 0 || len(payload.BMX.Series[0].Data) == 0 {
+		return nil, fmt.Errorf("Banxico returned no FIX rate")
+	}
+
+	observation := payload.BMX.Series[0].Data[0]
+	rate, err := strconv.ParseFloat(strings.ReplaceAll(observation.Value, ",", ""), 64)
+	if err != nil || rate <= 0 {
+		return nil, fmt.Errorf("invalid Banxico FIX rate %q", observation.Value)
+	}
+	date, err := time.Parse("02/01/2006", observation.Date)
+	if err != nil {
+		date = time.Now()
+	}
+	return &models.FXRate{Base: "USD", Quote: "MXN", Rate: rate, Date: date, Source: FXSourceBanxico}, nil
+}
+
+// TRMProvider fetches Colombia's official TRM from the datos.gov.co open data API
+type TRMProvider struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewTRMProvider creates a TRMProvider for the public dataset
+func NewTRMProvider() *TRMProvider {
+	return &TRMProvider{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		baseURL:    "https://www.datos.gov.co/resource/32sa-8pi3.json",
+	}
+}
+
+// trmRecord is one row of the TRM dataset
+type trmRecord struct {
+	Value     string `json:"valor"`
+	ValidFrom string `json:"vigenciadesde"`
+}
+
+// USDRate returns the TRM in force today
+func (p *TRMProvider) USDRate(ctx context.Context, currency string) (*models.FXRate, error) {
+	if currency != "COP" {
+		return nil, fmt.Errorf("TRM only quotes COP, not %s", currency)
+	}
+
+	body, err := fetchFXBody(ctx, p.httpClient, p.baseURL+"?$order=vigenciadesde%20DESC&$limit=1", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch TRM: %w", err)
+	}
+
+	var records []trmRecord
+	if err := json.Unmarshal(body, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode TRM response: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("TRM dataset returned no rate")
+	}
+
+	rate, err := strconv.ParseFloat(records[0].Value, 64)
+	if err != nil || rate <= 0 {
+		return nil, fmt.Errorf("invalid TRM %q", records[0].Value)
+	}
+	date, err := time.Parse("2006-01-02T15:04:05.000", records[0].ValidFrom)
+	if err != nil {
+		date = time.Now()
+	}
+	return &models.FXRate{Base: "USD", Quote: "COP", Rate: rate, Date: date, Source: FXSourceTRM}, nil
+}
+
+// fetchFXBody performs a GET bound to ctx and returns the body of a 200 response
+func fetchFXBody(ctx context.Context, client *http.Client, url string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// FXService resolves USD rates through one provider per currency, falls back to the
+// local stand-in when a source is down, and caches what it fetched
+type FXService struct {
+	providers map[string]FXRateProvider
+	fallback  FXRateProvider
+
+	mu    sync.Mutex
+	cache map[string]cachedFXRate
+}
+
+// cachedFXRate is a rate and when it was fetched
+type cachedFXRate struct {
+	rate      models.FXRate
+	fetchedAt time.Time
+}
+
+// NewFXService creates an FXService. Currencies without a provider, or whose provider
+// fails, are quoted by fallback.
+func NewFXService(providers map[string]FXRateProvider, fallback FXRateProvider) *FXService {
+	return &FXService{
+		providers: providers,
+		fallback:  fallback,
+		cache:     make(map[string]cachedFXRate),
+	}
+}
+
+// NewLocalFXService creates an FXService that only uses DefaultLocalFXRates
+func NewLocalFXService() *FXService {
+	return NewFXService(nil, NewLocalFXProvider(DefaultLocalFXRates))
+}
+
+// NewLiveFXService creates an FXService backed by PTAX for BRL, the TRM for COP and,
+// when banxicoToken is set, Banxico FIX for MXN
+func NewLiveFXService(banxicoToken string) *FXService {
+	providers := map[string]FXRateProvider{
+		"BRL": NewPTAXProvider(),
+		"COP": NewTRMProvider(),
+	}
+	if banxicoToken != "" {
+		providers["MXN"] = NewBanxicoProvider(banxicoToken)
+	}
+	return NewFXService(providers, NewLocalFXProvider(DefaultLocalFXRates))
+}
+
+// sharedFXService is used by summaries and market data; see ConfigureFX
+var sharedFXService atomic.Pointer[FXService]
+
+// defaultFXService is used until ConfigureFX is called
+var defaultFXService = NewLiveFXService("")
+
+// ConfigureFX replaces the FX service used across the application
+func ConfigureFX(fx *FXService) {
+	sharedFXService.Store(fx)
+}
+
+// SharedFXService returns the FX service configured with ConfigureFX
+func SharedFXService() *FXService {
+	if fx := sharedFXService.Load(); fx != nil {
+		return fx
+	}
+	return defaultFXService
+}
+
+// USDRate returns how many units of currency one US dollar buys
+func (fx *FXService) USDRate(ctx context.Context, currency string) (*models.FXRate, error) {
+	currency = strings.ToUpper(currency)
+	if currency == "USD" {
+		return &models.FXRate{Base: "USD", Quote: "USD", Rate: 1, Date: time.Now(), Source: FXSourceLocal}, nil
+	}
+
+	fx.mu.Lock()
+	cached, ok := fx.cache[currency]
+	fx.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < fxCacheTTL {
+		return &cached.rate, nil
+	}
+
+	var rate *models.FXRate
+	if provider, ok := fx.providers[currency]; ok {
+		var err error
+		rate, err = provider.USDRate(ctx, currency)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			fmt.Printf("⚠️ FX rate for %s unavailable, using local stand-in: %v\n", currency, err)
+			rate = nil
+		}
+	}
+	if rate == nil {
+		if fx.fallback == nil {
+			return nil, fmt.Errorf("no FX rate for %s", currency)
+		}
+		var err error
+		if rate, err = fx.fallback.USDRate(ctx, currency); err != nil {
+			return nil, err
+		}
+	}
+
+	fx.mu.Lock()
+	fx.cache[currency] = cachedFXRate{rate: *rate, fetchedAt: time.Now()}
+	fx.mu.Unlock()
+	return rate, nil
+}
+
+// Rates resolves the USD rate of every currency into a table for offline conversion.
+// Currencies no source can quote are left out and their amounts stay unconverted.
+func (fx *FXService) Rates(ctx context.Context, currencies ...string) (FXTable, error) {
+	table := make(FXTable)
+	for _, currency := range currencies {
+		currency = strings.ToUpper(currency)
+		if currency == "" {
+			continue
+		}
+		if _, ok := table[currency]; ok {
+			continue
+		}
+		rate, err := fx.USDRate(ctx, currency)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			fmt.Printf("⚠️ No FX rate for %s: %v\n", currency, err)
+			continue
+		}
+		table[currency] = *rate
+	}
+	return table, nil
+}
+
+// Convert converts amount from one currency to another at the current rate
+func (fx *FXService) Convert(ctx context.Context, amount float64, from, to string) (float64, error) {
+	table, err := fx.Rates(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+	converted, ok := table.Convert(amount, from, to)
+	if !ok {
+		return 0, fmt.Errorf("no FX rate from %s to %s", from, to)
+	}
+	return converted, nil
+}
+
+// FXTable holds USD rates by currency so amounts can be converted without I/O
+type FXTable map[string]models.FXRate
+
+// Rate returns the rate from one currency to another, crossing through the US dollar
+func (t FXTable) Rate(from, to string) (models.FXRate, bool) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return models.FXRate{Base: from, Quote: to, Rate: 1, Date: time.Now(), Source: FXSourceLocal}, true
+	}
+
+	fromRate, ok := t.usdRate(from)
+	if !ok {
+		return models.FXRate{}, false
+	}
+	toRate, ok := t.usdRate(to)
+	if !ok {
+		return models.FXRate{}, false
+	}
+
+	rate := models.FXRate{Base: from, Quote: to, Rate: toRate.Rate / fromRate.Rate, Date: toRate.Date, Source: toRate.Source}
+	switch {
+	case to == "USD":
+		rate.Date, rate.Source = fromRate.Date, fromRate.Source
+	case from != "USD":
+		if fromRate.Date.Before(rate.Date) {
+			rate.Date = fromRate.Date
+		}
+		if fromRate.Source != toRate.Source {
+			rate.Source = fromRate.Source + "/" + toRate.Source
+		}
+	}
+	return rate, true
+}
+
+// Convert converts amount from one currency to another, reporting false when either
+// currency has no rate
+func (t FXTable) Convert(amount float64, from, to string) (float64, bool) {
+	rate, ok := t.Rate(from, to)
+	if !ok {
+		return amount, false
+	}
+	return amount * rate.Rate, true
+}
+
+func (t FXTable) usdRate(currency string) (models.FXRate, bool) {
+	if currency == "USD" {
+		return models.FXRate{Base: "USD", Quote: "USD", Rate: 1, Source: FXSourceLocal}, true
+	}
+	rate, ok := t[currency]
+	return rate, ok
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// DefaultInstitutionCacheTTL is how long the institution list is reused; Belvo adds
+// institutions rarely, and their status is refreshed often enough at this pace
+const DefaultInstitutionCacheTTL = 6 * time.Hour
+
+// InstitutionFilter narrows the institution catalog. Zero values don't filter.
+type InstitutionFilter struct {
+	Country  string // ISO country code, e.g. BR
+	Type     string // bank, fiscal or employment
+	Resource string // A resource the institution must support, e.g. TRANSACTIONS
+	Status   string // healthy or down
+	Query    string // Searched in the display name and name, ignoring case and accents
+}
+
+// Matches reports whether an institution passes the filter
+func (f InstitutionFilter) Matches(institution models.BelvoInstitution) bool {
+	if f.Country != "" && !institutionInCountry(institution, f.Country) {
+		return false
+	}
+	if f.Type != "" && !strings.EqualFold(institution.Type, f.Type) {
+		return false
+	}
+	if f.Resource != "" && !containsFold(institution.Resources, f.Resource) {
+		return false
+	}
+	if f.Status != "" && !strings.EqualFold(institution.Status, f.Status) {
+		return false
+	}
+	if query := foldSearch(f.Query); query != "" {
+		return strings.Contains(foldSearch(institution.DisplayName), query) || strings.Contains(foldSearch(institution.Name), query)
+	}
+	return true
+}
+
+// institutionCache holds the institution list of one Belvo account
+type institutionCache struct {
+	mu           sync.Mutex // Held while fetching, so concurrent misses make one request
+	institutions []models.BelvoInstitution
+	fetchedAt    time.Time
+}
+
+// institutionCatalog caches institution lists per Belvo host and secret ID, so clients
+// built per request for user-provided credentials share the cache too
+type institutionCatalog struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*institutionCache
+}
+
+var sharedInstitutionCatalog = &institutionCatalog{
+	ttl:     DefaultInstitutionCacheTTL,
+	entries: make(map[string]*institutionCache),
+}
+
+// ConfigureInstitutionCache sets how long institution lists are cached and drops the
+// lists cached so far. A zero or negative ttl disables caching.
+func ConfigureInstitutionCache(ttl time.Duration) {
+	sharedInstitutionCatalog.mu.Lock()
+	defer sharedInstitutionCatalog.mu.Unlock()
+
+	sharedInstitutionCatalog.ttl = ttl
+	sharedInstitutionCatalog.entries = make(map[string]*institutionCache)
+}
+
+// entry returns the cache of one Belvo account and the TTL in effect
+func (c *institutionCatalog) entry(key string) (*institutionCache, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &institutionCache{}
+		c.entries[key] = entry
+	}
+	return entry, c.ttl
+}
+
+// cachedInstitutions returns the institution list, fetching it from Belvo when the
+// cached copy is missing or older than the TTL
+func (bs *BelvoService) cachedInstitutions(ctx context.Context) ([]models.BelvoInstitution, time.Time, time.Duration, error) {
+	entry, ttl := sharedInstitutionCatalog.entry(bs.credentials.BaseURL + "|" + bs.credentials.SecretID)
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.institutions != nil && ttl > 0 && time.Since(entry.fetchedAt) < ttl {
+		return entry.institutions, entry.fetchedAt, ttl, nil
+	}
+
+	institutions, err := bs.IterateInstitutions(ctx, bs.pageOptions).All()
+	if err != nil {
+		return nil, time.Time{}, ttl, err
+	}
+	if institutions == nil {
+		institutions = []models.BelvoInstitution{}
+	}
+	entry.institutions = institutions
+	entry.fetchedAt = time.Now()
+	fmt.Printf("🏦 Cached %d institutions for %s\n", len(institutions), ttl)
+
+	return entry.institutions, entry.fetchedAt, ttl, nil
+}
+
+// GetInstitutionCatalog returns the cached institutions that match the filter, sorted
+// as Belvo lists them
+func (bs *BelvoService) GetInstitutionCatalog(ctx context.Context, filter InstitutionFilter) (*models.InstitutionCatalog, error) {
+	institutions, fetchedAt, ttl, err := bs.cachedInstitutions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get institutions: %w", err)
+	}
+
+	catalog := &models.InstitutionCatalog{
+		Institutions: []models.BelvoInstitution{},
+		Total:        len(institutions),
+		CachedAt:     fetchedAt,
+		ExpiresAt:    fetchedAt.Add(ttl),
+	}
+	for _, institution := range institutions {
+		if filter.Matches(institution) {
+			catalog.Institutions = append(catalog.Institutions, institution)
+		}
+	}
+	catalog.Count = len(catalog.Institutions)
+	return catalog, nil
+}
+
+// Ping checks the credentials with the smallest request Belvo accepts, one institution,
+// and returns how many institutions the account can see
+func (bs *BelvoService) Ping(ctx context.Context) (int, error) {
+	it := bs.IterateInstitutions(ctx, PageOptions{PageSize: 1, MaxPages: 1})
+	if _, ok := it.Next(); !ok && it.Err() != nil {
+		return 0, fmt.Errorf("failed to reach Belvo: %w", it.Err())
+	}
+	return it.TotalCount(), nil
+}
+
+// institutionInCountry checks both the current country_codes list and the older single code
+func institutionInCountry(institution models.BelvoInstitution, country string) bool {
+	return strings.EqualFold(institution.CountryCode, country) || containsFold(institution.CountryCodes, country)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// accentFolder strips the accents used in Portuguese and Spanish institution names
+var accentFolder = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
+
+// foldSearch lowercases and strips accents so "itau" finds "Itaú"
+func foldSearch(value string) string {
+	return accentFolder.Replace(strings.ToLower(strings.TrimSpace(value)))
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"ai-financial-coach/internal/models"
+)
+
+// GetInvestmentPortfolios retrieves the investment portfolios and their instruments for a link
+func (bs *BelvoService) GetInvestmentPortfolios(ctx context.Context, linkID string) ([]models.BelvoInvestmentPortfolio, error) {
+	endpoint := fmt.Sprintf("%s/investments/portfolios/", bs.credentials.BaseURL)
+
+	reqBodyBytes, err := json.Marshal(map[string]interface{}{
+		"link": linkID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(reqBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.SetBasicAuth(bs.credentials.SecretID, bs.credentials.SecretKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("accept", "application/json")
+
+	// POST is how Belvo reads these resources, so it is safe to retry
+	resp, err := bs.httpClient.Do(markIdempotentRead(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to retrieve investment portfolios: %w", NewBelvoAPIError(resp.StatusCode, body))
+	}
+
+	var portfolios []models.BelvoInvestmentPortfolio
+	if err := json.Unmarshal(body, &portfolios); err != nil {
+		return nil, fmt.Errorf("failed to decode investment portfolios response: %w", err)
+	}
+
+	return portfolios, nil
+}
+
+// holdingsFromPortfolios flattens portfolios into holdings and returns their total market value
+func holdingsFromPortfolios(portfolios []models.BelvoInvestmentPortfolio) ([]models.InvestmentHolding, float64) {
+	var holdings []models.InvestmentHolding
+	total := 0.0
+
+	for _, portfolio := range portfolios {
+		for _, instrument := range portfolio.Instruments {
+			marketValue := instrument.BalanceGross
+			if marketValue == 0 {
+				marketValue = instrument.Quantity * instrument.Price
+			}
+			if marketValue <= 0 {
+				continue
+			}
+
+			assetType, symbol := classifyInstrument(portfolio.Type, instrument)
+			currency := instrument.Currency
+			if currency == "" {
+				currency = portfolio.Currency
+			}
+
+			holdings = append(holdings, models.InvestmentHolding{
+				Symbol:         symbol,
+				Name:           instrument.Name,
+				Type:           assetType,
+				InstrumentType: instrument.Type,
+				PortfolioID:    portfolio.ID,
+				Quantity:       instrument.Quantity,
+				Price:          instrument.Price,
+				MarketValue:    marketValue,
+				Currency:       currency,
+			})
+			total += marketValue
+		}
+	}
+
+	return holdings, total
+}
+
+// classifyInstrument maps a Belvo instrument onto the asset types and symbols used by
+// models.DefaultPortfolioTemplates. B3 tickers get the ".SA" suffix the market data uses;
+// fixed income is bucketed as SELIC (government bonds) or CDI (bank-issued paper and funds).
+func classifyInstrument(portfolioType string, instrument models.BelvoInvestmentInstrument) (models.AssetType, string) {
+	instrumentType := strings.ToUpper(instrument.Type)
+	if instrumentType == "" {
+		instrumentType = strings.ToUpper(portfolioType)
+	}
+	code := strings.ToUpper(strings.TrimSpace(instrument.Code))
+	name := strings.ToUpper(instrument.Name)
+
+	switch instrumentType {
+	case "CRYPTO", "CRYPTOCURRENCY":
+		return models.AssetTypeCrypto, code
+	case "ETF":
+		return models.AssetTypeETF, b3Symbol(code)
+	case "STOCK", "EQUITY", "SHARE", "BDR", "REIT", "FII":
+		return models.AssetTypeEquity, b3Symbol(code)
+	}
+
+	if strings.Contains(name, "SELIC") || strings.Contains(name, "TESOURO") || strings.Contains(code, "SELIC") {
+		return models.AssetTypeFixedIncome, "SELIC"
+	}
+	return models.AssetTypeFixedIncome, "CDI"
+}
+
+// b3Symbol adds the Yahoo Finance suffix for B3 tickers such as BOVA11 or PETR4
+func b3Symbol(code string) string {
+	if code == "" || strings.Contains(code, ".") {
+		return code
+	}
+	return code + ".SA"
+}
@@ -0,0 +1,675 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// Default endpoints and models of the built-in providers
+const (
+	DefaultOpenAIBaseURL    = "https://api.openai.com/v1"
+	DefaultOpenAIModel      = "gpt-4o-mini"
+	DefaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+	DefaultAnthropicModel   = "claude-3-5-haiku-latest"
+	DefaultLocalLLMBaseURL  = "http://localhost:11434/v1" // Ollama; llama.cpp's server listens on :8080/v1
+	DefaultLocalLLMModel    = "llama3.1"
+
+	anthropicVersion = "2023-06-01"
+)
+
+// LLMProvider sends a chat completion to a language model. Requests and responses use
+// the OpenAI chat format, which providers with another API translate. Stream hands each
+// piece of the answer to onDelta as it is generated and returns the whole response.
+type LLMProvider interface {
+	Name() string
+	DefaultModel() string
+	Complete(ctx context.Context, request models.LLMRequest) (*models.LLMResponse, error)
+	Stream(ctx context.Context, request models.LLMRequest, onDelta func(string) error) (*models.LLMResponse, error)
+}
+
+// LLMUse names what a completion is for, so each use can have its own model settings
+type LLMUse string
+
+const (
+	LLMUseChat    LLMUse = "chat"
+	LLMUseSummary LLMUse = "summary"
+)
+
+// LLMSettings are the model parameters for one use. An empty model means the provider's default.
+type LLMSettings struct {
+	Model       string
+	Temperature float64
+	MaxTokens   int
+}
+
+// Default settings per use: chat answers stay short, summaries are a little more deterministic
+var (
+	DefaultChatLLMSettings    = LLMSettings{Temperature: 0.7, MaxTokens: 500}
+	DefaultSummaryLLMSettings = LLMSettings{Temperature: 0.5, MaxTokens: 500}
+)
+
+// LLMConfig is a provider together with the settings each use runs with
+type LLMConfig struct {
+	Provider LLMProvider
+	Chat     LLMSettings
+	Summary  LLMSettings
+}
+
+// NewLLMConfig creates a config with the default settings for every use
+func NewLLMConfig(provider LLMProvider) *LLMConfig {
+	return &LLMConfig{
+		Provider: provider,
+		Chat:     DefaultChatLLMSettings,
+		Summary:  DefaultSummaryLLMSettings,
+	}
+}
+
+// Settings returns the settings for a use, with the provider's default model filled in
+func (c *LLMConfig) Settings(use LLMUse) LLMSettings {
+	settings := c.Chat
+	if use == LLMUseSummary {
+		settings = c.Summary
+	}
+	if settings.Model == "" {
+		settings.Model = c.Provider.DefaultModel()
+	}
+	return settings
+}
+
+// Request builds a completion request for a use
+func (c *LLMConfig) Request(use LLMUse, messages []models.LLMMessage) models.LLMRequest {
+	settings := c.Settings(use)
+	return models.LLMRequest{
+		Model:       settings.Model,
+		Temperature: settings.Temperature,
+		MaxTokens:   settings.MaxTokens,
+		Messages:    messages,
+	}
+}
+
+// OpenAICompatibleProvider talks to OpenAI's chat completions API or any server that
+// implements it, such as Ollama, llama.cpp, vLLM or LM Studio
+type OpenAICompatibleProvider struct {
+	name         string
+	baseURL      string
+	apiKey       string
+	defaultModel string
+	httpClient   *http.Client
+}
+
+// NewOpenAIProvider creates a provider for api.openai.com
+func NewOpenAIProvider(apiKey string) *OpenAICompatibleProvider {
+	return NewOpenAICompatibleProvider("openai", DefaultOpenAIBaseURL, apiKey, DefaultOpenAIModel, 60*time.Second)
+}
+
+// NewLocalLLMProvider creates a provider for a self-hosted OpenAI-compatible server.
+// Most local servers don't check the API key, so it may be empty. Local inference is
+// slower, so requests get more time.
+func NewLocalLLMProvider(baseURL, apiKey, model string) *OpenAICompatibleProvider {
+	if baseURL == "" {
+		baseURL = DefaultLocalLLMBaseURL
+	}
+	if model == "" {
+		model = DefaultLocalLLMModel
+	}
+	return NewOpenAICompatibleProvider("local", baseURL, apiKey, model, 3*time.Minute)
+}
+
+// NewOpenAICompatibleProvider creates a provider for the chat completions API at baseURL
+func NewOpenAICompatibleProvider(name, baseURL, apiKey, defaultModel string, timeout time.Duration) *OpenAICompatibleProvider {
+	return &OpenAICompatibleProvider{
+		name:         name,
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		apiKey:       apiKey,
+		defaultModel: defaultModel,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Name returns the provider name used in logs
+func (p *OpenAICompatibleProvider) Name() string {
+	return p.name
+}
+
+// DefaultModel returns the model used when a use doesn't name one
+func (p *OpenAICompatibleProvider) DefaultModel() string {
+	return p.defaultModel
+}
+
+// Complete calls POST {baseURL}/chat/completions
+func (p *OpenAICompatibleProvider) Complete(ctx context.Context, request models.LLMRequest) (*models.LLMResponse, error) {
+	var response models.LLMResponse
+	if err := postLLM(ctx, p.httpClient, p.name, p.baseURL+"/chat/completions", p.headers(), request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// openAIStreamChunk is one server-sent event of a streaming chat completion
+type openAIStreamChunk struct {
+	ID      string `json:"id"`
+	Created int64  `json:"created"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content   string                `json:"content"`
+			ToolCalls []openAIToolCallDelta `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *models.LLMUsage `json:"usage"`
+}
+
+// openAIToolCallDelta is a piece of a tool call: the first one for an index carries the
+// ID and name, the following ones more of the arguments
+type openAIToolCallDelta struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// Stream calls POST {baseURL}/chat/completions with stream set. Servers that don't
+// support stream_options simply leave the usage empty.
+func (p *OpenAICompatibleProvider) Stream(ctx context.Context, request models.LLMRequest, onDelta func(string) error) (*models.LLMResponse, error) {
+	request.Stream = true
+	request.StreamOptions = &models.LLMStreamOptions{IncludeUsage: true}
+
+	resp, err := sendLLM(ctx, p.httpClient, p.name, p.baseURL+"/chat/completions", p.headers(), request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	response := &models.LLMResponse{Object: "chat.completion"}
+	var content strings.Builder
+	var toolCalls []models.LLMToolCall
+	finishReason := ""
+	err = readSSE(resp.Body, func(_, data string) error {
+		if data == "[DONE]" {
+			return errSSEDone
+		}
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		response.ID, response.Created, response.Model = chunk.ID, chunk.Created, chunk.Model
+		if chunk.Usage != nil {
+			response.Usage = *chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+			for _, delta := range choice.Delta.ToolCalls {
+				for len(toolCalls) <= delta.Index {
+					toolCalls = append(toolCalls, models.LLMToolCall{Type: "function"})
+				}
+				call := &toolCalls[delta.Index]
+				if delta.ID != "" {
+					call.ID = delta.ID
+				}
+				call.Function.Name += delta.Function.Name
+				call.Function.Arguments += delta.Function.Arguments
+			}
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			if err := onDelta(choice.Delta.Content); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s stream failed: %w", p.name, err)
+	}
+
+	response.Choices = []models.LLMChoice{{
+		Message:      models.LLMMessage{Role: "assistant", Content: content.String(), ToolCalls: toolCalls},
+		FinishReason: finishReason,
+	}}
+	return response, nil
+}
+
+// headers authenticates requests when an API key is set
+func (p *OpenAICompatibleProvider) headers() map[string]string {
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+	return headers
+}
+
+// AnthropicProvider talks to Anthropic's Messages API
+type AnthropicProvider struct {
+	baseURL      string
+	apiKey       string
+	defaultModel string
+	httpClient   *http.Client
+}
+
+// NewAnthropicProvider creates a provider for api.anthropic.com
+func NewAnthropicProvider(apiKey string) *AnthropicProvider {
+	return NewAnthropicProviderWithBaseURL(apiKey, DefaultAnthropicBaseURL)
+}
+
+// NewAnthropicProviderWithBaseURL creates a provider for the Messages API at baseURL,
+// e.g. behind a gateway
+func NewAnthropicProviderWithBaseURL(apiKey, baseURL string) *AnthropicProvider {
+	return &AnthropicProvider{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		apiKey:       apiKey,
+		defaultModel: DefaultAnthropicModel,
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+// Name returns the provider name used in logs
+func (p *AnthropicProvider) Name() string {
+	return "anthropic"
+}
+
+// DefaultModel returns the model used when a use doesn't name one
+func (p *AnthropicProvider) DefaultModel() string {
+	return p.defaultModel
+}
+
+// anthropicRequest is the body of POST /v1/messages
+type anthropicRequest struct {
+	Model       string               `json:"model"`
+	System      string               `json:"system,omitempty"`
+	Messages    []anthropicMessage   `json:"messages"`
+	MaxTokens   int                  `json:"max_tokens"`
+	Temperature float64              `json:"temperature"`
+	Stream      bool                 `json:"stream,omitempty"`
+	Tools       []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+// anthropicMessage is one turn of the conversation
+type anthropicMessage struct {
+	Role    string                  `json:"role"` // "user" or "assistant"
+	Content []anthropicContentBlock `json:"content"`
+}
+
+// anthropicContentBlock is a "text", "tool_use" or "tool_result" block
+type anthropicContentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`          // tool_use
+	Name      string          `json:"name,omitempty"`        // tool_use
+	Input     json.RawMessage `json:"input,omitempty"`       // tool_use
+	ToolUseID string          `json:"tool_use_id,omitempty"` // tool_result
+	Content   string          `json:"content,omitempty"`     // tool_result
+}
+
+// anthropicTool describes a tool; the schema is the same as OpenAI's parameters
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+// anthropicToolChoice is "auto" or "none"
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+}
+
+// anthropicUsage counts the tokens of a message
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicResponse is the part of a Messages API response we use
+type anthropicResponse struct {
+	ID         string                  `json:"id"`
+	Model      string                  `json:"model"`
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      anthropicUsage          `json:"usage"`
+}
+
+// anthropicStreamEvent is one server-sent event of a streaming message
+type anthropicStreamEvent struct {
+	Type         string                 `json:"type"`
+	Message      *anthropicResponse     `json:"message"`       // message_start
+	Index        int                    `json:"index"`         // content_block_start and content_block_delta
+	ContentBlock *anthropicContentBlock `json:"content_block"` // content_block_start
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`         // content_block_delta, text_delta
+		PartialJSON string `json:"partial_json"` // content_block_delta, input_json_delta
+		StopReason  string `json:"stop_reason"`  // message_delta
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage"` // message_delta
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Complete translates the request to the Messages API and the answer back
+func (p *AnthropicProvider) Complete(ctx context.Context, request models.LLMRequest) (*models.LLMResponse, error) {
+	var response anthropicResponse
+	if err := postLLM(ctx, p.httpClient, p.Name(), p.baseURL+"/messages", p.headers(), anthropicMessages(request), &response); err != nil {
+		return nil, err
+	}
+	return response.llmResponse(), nil
+}
+
+// Stream translates the request to a streaming Messages API call and relays the text
+// deltas. Tool calls arrive as pieces of JSON and are put together per content block.
+func (p *AnthropicProvider) Stream(ctx context.Context, request models.LLMRequest, onDelta func(string) error) (*models.LLMResponse, error) {
+	body := anthropicMessages(request)
+	body.Stream = true
+
+	resp, err := sendLLM(ctx, p.httpClient, p.Name(), p.baseURL+"/messages", p.headers(), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	response := anthropicResponse{}
+	inputs := map[int]*strings.Builder{}
+	block := func(index int) *anthropicContentBlock {
+		for len(response.Content) <= index {
+			response.Content = append(response.Content, anthropicContentBlock{Type: "text"})
+		}
+		return &response.Content[index]
+	}
+	err = readSSE(resp.Body, func(_, data string) error {
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("failed to decode stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				response.ID, response.Model, response.Usage = event.Message.ID, event.Message.Model, event.Message.Usage
+			}
+		case "content_block_start":
+			if event.ContentBlock != nil {
+				*block(event.Index) = *event.ContentBlock
+			}
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				if event.Delta.Text != "" {
+					block(event.Index).Text += event.Delta.Text
+					return onDelta(event.Delta.Text)
+				}
+			case "input_json_delta":
+				if inputs[event.Index] == nil {
+					inputs[event.Index] = &strings.Builder{}
+				}
+				inputs[event.Index].WriteString(event.Delta.PartialJSON)
+			}
+		case "message_delta":
+			response.StopReason = event.Delta.StopReason
+			if event.Usage != nil {
+				response.Usage.OutputTokens = event.Usage.OutputTokens
+			}
+		case "message_stop":
+			return errSSEDone
+		case "error":
+			if event.Error != nil {
+				return fmt.Errorf("%s: %s", event.Error.Type, event.Error.Message)
+			}
+			return fmt.Errorf("stream error")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("anthropic stream failed: %w", err)
+	}
+
+	for index, input := range inputs {
+		if input.Len() > 0 {
+			block(index).Input = json.RawMessage(input.String())
+		}
+	}
+	return response.llmResponse(), nil
+}
+
+// headers authenticates requests and pins the API version
+func (p *AnthropicProvider) headers() map[string]string {
+	return map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+}
+
+// llmResponse converts a Messages API answer to the OpenAI format
+func (r anthropicResponse) llmResponse() *models.LLMResponse {
+	var text strings.Builder
+	var toolCalls []models.LLMToolCall
+	for _, block := range r.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			arguments := "{}"
+			if len(block.Input) > 0 {
+				arguments = string(block.Input)
+			}
+			toolCalls = append(toolCalls, models.LLMToolCall{
+				ID:       block.ID,
+				Type:     "function",
+				Function: models.LLMFunctionCall{Name: block.Name, Arguments: arguments},
+			})
+		}
+	}
+
+	return &models.LLMResponse{
+		ID:      r.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   r.Model,
+		Choices: []models.LLMChoice{{
+			Message:      models.LLMMessage{Role: "assistant", Content: text.String(), ToolCalls: toolCalls},
+			FinishReason: anthropicFinishReason(r.StopReason),
+		}},
+		Usage: models.LLMUsage{
+			PromptTokens:     r.Usage.InputTokens,
+			CompletionTokens: r.Usage.OutputTokens,
+			TotalTokens:      r.Usage.InputTokens + r.Usage.OutputTokens,
+		},
+	}
+}
+
+// anthropicMessages moves system messages into the system prompt and merges consecutive
+// messages of the same role, since the Messages API requires alternating turns that
+// start with the user. Tool calls become tool_use blocks and tool answers tool_result
+// blocks of a user turn.
+func anthropicMessages(request models.LLMRequest) anthropicRequest {
+	body := anthropicRequest{
+		Model:       request.Model,
+		MaxTokens:   request.MaxTokens,
+		Temperature: math.Min(request.Temperature, 1), // Anthropic accepts 0-1, OpenAI 0-2
+	}
+
+	var system []string
+	for _, message := range request.Messages {
+		if message.Role == "system" {
+			system = append(system, message.Content)
+			continue
+		}
+
+		role, blocks := message.Role, []anthropicContentBlock{}
+		if role == "tool" {
+			role = "user"
+			blocks = append(blocks, anthropicContentBlock{Type: "tool_result", ToolUseID: message.ToolCallID, Content: message.Content})
+		} else if message.Content != "" {
+			blocks = append(blocks, anthropicContentBlock{Type: "text", Text: message.Content})
+		}
+		for _, call := range message.ToolCalls {
+			input := json.RawMessage(call.Function.Arguments)
+			if !json.Valid(input) {
+				input = json.RawMessage("{}")
+			}
+			blocks = append(blocks, anthropicContentBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: input})
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if len(body.Messages) == 0 && role != "user" {
+			continue // A leading assistant turn, e.g. a greeting, has nothing to answer
+		}
+		if last := len(body.Messages) - 1; last >= 0 && body.Messages[last].Role == role {
+			body.Messages[last].Content = append(body.Messages[last].Content, blocks...)
+			continue
+		}
+		body.Messages = append(body.Messages, anthropicMessage{Role: role, Content: blocks})
+	}
+	body.System = strings.Join(system, "\n\n")
+
+	for _, tool := range request.Tools {
+		body.Tools = append(body.Tools, anthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: tool.Function.Parameters,
+		})
+	}
+	if request.ToolChoice != "" && len(body.Tools) > 0 {
+		body.ToolChoice = &anthropicToolChoice{Type: request.ToolChoice}
+	}
+
+	if body.MaxTokens <= 0 {
+		body.MaxTokens = DefaultChatLLMSettings.MaxTokens // Required by the Messages API
+	}
+	return body
+}
+
+// anthropicFinishReason maps a stop reason to its OpenAI equivalent
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	default:
+		return stopReason
+	}
+}
+
+// postLLM posts a JSON request to a provider and decodes the JSON response
+func postLLM(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, payload, dest interface{}) error {
+	resp, err := sendLLM(ctx, client, provider, url, headers, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if err := json.Unmarshal(body, dest); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// sendLLM posts a JSON request to a provider and returns the response with its body
+// unread, or an error carrying the start of the body when the status isn't 200
+func sendLLM(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, payload interface{}) (*http.Response, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return nil, fmt.Errorf("%s API error: status %d: %s", provider, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// errSSEDone stops readSSE without an error
+var errSSEDone = errors.New("event stream done")
+
+// readSSE reads a server-sent event stream and calls fn with each event's name and
+// data until the stream ends, fn returns errSSEDone or fn fails
+func readSSE(body io.Reader, fn func(event, data string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	event := ""
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		err := fn(event, strings.Join(data, "\n"))
+		event, data = "", nil
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		var err error
+		switch {
+		case line == "":
+			err = dispatch()
+		case strings.HasPrefix(line, ":"):
+			// Comment, used as a keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		if errors.Is(err, errSSEDone) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read event stream: %w", err)
+	}
+	if err := dispatch(); err != nil && !errors.Is(err, errSSEDone) {
+		return err
+	}
+	return nil
+}
@@ -1,10 +1,12 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"ai-financial-coach/internal/models"
@@ -76,16 +78,16 @@ type BrazilianCentralBankResponse struct {
 }
 
 // GetAssetPerformance fetches and calculates performance metrics for an asset
-func (ms *MarketService) GetAssetPerformance(symbol string) (*models.AssetPerformance, error) {
+func (ms *MarketService) GetAssetPerformance(ctx context.Context, symbol string) (*models.AssetPerformance, error) {
 	asset := ms.getAssetInfo(symbol)
 
 	switch asset.Type {
 	case models.AssetTypeETF:
-		return ms.getETFPerformance(symbol)
+		return ms.getETFPerformance(ctx, symbol)
 	case models.AssetTypeCrypto:
-		return ms.getCryptoPerformance(symbol)
+		return ms.getCryptoPerformance(ctx, symbol)
 	case models.AssetTypeFixedIncome:
-		return ms.getFixedIncomePerformance(symbol)
+		return ms.getFixedIncomePerformance(ctx, symbol)
 	default:
 		return nil, fmt.Errorf("unsupported asset type: %s", asset.Type)
 	}
@@ -108,7 +110,7 @@ func (ms *MarketService) getAssetInfo(symbol string) models.Asset {
 }
 
 // getETFPerformance fetches ETF data from Yahoo Finance
-func (ms *MarketService) getETFPerformance(symbol string) (*models.AssetPerformance, error) {
+func (ms *MarketService) getETFPerformance(ctx context.Context, symbol string) (*models.AssetPerformance, error) {
 	// Fetch historical data (1 year)
 	endTime := time.Now().Unix()
 	startTime := time.Now().AddDate(-1, 0, 0).Unix()
@@ -116,7 +118,7 @@ func (ms *MarketService) getETFPerformance(symbol string) (*models.AssetPerforma
 	url := fmt.Sprintf("https://query1.finance.yahoo.com/v8/finance/chart/%s?period1=%d&period2=%d&interval=1d",
 		symbol, startTime, endTime)
 
-	resp, err := ms.httpClient.Get(url)
+	resp, err := ms.get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch Yahoo Finance data: %w", err)
 	}
@@ -181,11 +183,11 @@ func (ms *MarketService) getETFPerformance(symbol string) (*models.AssetPerforma
 }
 
 // getCryptoPerformance fetches crypto data from CoinGecko
-func (ms *MarketService) getCryptoPerformance(symbol string) (*models.AssetPerformance, error) {
+func (ms *MarketService) getCryptoPerformance(ctx context.Context, symbol string) (*models.AssetPerformance, error) {
 	// Get current price
 	url := "https://api.coingecko.com/api/v3/simple/price?ids=bitcoin&vs_currencies=usd,brl&include_24hr_change=true"
 
-	resp, err := ms.httpClient.Get(url)
+	resp, err := ms.get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch CoinGecko data: %w", err)
 	}
@@ -216,7 +218,7 @@ func (ms *MarketService) getCryptoPerformance(symbol string) (*models.AssetPerfo
 
 	// Get historical data for longer-term returns
 	historicalURL := "https://api.coingecko.com/api/v3/coins/bitcoin/market_chart?vs_currency=usd&days=365"
-	histResp, err := ms.httpClient.Get(historicalURL)
+	histResp, err := ms.get(ctx, historicalURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch historical data: %w", err)
 	}
@@ -270,8 +272,8 @@ func (ms *MarketService) getCryptoPerformance(symbol string) (*models.AssetPerfo
 }
 
 // getFixedIncomePerformance gets Brazilian fixed income rates
-func (ms *MarketService) getFixedIncomePerformance(symbol string) (*models.AssetPerformance, error) {
-	rates, err := ms.GetBrazilianRates()
+func (ms *MarketService) getFixedIncomePerformance(ctx context.Context, symbol string) (*models.AssetPerformance, error) {
+	rates, err := ms.GetBrazilianRates(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Brazilian rates: %w", err)
 	}
@@ -308,8 +310,17 @@ func (ms *MarketService) getFixedIncomePerformance(symbol string) (*models.Asset
 	}, nil
 }
 
+// get performs a GET request bound to ctx
+func (ms *MarketService) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	return ms.httpClient.Do(req)
+}
+
 // GetBrazilianRates fetches current Brazilian economic rates
-func (ms *MarketService) GetBrazilianRates() (*models.BrazilianRates, error) {
+func (ms *MarketService) GetBrazilianRates(ctx context.Context) (*models.BrazilianRates, error) {
 	// For now, we'll use approximated rates since the Central Bank API requires specific setup
 	// In production, you would integrate with: https://olinda.bcb.gov.br/olinda/servico/PTAX/versao/v1/odata/
 
@@ -408,13 +419,16 @@ func (ms *MarketService) calculateVolatility(prices []float64) float64 {
 }
 
 // GetMarketDataSummary aggregates all market data
-func (ms *MarketService) GetMarketDataSummary() (*models.MarketDataSummary, error) {
+func (ms *MarketService) GetMarketDataSummary(ctx context.Context) (*models.MarketDataSummary, error) {
 	var assets []models.AssetPerformance
 	var dataSources []string
 
 	// Fetch data for all default assets
 	for _, asset := range models.DefaultAssets {
-		performance, err := ms.GetAssetPerformance(asset.Symbol)
+		performance, err := ms.GetAssetPerformance(ctx, asset.Symbol)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		if err != nil {
 			// Log error but continue with other assets
 			continue
@@ -423,7 +437,7 @@ func (ms *MarketService) GetMarketDataSummary() (*models.MarketDataSummary, erro
 	}
 
 	// Get Brazilian rates
-	rates, err := ms.GetBrazilianRates()
+	rates, err := ms.GetBrazilianRates(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Brazilian rates: %w", err)
 	}
@@ -437,3 +451,41 @@ func (ms *MarketService) GetMarketDataSummary() (*models.MarketDataSummary, erro
 		DataSources:    dataSources,
 	}, nil
 }
+
+// ConvertMarketData returns a copy of data with asset prices in currency. Returns and
+// volatility are percentages and stay as they are; converted assets keep their
+// original price and currency.
+func (ms *MarketService) ConvertMarketData(ctx context.Context, data *models.MarketDataSummary, currency string) (*models.MarketDataSummary, error) {
+	currency = strings.ToUpper(currency)
+	currencies := []string{currency}
+	for _, asset := range data.Assets {
+		currencies = append(currencies, asset.Currency)
+	}
+	rates, err := SharedFXService().Rates(ctx, currencies...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get FX rates: %w", err)
+	}
+
+	converted := *data
+	converted.Currency = currency
+	converted.FXRates = nil
+	converted.Assets = make([]models.AssetPerformance, len(data.Assets))
+	used := make(map[string]bool)
+	for i, asset := range data.Assets {
+		if asset.Currency != "" && !strings.EqualFold(asset.Currency, currency) {
+			if rate, ok := rates.Rate(asset.Currency, currency); ok {
+				asset.OriginalCurrency = asset.Currency
+				asset.OriginalPrice = asset.CurrentPrice
+				asset.CurrentPrice *= rate.Rate
+				asset.PriceChange24h *= rate.Rate
+				asset.Currency = currency
+				if !used[rate.Base] {
+					used[rate.Base] = true
+					converted.FXRates = append(converted.FXRates, rate)
+				}
+			}
+		}
+		converted.Assets[i] = asset
+	}
+	return &converted, nil
+}
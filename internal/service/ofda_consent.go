@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// DefaultOFDAInstitution is the Open Finance Brazil sandbox institution
+const DefaultOFDAInstitution = "ofmockbank_br_retail"
+
+// OFDA widget features: a new consent and link, or renewing an existing consent
+const (
+	OFDAFeatureConsentCreation = "consent_link_creation"
+	OFDAFeatureConsentRenewal  = "consent_renewal"
+)
+
+// ofdaScopes are the token scopes the Open Finance widget needs to create and renew consents
+const ofdaScopes = "read_institutions,write_links,read_consents,write_consents,write_consent_callback,delete_consents"
+
+// ofdaWidgetURL is where the frontend opens the widget with the access token
+const ofdaWidgetURL = "https://widget.belvo.io/#/connect"
+
+// OFDAConsentValidity is how long an Open Finance Brazil consent lasts when Belvo doesn't
+// report an expiration date; the regulation caps data sharing consents at 12 months
+const OFDAConsentValidity = 12
+
+// DefaultOFDAPermissions are requested when the caller doesn't choose any
+var DefaultOFDAPermissions = []string{"REGISTER", "ACCOUNTS", "CREDIT_CARDS", "CREDIT_OPERATIONS"}
+
+// ofdaPermissions are the permission groups Belvo accepts for Open Finance Brazil.
+// REGISTER (the customer's identification data) is mandatory for every consent.
+var ofdaPermissions = map[string]bool{
+	"REGISTER":          true,
+	"ACCOUNTS":          true,
+	"CREDIT_CARDS":      true,
+	"CREDIT_OPERATIONS": true,
+	"INVESTMENTS":       true,
+	"EXCHANGES":         true,
+}
+
+// OFDACallbackURLs are where the widget sends the user after a success, an exit or an error event
+type OFDACallbackURLs struct {
+	Success string
+	Exit    string
+	Event   string
+}
+
+// OFDAWidgetRequest describes a widget token for creating a consent, or renewing one
+// when RenewConsentID is set
+type OFDAWidgetRequest struct {
+	Consent        models.OFDAConsentRequest
+	Callbacks      OFDACallbackURLs
+	RenewConsentID string
+}
+
+// ValidateOFDAConsentRequest normalizes the consent fields and rejects requests the
+// widget would refuse: invalid CPFs, missing names, unknown permissions or terms URLs
+// that aren't http(s)
+func ValidateOFDAConsentRequest(req models.OFDAConsentRequest) (models.OFDAConsentRequest, error) {
+	req.CustomerID = strings.TrimSpace(req.CustomerID)
+	if req.CustomerID == "" {
+		return req, fmt.Errorf("customer_id is required")
+	}
+
+	req.CPF = digitsOnly(req.CPF)
+	if !validCPF(req.CPF) {
+		return req, fmt.Errorf("cpf is not a valid CPF")
+	}
+
+	req.FullName = strings.TrimSpace(req.FullName)
+	if req.FullName == "" {
+		return req, fmt.Errorf("full_name is required")
+	}
+
+	terms, err := url.Parse(req.TermsURL)
+	if err != nil || (terms.Scheme != "http" && terms.Scheme != "https") || terms.Host == "" {
+		return req, fmt.Errorf("terms_and_conditions_url must be an http(s) URL")
+	}
+
+	if len(req.Permissions) == 0 {
+		req.Permissions = DefaultOFDAPermissions
+	}
+	permissions := []string{"REGISTER"}
+	for _, permission := range req.Permissions {
+		permission = strings.ToUpper(strings.TrimSpace(permission))
+		if !ofdaPermissions[permission] {
+			return req, fmt.Errorf("unknown Open Finance permission %s", permission)
+		}
+		if permission != "REGISTER" {
+			permissions = append(permissions, permission)
+		}
+	}
+	req.Permissions = permissions
+
+	if req.Institution == "" {
+		req.Institution = DefaultOFDAInstitution
+	}
+	if req.CompanyName == "" {
+		req.CompanyName = "AI Financial Coach"
+	}
+	return req, nil
+}
+
+// CreateOFDAWidgetToken generates an Open Finance widget token carrying the consent
+// fields Belvo requires. Validate the consent with ValidateOFDAConsentRequest first.
+func (bs *BelvoService) CreateOFDAWidgetToken(ctx context.Context, req OFDAWidgetRequest) (map[string]interface{}, error) {
+	consent := req.Consent
+	widget := map[string]interface{}{
+		"openfinance_feature": OFDAFeatureConsentCreation,
+		"branding": map[string]interface{}{
+			"company_name": consent.CompanyName,
+		},
+		"customer_id": consent.CustomerID,
+		"consent": map[string]interface{}{
+			"terms_and_conditions_url": consent.TermsURL,
+			"permissions":              consent.Permissions,
+			"identification_info": []map[string]interface{}{
+				{
+					"type":   "CPF",
+					"number": consent.CPF,
+					"name":   consent.FullName,
+				},
+			},
+		},
+		"callback_urls": map[string]interface{}{
+			"success": req.Callbacks.Success,
+			"exit":    req.Callbacks.Exit,
+			"event":   req.Callbacks.Event,
+		},
+	}
+	if req.RenewConsentID != "" {
+		widget["openfinance_feature"] = OFDAFeatureConsentRenewal
+		widget["consent_id"] = req.RenewConsentID
+	}
+
+	payload := map[string]interface{}{
+		"id":              bs.credentials.SecretID,
+		"password":        bs.credentials.SecretKey,
+		"scopes":          ofdaScopes,
+		"stale_in":        "365d",
+		"fetch_resources": []string{"ACCOUNTS", "TRANSACTIONS", "OWNERS"},
+		"widget":          widget,
+	}
+
+	fmt.Printf("🪪 Requesting %s widget token for customer %s\n", widget["openfinance_feature"], consent.CustomerID)
+	return bs.GenerateOFDAWidgetToken(ctx, payload)
+}
+
+// OFDAWidgetURL returns the URL that opens the widget with an access token
+func OFDAWidgetURL(token map[string]interface{}) string {
+	access, _ := token["access"].(string)
+	return fmt.Sprintf("%s?token=%s", ofdaWidgetURL, url.QueryEscape(access))
+}
+
+// IterateConsents returns an iterator over the Open Finance consents of a link
+func (bs *BelvoService) IterateConsents(ctx context.Context, linkID string, opts PageOptions) *PageIterator[models.BelvoConsent] {
+	return newPageIterator[models.BelvoConsent](ctx, bs, linkFilteredEndpoint("/api/consents/", linkID), opts)
+}
+
+// GetConsents retrieves the Open Finance consents of a link across all pages
+func (bs *BelvoService) GetConsents(ctx context.Context, linkID string) ([]models.BelvoConsent, error) {
+	consents, err := bs.IterateConsents(ctx, linkID, bs.pageOptions).All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consents: %w", err)
+	}
+
+	return consents, nil
+}
+
+// consentExpiry returns when a consent expires: Belvo's expiration date when it
+// reports one, otherwise OFDAConsentValidity months after it was granted
+func consentExpiry(belvo *models.BelvoConsent, grantedAt time.Time) (time.Time, string) {
+	if belvo != nil && belvo.ExpirationDate != nil && !belvo.ExpirationDate.IsZero() {
+		return *belvo.ExpirationDate, "belvo"
+	}
+	return grantedAt.AddDate(0, OFDAConsentValidity, 0), "estimated"
+}
+
+// digitsOnly strips the punctuation from a formatted document number
+func digitsOnly(value string) string {
+	var digits strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	return digits.String()
+}
+
+// validCPF checks a CPF's length and its two check digits
+func validCPF(cpf string) bool {
+	if len(cpf) != 11 || strings.Count(cpf, cpf[:1]) == 11 {
+		return false
+	}
+	for _, length := range []int{9, 10} {
+		sum := 0
+		for i := 0; i < length; i++ {
+			sum += int(cpf[i]-'0') * (length + 1 - i)
+		}
+		check := sum * 10 % 11 % 10
+		if check != int(cpf[length]-'0') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// PageOptions controls how paginated Belvo list endpoints are traversed
+type PageOptions struct {
+	PageSize int `json:"page_size"` // Results requested per page (Belvo caps this at 1000)
+	MaxPages int `json:"max_pages"` // Safety cap on pages fetched per listing
+}
+
+// DefaultPageOptions are used when a service has no explicit page options
+var DefaultPageOptions = PageOptions{
+	PageSize: 100,
+	MaxPages: 50,
+}
+
+const maxBelvoPageSize = 1000
+
+// ErrPageLimitReached reports a listing that has more pages than PageOptions.MaxPages
+var ErrPageLimitReached = errors.New("listing has more pages than the page limit")
+
+// normalize fills zero values with defaults and clamps the page size to Belvo's limit
+func (o PageOptions) normalize() PageOptions {
+	if o.PageSize <= 0 {
+		o.PageSize = DefaultPageOptions.PageSize
+	}
+	if o.PageSize > maxBelvoPageSize {
+		o.PageSize = maxBelvoPageSize
+	}
+	if o.MaxPages <= 0 {
+		o.MaxPages = DefaultPageOptions.MaxPages
+	}
+	return o
+}
+
+// belvoPage is the paginated envelope returned by Belvo list endpoints
+type belvoPage struct {
+	Count    int               `json:"count"`
+	Next     *string           `json:"next"`
+	Previous *string           `json:"previous"`
+	Results  []json.RawMessage `json:"results"`
+}
+
+// pageFetcher performs an authenticated request against an absolute Belvo URL
+type pageFetcher func(ctx context.Context, method, requestURL string, body []byte) (*http.Response, error)
+
+// PageIterator walks a paginated Belvo list endpoint following the "next" links
+type PageIterator[T any] struct {
+	ctx       context.Context
+	fetch     pageFetcher
+	origin    *url.URL // Scheme and host "next" links must stay on, since they get the credentials
+	nextURL   string
+	opts      PageOptions
+	pagesRead int
+	count     int
+	skipped   int
+	err       error
+	truncated bool
+}
+
+// newPageIterator creates an iterator for the given endpoint (relative to the base URL).
+// Every page request is bound to ctx.
+func newPageIterator[T any](ctx context.Context, bs *BelvoService, endpoint string, opts PageOptions) *PageIterator[T] {
+	opts = opts.normalize()
+
+	firstURL, err := url.Parse(bs.credentials.BaseURL + endpoint)
+	if err != nil {
+		return &PageIterator[T]{err: fmt.Errorf("invalid endpoint %s: %w", endpoint, err)}
+	}
+	query := firstURL.Query()
+	query.Set("page_size", strconv.Itoa(opts.PageSize))
+	firstURL.RawQuery = query.Encode()
+
+	return &PageIterator[T]{
+		ctx:     ctx,
+		fetch:   bs.makeRequestURL,
+		origin:  firstURL,
+		nextURL: firstURL.String(),
+		opts:    opts,
+	}
+}
+
+// Next fetches the next page of results. It returns false once there are no more
+// pages, the max-pages cap was reached, or an error occurred (check Err).
+func (it *PageIterator[T]) Next() ([]T, bool) {
+	if it.err != nil || it.nextURL == "" {
+		return nil, false
+	}
+	if it.pagesRead >= it.opts.MaxPages {
+		it.truncated = true
+		return nil, false
+	}
+
+	resp, err := it.fetch(it.ctx, "GET", it.nextURL, nil)
+	if err != nil {
+		it.err = fmt.Errorf("failed to fetch page %d: %w", it.pagesRead+1, err)
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		it.err = fmt.Errorf("failed to read response body: %w", err)
+		return nil, false
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		it.err = NewBelvoAPIError(resp.StatusCode, body)
+		return nil, false
+	}
+
+	var page belvoPage
+	if err := json.Unmarshal(body, &page); err != nil {
+		it.err = fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, false
+	}
+
+	it.pagesRead++
+	it.count = page.Count
+	it.nextURL = ""
+	if page.Next != nil && *page.Next != "" {
+		nextURL, err := it.followable(*page.Next)
+		if err != nil {
+			it.err = err
+			return nil, false
+		}
+		it.nextURL = nextURL
+	}
+
+	items := make([]T, 0, len(page.Results))
+	for _, raw := range page.Results {
+		if item, ok := it.decode(raw); ok {
+			items = append(items, item)
+		}
+	}
+
+	return items, true
+}
+
+// followable resolves a "next" link and checks it points at the Belvo API the iteration
+// started on. Requests carry the secret ID and key, so a link to any other host, from a
+// tampered or broken response, ends the iteration instead of being followed.
+func (it *PageIterator[T]) followable(next string) (string, error) {
+	nextURL, err := url.Parse(next)
+	if err != nil {
+		return "", fmt.Errorf("invalid next page link %q: %w", next, err)
+	}
+	nextURL = it.origin.ResolveReference(nextURL)
+	if nextURL.Scheme != it.origin.Scheme || nextURL.Host != it.origin.Host {
+		return "", fmt.Errorf("refusing to follow next page link to %s://%s, which is not the Belvo API at %s://%s",
+			nextURL.Scheme, nextURL.Host, it.origin.Scheme, it.origin.Host)
+	}
+	return nextURL.String(), nil
+}
+
+// Each streams every page to fn, stopping early if fn returns an error
+func (it *PageIterator[T]) Each(fn func(page []T) error) error {
+	for {
+		items, ok := it.Next()
+		if !ok {
+			return it.err
+		}
+		if err := fn(items); err != nil {
+			return err
+		}
+	}
+}
+
+// All collects the results of every page into a single slice. A listing cut off by the
+// max-pages cap is an error wrapping ErrPageLimitReached, returned with the results read.
+func (it *PageIterator[T]) All() ([]T, error) {
+	var all []T
+	err := it.Each(func(page []T) error {
+		all = append(all, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if it.truncated {
+		return all, fmt.Errorf("%w: read %d of %d results in %d pages", ErrPageLimitReached, len(all), it.count, it.pagesRead)
+	}
+	return all, nil
+}
+
+// Stream passes every result to fn, one page at a time, so only one page is ever held
+// in memory. Each page is read in full and its response closed before fn sees it: a
+// slow consumer, such as a client reading a streamed export, must not hold the
+// transport's per-secret concurrency slot. It stops early if fn returns an error.
+func (it *PageIterator[T]) Stream(fn func(item T) error) error {
+	return it.Each(func(page []T) error {
+		for _, item := range page {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// decode unmarshals one record. A record that doesn't match the model is skipped
+// instead of failing the whole page, but logged and counted (see Skipped).
+func (it *PageIterator[T]) decode(raw json.RawMessage) (T, bool) {
+	var item T
+	if err := json.Unmarshal(raw, &item); err != nil {
+		it.skipped++
+		fmt.Printf("⚠️ Skipping Belvo record that doesn't match %T: %v\n", item, err)
+		return item, false
+	}
+	return item, true
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *PageIterator[T]) Err() error {
+	return it.err
+}
+
+// PagesRead returns how many pages have been fetched so far
+func (it *PageIterator[T]) PagesRead() int {
+	return it.pagesRead
+}
+
+// TotalCount returns the total result count reported by Belvo
+func (it *PageIterator[T]) TotalCount() int {
+	return it.count
+}
+
+// Skipped returns how many records were dropped because they couldn't be decoded
+func (it *PageIterator[T]) Skipped() int {
+	return it.skipped
+}
+
+// Truncated reports whether iteration stopped because the max-pages cap was hit
+func (it *PageIterator[T]) Truncated() bool {
+	return it.truncated
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// DefaultSessionTTL is how long a session token is valid after login
+const DefaultSessionTTL = 8 * time.Hour
+
+// Session errors; all of them mean the caller has to log in again
+var (
+	ErrInvalidSessionToken = errors.New("invalid session token")
+	ErrSessionExpired      = errors.New("session expired")
+)
+
+// SessionManager issues signed, expiring session tokens and keeps each session's Belvo
+// secrets in memory encrypted with AES-GCM. Tokens carry only the session ID and expiry,
+// signed with HMAC-SHA256, so a leaked token never reveals the secrets.
+type SessionManager struct {
+	ttl        time.Duration
+	signingKey []byte
+	aead       cipher.AEAD
+
+	mu       sync.Mutex
+	sessions map[string]*storedSession
+}
+
+// storedSession is a session with its encrypted Belvo secrets
+type storedSession struct {
+	session models.Session
+	secrets []byte // nonce followed by the sealed secret ID and key
+}
+
+// sessionClaims is the signed part of a token
+type sessionClaims struct {
+	SessionID string `json:"sid"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// sessionSecrets is what gets encrypted
+type sessionSecrets struct {
+	SecretID  string `json:"secret_id"`
+	SecretKey string `json:"secret_key"`
+}
+
+// NewSessionManager derives the signing and encryption keys from secret. An empty
+// secret uses a random one, so sessions don't survive a restart.
+func NewSessionManager(secret string, ttl time.Duration) (*SessionManager, error) {
+	if secret == "" {
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			return nil, fmt.Errorf("failed to generate session secret: %w", err)
+		}
+		secret = hex.EncodeToString(random)
+	}
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+
+	signingKey := sha256.Sum256([]byte("session-signing:" + secret))
+	encryptionKey := sha256.Sum256([]byte("session-encryption:" + secret))
+
+	block, err := aes.NewCipher(encryptionKey[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session cipher: %w", err)
+	}
+
+	return &SessionManager{
+		ttl:        ttl,
+		signingKey: signingKey[:],
+		aead:       aead,
+		sessions:   make(map[string]*storedSession),
+	}, nil
+}
+
+// Create starts a session for credentials that were already checked against Belvo and
+// returns its token
+func (sm *SessionManager) Create(creds models.RequestCredentials) (string, *models.Session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+
+	now := time.Now()
+	session := models.Session{
+		ID:           hex.EncodeToString(id),
+		SecretIDHint: secretHint(creds.SecretID),
+		Environment:  creds.Environment,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(sm.ttl),
+	}
+
+	secrets, err := sm.seal(session.ID, sessionSecrets{SecretID: creds.SecretID, SecretKey: creds.SecretKey})
+	if err != nil {
+		return "", nil, err
+	}
+	token, err := sm.sign(sessionClaims{SessionID: session.ID, ExpiresAt: session.ExpiresAt.Unix()})
+	if err != nil {
+		return "", nil, err
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	for key, stored := range sm.sessions {
+		if now.After(stored.session.ExpiresAt) {
+			delete(sm.sessions, key)
+		}
+	}
+	sm.sessions[session.ID] = &storedSession{session: session, secrets: secrets}
+	return token, &session, nil
+}
+
+// Resolve verifies a token and returns the session with its decrypted credentials
+func (sm *SessionManager) Resolve(token string) (models.RequestCredentials, *models.Session, error) {
+	claims, err := sm.verify(token)
+	if err != nil {
+		return models.RequestCredentials{}, nil, err
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return models.RequestCredentials{}, nil, ErrSessionExpired
+	}
+
+	sm.mu.Lock()
+	stored, ok := sm.sessions[claims.SessionID]
+	sm.mu.Unlock()
+	if !ok {
+		// Logged out, pruned, or issued before a restart
+		return models.RequestCredentials{}, nil, ErrSessionExpired
+	}
+
+	var secrets sessionSecrets
+	if err := sm.open(stored.session.ID, stored.secrets, &secrets); err != nil {
+		return models.RequestCredentials{}, nil, err
+	}
+
+	session := stored.session
+	return models.RequestCredentials{
+		SecretID:    secrets.SecretID,
+		SecretKey:   secrets.SecretKey,
+		Environment: session.Environment,
+	}, &session, nil
+}
+
+// Revoke ends the session a token belongs to
+func (sm *SessionManager) Revoke(token string) error {
+	claims, err := sm.verify(token)
+	if err != nil {
+		return err
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	delete(sm.sessions, claims.SessionID)
+	return nil
+}
+
+// sign encodes claims as base64url(JSON) "." base64url(HMAC)
+func (sm *SessionManager) sign(claims sessionClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session token: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(sm.mac(encoded)), nil
+}
+
+// verify checks a token's signature and decodes its claims
+func (sm *SessionManager) verify(token string) (sessionClaims, error) {
+	var claims sessionClaims
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return claims, ErrInvalidSessionToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, sm.mac(encoded)) {
+		return claims, ErrInvalidSessionToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(payload, &claims) != nil || claims.SessionID == "" {
+		return claims, ErrInvalidSessionToken
+	}
+	return claims, nil
+}
+
+func (sm *SessionManager) mac(payload string) []byte {
+	h := hmac.New(sha256.New, sm.signingKey)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+// SealCredentials encrypts credentials with the session key for work that outlives the
+// request that brought them, such as a webhook-driven refresh or a consent renewal
+func (sm *SessionManager) SealCredentials(creds models.RequestCredentials) ([]byte, error) {
+	return sm.seal(storedCredentialsScope, creds)
+}
+
+// OpenCredentials decrypts credentials sealed by SealCredentials. It fails for
+// credentials sealed before a restart without SESSION_SECRET.
+func (sm *SessionManager) OpenCredentials(sealed []byte) (models.RequestCredentials, error) {
+	var creds models.RequestCredentials
+	err := sm.open(storedCredentialsScope, sealed, &creds)
+	return creds, err
+}
+
+// storedCredentialsScope binds SealCredentials output so it can't pass for a session's secrets
+const storedCredentialsScope = "stored-credentials"
+
+// seal encrypts value, bound to scope (a session ID) so it can't be moved to another one
+func (sm *SessionManager) seal(scope string, value interface{}) ([]byte, error) {
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode secrets: %w", err)
+	}
+	nonce := make([]byte, sm.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return sm.aead.Seal(nonce, nonce, plaintext, []byte(scope)), nil
+}
+
+func (sm *SessionManager) open(scope string, sealed []byte, value interface{}) error {
+	size := sm.aead.NonceSize()
+	if len(sealed) < size {
+		return fmt.Errorf("failed to decrypt secrets: too short")
+	}
+	plaintext, err := sm.aead.Open(nil, sealed[:size], sealed[size:], []byte(scope))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt secrets: %w", err)
+	}
+	if err := json.Unmarshal(plaintext, value); err != nil {
+		return fmt.Errorf("failed to decode secrets: %w", err)
+	}
+	return nil
+}
+
+// secretHint shows enough of a secret ID to recognize it, as the startup log does
+func secretHint(secretID string) string {
+	if len(secretID) <= 8 {
+		return "***"
+	}
+	return secretID[:8] + "..."
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"ai-financial-coach/internal/models"
+)
+
+// defaultReportingCurrency is the currency of the first account, as summaries reported
+// before a reporting currency could be chosen, or BRL without accounts
+func defaultReportingCurrency(accounts []models.BelvoAccount) string {
+	if len(accounts) > 0 && accounts[0].Currency != "" {
+		return strings.ToUpper(accounts[0].Currency)
+	}
+	return "BRL"
+}
+
+// currencyConverter converts amounts into a summary's reporting currency and remembers
+// which rates it used
+type currencyConverter struct {
+	rates   FXTable
+	to      string
+	used    map[string]models.FXRate
+	missing map[string]bool
+}
+
+func newCurrencyConverter(rates FXTable, to string) *currencyConverter {
+	return &currencyConverter{
+		rates:   rates,
+		to:      to,
+		used:    make(map[string]models.FXRate),
+		missing: make(map[string]bool),
+	}
+}
+
+// convert returns amount in the reporting currency. Amounts without a currency are
+// taken to be in it already; amounts with no known rate are left as they are.
+func (c *currencyConverter) convert(amount float64, currency string) float64 {
+	currency = strings.ToUpper(currency)
+	if currency == "" || currency == c.to || amount == 0 {
+		return amount
+	}
+	rate, ok := c.rates.Rate(currency, c.to)
+	if !ok {
+		if !c.missing[currency] {
+			c.missing[currency] = true
+			fmt.Printf("⚠️ No FX rate from %s to %s, amounts left unconverted\n", currency, c.to)
+		}
+		return amount
+	}
+	c.used[currency] = rate
+	return amount * rate.Rate
+}
+
+// converts reports whether amounts in currency end up in the reporting currency
+func (c *currencyConverter) converts(currency string) bool {
+	currency = strings.ToUpper(currency)
+	if currency == "" || currency == c.to {
+		return true
+	}
+	_, ok := c.rates.Rate(currency, c.to)
+	return ok
+}
+
+// currency is the currency a converted amount is in
+func (c *currencyConverter) currency(original string) string {
+	if c.converts(original) {
+		return c.to
+	}
+	return original
+}
+
+// usedRates lists the rates applied so far, by source currency
+func (c *currencyConverter) usedRates() []models.FXRate {
+	if len(c.used) == 0 {
+		return nil
+	}
+	rates := make([]models.FXRate, 0, len(c.used))
+	for _, rate := range c.used {
+		rates = append(rates, rate)
+	}
+	sort.Slice(rates, func(a, b int) bool { return rates[a].Base < rates[b].Base })
+	return rates
+}
+
+// converter returns the input's currency converter, creating it on first use
+func (input *SummaryInput) converter() *currencyConverter {
+	if input.fx == nil {
+		input.fx = newCurrencyConverter(input.FXRates, input.ReportingCurrency)
+	}
+	return input.fx
+}
+
+// CurrencyCalculator converts the raw amounts every later calculator reads into the
+// reporting currency. The summary keeps the accounts, transactions, incomes, recurring
+// expenses and loans as Belvo returned them, in their original currencies, for display.
+// Investments are converted by InvestmentCalculator so holdings keep their originals.
+type CurrencyCalculator struct{}
+
+// Calculate converts the input and sets FXRates
+func (CurrencyCalculator) Calculate(input *SummaryInput, summary *models.FinancialSummary) {
+	c := input.converter()
+
+	accounts := make([]models.BelvoAccount, len(input.Accounts))
+	for i, account := range input.Accounts {
+		currency := account.Currency
+		account.Balance.Current = c.convert(account.Balance.Current, currency)
+		account.Balance.Available = c.convert(account.Balance.Available, currency)
+		if account.CreditData != nil {
+			credit := *account.CreditData
+			credit.CreditLimit = c.convert(credit.CreditLimit, currency)
+			credit.MinimumPayment = c.convert(credit.MinimumPayment, currency)
+			credit.NoInterestPayment = c.convert(credit.NoInterestPayment, currency)
+			credit.LastPeriodBalance = c.convert(credit.LastPeriodBalance, currency)
+			account.CreditData = &credit
+		}
+		account.Currency = c.currency(currency)
+		accounts[i] = account
+	}
+	input.Accounts = accounts
+
+	transactions := make([]models.BelvoTransaction, len(input.Transactions))
+	for i, transaction := range input.Transactions {
+		currency := transaction.Currency
+		transaction.Amount = c.convert(transaction.Amount, currency)
+		transaction.Balance = c.convert(transaction.Balance, currency)
+		transaction.Currency = c.currency(currency)
+		transactions[i] = transaction
+	}
+	input.Transactions = transactions
+
+	incomes := make([]models.BelvoIncome, len(input.Incomes))
+	for i, income := range input.Incomes {
+		income.MonthlyAverage = c.convert(income.MonthlyAverage, income.Currency)
+		income.Currency = c.currency(income.Currency)
+		incomes[i] = income
+	}
+	input.Incomes = incomes
+
+	expenses := make([]models.BelvoRecurringExpense, len(input.RecurringExpenses))
+	for i, expense := range input.RecurringExpenses {
+		currency := expense.Currency
+		expense.AverageTransactionAmount = c.convert(expense.AverageTransactionAmount, currency)
+		expense.MedianTransactionAmount = c.convert(expense.MedianTransactionAmount, currency)
+		expense.TransactionsMeanAmount = c.convert(expense.TransactionsMeanAmount, currency)
+		expense.Currency = c.currency(currency)
+		expenses[i] = expense
+	}
+	input.RecurringExpenses = expenses
+
+	loans := make([]models.BelvoLoan, len(input.Loans))
+	for i, loan := range input.Loans {
+		currency := loan.Currency
+		loan.ContractAmount = c.convert(loan.ContractAmount, currency)
+		loan.OutstandingPrincipal = c.convert(loan.OutstandingPrincipal, currency)
+		loan.MonthlyPayment = c.convert(loan.MonthlyPayment, currency)
+		loan.Currency = c.currency(currency)
+		loans[i] = loan
+	}
+	input.Loans = loans
+
+	summary.FXRates = c.usedRates()
+}
+
+// convertHoldings converts holdings into the reporting currency, keeping the original
+// market value of any held in another currency
+func convertHoldings(c *currencyConverter, holdings []models.InvestmentHolding) ([]models.InvestmentHolding, float64) {
+	total := 0.0
+	for i, holding := range holdings {
+		if holding.Currency != "" && !strings.EqualFold(holding.Currency, c.to) && c.converts(holding.Currency) {
+			holdings[i].OriginalCurrency = holding.Currency
+			holdings[i].OriginalMarketValue = holding.MarketValue
+			holdings[i].Price = c.convert(holding.Price, holding.Currency)
+			holdings[i].MarketValue = c.convert(holding.MarketValue, holding.Currency)
+			holdings[i].Currency = c.to
+		}
+		total += holdings[i].MarketValue
+	}
+	return holdings, total
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// SummaryInput is the raw Belvo data a financial summary is calculated from
+type SummaryInput struct {
+	UserID            string
+	LookbackMonths    int
+	From              time.Time
+	To                time.Time
+	Accounts          []models.BelvoAccount
+	Transactions      []models.BelvoTransaction
+	Incomes           []models.BelvoIncome
+	RecurringExpenses []models.BelvoRecurringExpense
+	Investments       []models.BelvoInvestmentPortfolio
+	Loans             []models.BelvoLoan
+
+	// ReportingCurrency is what every amount is converted to; empty uses the first
+	// account's currency. FXRates must quote every other currency in the data.
+	ReportingCurrency string
+	FXRates           FXTable
+
+	fx *currencyConverter
+}
+
+// SummaryCalculator fills in part of a financial summary. Calculators run in order, so
+// each one may read the fields written by those before it.
+type SummaryCalculator interface {
+	Calculate(input *SummaryInput, summary *models.FinancialSummary)
+}
+
+// SummaryCalculatorFunc adapts a plain function to SummaryCalculator
+type SummaryCalculatorFunc func(input *SummaryInput, summary *models.FinancialSummary)
+
+// Calculate calls f
+func (f SummaryCalculatorFunc) Calculate(input *SummaryInput, summary *models.FinancialSummary) {
+	f(input, summary)
+}
+
+// SummaryEngine builds financial summaries by running a chain of calculators
+type SummaryEngine struct {
+	calculators []SummaryCalculator
+}
+
+// NewSummaryEngine creates an engine that runs calculators in the given order
+func NewSummaryEngine(calculators ...SummaryCalculator) *SummaryEngine {
+	return &SummaryEngine{calculators: calculators}
+}
+
+// DefaultSummaryEngine is the summary math shared by every endpoint
+var DefaultSummaryEngine = NewSummaryEngine(
+	CurrencyCalculator{},
+	BalanceTrendCalculator{},
+	TransferCalculator{},
+	PeriodCalculator{},
+	IncomeCalculator{},
+	FixedExpenseCalculator{},
+	VariableExpenseCalculator{},
+	InvestmentCalculator{},
+	BalanceCalculator{},
+	DebtCalculator{},
+	SurplusCalculator{},
+)
+
+// Build copies the raw data into a new summary and runs every calculator over it
+func (e *SummaryEngine) Build(input SummaryInput) *models.FinancialSummary {
+	if input.LookbackMonths <= 0 {
+		input.LookbackMonths = DefaultSummaryOptions.LookbackMonths
+	}
+	if input.To.IsZero() {
+		input.To = time.Now()
+	}
+	if input.From.IsZero() {
+		input.From = input.To.AddDate(0, -input.LookbackMonths, 0)
+	}
+
+	if input.ReportingCurrency == "" {
+		input.ReportingCurrency = defaultReportingCurrency(input.Accounts)
+	}
+	input.ReportingCurrency = strings.ToUpper(input.ReportingCurrency)
+	input.fx = nil
+
+	summary := &models.FinancialSummary{
+		UserID:             input.UserID,
+		GeneratedAt:        input.To,
+		Accounts:           input.Accounts,
+		RecentTransactions: input.Transactions,
+		IncomeStreams:      input.Incomes,
+		RecurringExpenses:  input.RecurringExpenses,
+		Currency:           input.ReportingCurrency,
+		Investments:        input.Investments,
+		Loans:              input.Loans,
+	}
+	if summary.RecurringExpenses == nil {
+		summary.RecurringExpenses = []models.BelvoRecurringExpense{}
+	}
+
+	for _, calculator := range e.calculators {
+		calculator.Calculate(&input, summary)
+	}
+	return summary
+}
+
+// BalanceTrendCalculator follows checking and savings balances across the window. It
+// must run before TransferCalculator: the running balances include the transfers.
+type BalanceTrendCalculator struct{}
+
+// Calculate sets EmergencyFundTrend
+func (BalanceTrendCalculator) Calculate(input *SummaryInput, summary *models.FinancialSummary) {
+	summary.EmergencyFundTrend = liquidBalanceTrend(input.Accounts, input.Transactions, input.From, input.To)
+}
+
+// TransferCalculator drops transfers between the user's own accounts from the
+// transactions every later calculator sees
+type TransferCalculator struct{}
+
+// Calculate sets RecentTransactions and TransfersExcluded
+func (TransferCalculator) Calculate(input *SummaryInput, summary *models.FinancialSummary) {
+	excluded := internalTransfers(input.Accounts, input.Transactions)
+	if len(excluded) == 0 {
+		return
+	}
+	fmt.Printf("🔁 Excluded %d internal transfer transactions\n", len(excluded))
+
+	// RecentTransactions may hold the unconverted originals, in the same order
+	input.Transactions = withoutIndexes(input.Transactions, excluded)
+	if len(summary.RecentTransactions) == len(excluded)+len(input.Transactions) {
+		summary.RecentTransactions = withoutIndexes(summary.RecentTransactions, excluded)
+	} else {
+		summary.RecentTransactions = input.Transactions
+	}
+	summary.TransfersExcluded = len(excluded)
+}
+
+// PeriodCalculator measures the history the transactions actually cover
+type PeriodCalculator struct{}
+
+// Calculate sets Period and MonthlyBreakdown
+func (PeriodCalculator) Calculate(input *SummaryInput, summary *models.FinancialSummary) {
+	summary.Period, summary.MonthlyBreakdown = AnalyzePeriod(input.Transactions, input.LookbackMonths, input.From, input.To)
+	fmt.Printf("📆 Averaging over %.2f months of data (%s confidence)\n", summary.Period.MonthsOfData, summary.Period.Confidence)
+}
+
+// IncomeCalculator uses Belvo's income streams, falling back to the average monthly
+// inflow when the institution reports none
+type IncomeCalculator struct{}
+
+// Calculate sets MonthlyIncome
+func (IncomeCalculator) Calculate(input *SummaryInput, summary *models.FinancialSummary) {
+	monthlyIncome := 0.0
+	for _, income := range input.Incomes {
+		monthlyIncome += income.MonthlyAverage
+	}
+
+	if monthlyIncome == 0 && len(input.Transactions) > 0 {
+		inflow, _ := transactionTotals(input.Transactions)
+		monthlyIncome = inflow / monthsOfData(input, summary)
+		fmt.Printf("✅ Using transaction-based income: %.2f\n", monthlyIncome)
+	}
+	summary.MonthlyIncome = monthlyIncome
+}
+
+// FixedExpenseCalculator counts recurring expenses as fixed costs
+type FixedExpenseCalculator struct{}
+
+// Calculate sets MonthlyFixedExpenses
+func (FixedExpenseCalculator) Calculate(input *SummaryInput, summary *models.FinancialSummary) {
+	fixed := 0.0
+	for _, expense := range input.RecurringExpenses {
+		fixed += expense.AverageTransactionAmount
+	}
+	summary.MonthlyFixedExpenses = fixed
+}
+
+// VariableExpenseCalculator is the average monthly outflow not already counted as a
+// fixed cost. Recurring expenses are paid from the same accounts, so their payments are
+// part of the outflows too. Must run after FixedExpenseCalculator.
+type VariableExpenseCalculator struct{}
+
+// Calculate sets MonthlyVariableExpenses
+func (VariableExpenseCalculator) Calculate(input *SummaryInput, summary *models.FinancialSummary) {
+	inflow, outflow := transactionTotals(input.Transactions)
+	fmt.Printf("💰 Total inflow: %.2f, Total outflow: %.2f\n", inflow, outflow)
+
+	if len(input.Transactions) == 0 {
+		summary.MonthlyVariableExpenses = 0
+		return
+	}
+	monthlyOutflow := outflow / monthsOfData(input, summary)
+	summary.MonthlyVariableExpenses = math.Max(0, monthlyOutflow-summary.MonthlyFixedExpenses)
+}
+
+// InvestmentCalculator flattens investment portfolios into holdings
+type InvestmentCalculator struct{}
+
+// Calculate sets Holdings and TotalInvested, in the reporting currency
+func (InvestmentCalculator) Calculate(input *SummaryInput, summary *models.FinancialSummary) {
+	holdings, _ := holdingsFromPortfolios(input.Investments)
+	summary.Holdings, summary.TotalInvested = convertHoldings(input.converter(), holdings)
+	summary.FXRates = input.converter().usedRates()
+}
+
+// BalanceCalculator classifies account balances by category. Must run after
+// InvestmentCalculator so holdings can stand in for investment account balances.
+type BalanceCalculator struct{}
+
+// Calculate sets TotalBalance, TotalLiabilities, AvailableCredit and NetWorth
+func (BalanceCalculator) Calculate(input *SummaryInput, summary *models.FinancialSummary) {
+	// Only checking and savings count as cash; card limits are credit, not balance
+	balances := SummarizeBalances(input.Accounts)
+	netWorth := balances.NetWorth
+	liabilities := balances.Liabilities
+
+	// Portfolios usually describe the same money as investment accounts, so use them
+	// instead of the account balances when the link exposes them
+	if summary.TotalInvested > 0 {
+		netWorth = roundCents(netWorth - balances.InvestmentAccounts + summary.TotalInvested)
+	}
+
+	// Loans without an account of their own aren't in the account balances yet
+	if extra := undescribedLoanPrincipal(input.Accounts, input.Loans); extra > 0 {
+		liabilities = roundCents(liabilities + extra)
+		netWorth = roundCents(netWorth - extra)
+	}
+
+	summary.TotalBalance = balances.LiquidAssets
+	summary.TotalLiabilities = liabilities
+	summary.AvailableCredit = balances.AvailableCredit
+	summary.NetWorth = netWorth
+}
+
+// DebtCalculator normalizes loans and credit card balances into debts
+type DebtCalculator struct{}
+
+// Calculate sets Debts
+func (DebtCalculator) Calculate(input *SummaryInput, summary *models.FinancialSummary) {
+	summary.Debts = debtsFromData(input.Accounts, input.Loans)
+}
+
+// SurplusCalculator is what is left of income after fixed and variable expenses
+type SurplusCalculator struct{}
+
+// Calculate sets MonthlySurplus
+func (SurplusCalculator) Calculate(input *SummaryInput, summary *models.FinancialSummary) {
+	summary.MonthlySurplus = summary.MonthlyIncome - summary.MonthlyFixedExpenses - summary.MonthlyVariableExpenses
+}
+
+// monthsOfData is the span monthly averages are divided by, from PeriodCalculator when it ran
+func monthsOfData(input *SummaryInput, summary *models.FinancialSummary) float64 {
+	if summary.Period != nil && summary.Period.MonthsOfData > 0 {
+		return summary.Period.MonthsOfData
+	}
+	return float64(input.LookbackMonths)
+}
+
+// transactionTotals sums inflows and outflows
+func transactionTotals(transactions []models.BelvoTransaction) (inflow, outflow float64) {
+	for _, transaction := range transactions {
+		switch transaction.Type {
+		case "INFLOW":
+			inflow += transaction.Amount
+		case "OUTFLOW":
+			outflow += transaction.Amount
+		}
+	}
+	return inflow, outflow
+}
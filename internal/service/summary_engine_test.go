@@ -0,0 +1,254 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// testWindowFrom and testWindowTo span exactly three months of daysPerMonth, so the
+// monthly averages divide by 3
+var (
+	testWindowFrom = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	testWindowTo   = testWindowFrom.Add(time.Duration(3 * daysPerMonth * float64(24*time.Hour)))
+)
+
+func testAccount(id, category, currency string, current float64) models.BelvoAccount {
+	return models.BelvoAccount{ID: id, Category: category, Currency: currency, Balance: models.BelvoBalance{Current: current}}
+}
+
+// testTransaction books a transaction day days into the test window
+func testTransaction(id, accountID string, day int, amount float64, kind, category, currency string) models.BelvoTransaction {
+	return models.BelvoTransaction{
+		ID:             id,
+		Account:        map[string]interface{}{"id": accountID},
+		AccountingDate: models.BelvoTime(testWindowFrom.AddDate(0, 0, day)),
+		Amount:         amount,
+		Currency:       currency,
+		Category:       category,
+		Type:           kind,
+	}
+}
+
+// summaryTotals are the figures each calculator is responsible for
+type summaryTotals struct {
+	MonthlyIncome           float64
+	MonthlyFixedExpenses    float64
+	MonthlyVariableExpenses float64
+	MonthlySurplus          float64
+	TotalBalance            float64
+	TotalLiabilities        float64
+	TotalInvested           float64
+	NetWorth                float64
+	TransfersExcluded       int
+	Debts                   int
+}
+
+func totalsOf(summary *models.FinancialSummary) summaryTotals {
+	return summaryTotals{
+		MonthlyIncome:           roundCents(summary.MonthlyIncome),
+		MonthlyFixedExpenses:    roundCents(summary.MonthlyFixedExpenses),
+		MonthlyVariableExpenses: roundCents(summary.MonthlyVariableExpenses),
+		MonthlySurplus:          roundCents(summary.MonthlySurplus),
+		TotalBalance:            roundCents(summary.TotalBalance),
+		TotalLiabilities:        roundCents(summary.TotalLiabilities),
+		TotalInvested:           roundCents(summary.TotalInvested),
+		NetWorth:                roundCents(summary.NetWorth),
+		TransfersExcluded:       summary.TransfersExcluded,
+		Debts:                   len(summary.Debts),
+	}
+}
+
+func TestSummaryEngineCalculators(t *testing.T) {
+	checking := testAccount("chk", "CHECKING_ACCOUNT", "BRL", 1000)
+
+	tests := []struct {
+		name  string
+		input SummaryInput
+		want  summaryTotals
+	}{
+		{
+			name: "no data",
+			input: SummaryInput{
+				Accounts: []models.BelvoAccount{checking},
+			},
+			want: summaryTotals{TotalBalance: 1000, NetWorth: 1000},
+		},
+		{
+			name: "transfers between own accounts are excluded",
+			input: SummaryInput{
+				Accounts: []models.BelvoAccount{checking, testAccount("sav", "SAVINGS_ACCOUNT", "BRL", 500)},
+				Transactions: []models.BelvoTransaction{
+					testTransaction("salary", "chk", 0, 3000, "INFLOW", "Income & Payments", "BRL"),
+					testTransaction("to-savings", "chk", 10, 600, "OUTFLOW", "Transfers", "BRL"),
+					testTransaction("from-checking", "sav", 11, 600, "INFLOW", "", "BRL"),
+					testTransaction("groceries", "chk", 20, 300, "OUTFLOW", "Food & Groceries", "BRL"),
+				},
+			},
+			want: summaryTotals{
+				MonthlyIncome: 1000, MonthlyVariableExpenses: 100, MonthlySurplus: 900,
+				TotalBalance: 1500, NetWorth: 1500, TransfersExcluded: 2,
+			},
+		},
+		{
+			name: "transfer legs too far apart are kept",
+			input: SummaryInput{
+				Accounts: []models.BelvoAccount{checking, testAccount("sav", "SAVINGS_ACCOUNT", "BRL", 500)},
+				Transactions: []models.BelvoTransaction{
+					testTransaction("to-savings", "chk", 0, 600, "OUTFLOW", "Transfers", "BRL"),
+					testTransaction("from-checking", "sav", 10, 600, "INFLOW", "Transfers", "BRL"),
+				},
+			},
+			want: summaryTotals{
+				MonthlyIncome: 200, MonthlyVariableExpenses: 200,
+				TotalBalance: 1500, NetWorth: 1500,
+			},
+		},
+		{
+			name: "income streams take precedence over inflows",
+			input: SummaryInput{
+				Accounts: []models.BelvoAccount{checking},
+				Incomes: []models.BelvoIncome{
+					{ID: "salary", MonthlyAverage: 4000, Currency: "BRL"},
+					{ID: "rent", MonthlyAverage: 500, Currency: "BRL"},
+				},
+				Transactions: []models.BelvoTransaction{
+					testTransaction("salary", "chk", 0, 9000, "INFLOW", "Income & Payments", "BRL"),
+				},
+			},
+			want: summaryTotals{MonthlyIncome: 4500, MonthlySurplus: 4500, TotalBalance: 1000, NetWorth: 1000},
+		},
+		{
+			name: "fixed and variable expenses",
+			input: SummaryInput{
+				Accounts:          []models.BelvoAccount{checking},
+				Incomes:           []models.BelvoIncome{{ID: "salary", MonthlyAverage: 5000, Currency: "BRL"}},
+				RecurringExpenses: []models.BelvoRecurringExpense{{ID: "rent", AverageTransactionAmount: 1200, Currency: "BRL"}},
+				Transactions: []models.BelvoTransaction{
+					testTransaction("rent-1", "chk", 0, 1200, "OUTFLOW", "Housing", "BRL"),
+					testTransaction("shopping", "chk", 40, 4800, "OUTFLOW", "Shopping", "BRL"),
+				},
+			},
+			want: summaryTotals{
+				MonthlyIncome: 5000, MonthlyFixedExpenses: 1200, MonthlyVariableExpenses: 800, MonthlySurplus: 3000,
+				TotalBalance: 1000, NetWorth: 1000,
+			},
+		},
+		{
+			name: "variable expenses are never negative",
+			input: SummaryInput{
+				Accounts:          []models.BelvoAccount{checking},
+				Incomes:           []models.BelvoIncome{{ID: "salary", MonthlyAverage: 3000, Currency: "BRL"}},
+				RecurringExpenses: []models.BelvoRecurringExpense{{ID: "rent", AverageTransactionAmount: 2500, Currency: "BRL"}},
+				Transactions: []models.BelvoTransaction{
+					testTransaction("rent-1", "chk", 0, 3000, "OUTFLOW", "Housing", "BRL"),
+				},
+			},
+			want: summaryTotals{
+				MonthlyIncome: 3000, MonthlyFixedExpenses: 2500, MonthlySurplus: 500,
+				TotalBalance: 1000, NetWorth: 1000,
+			},
+		},
+		{
+			name: "holdings replace investment account balances",
+			input: SummaryInput{
+				Accounts: []models.BelvoAccount{checking, testAccount("inv", "INVESTMENT_ACCOUNT", "BRL", 5000)},
+				Investments: []models.BelvoInvestmentPortfolio{{
+					ID: "portfolio", Type: "FIXED_INCOME", Currency: "BRL",
+					Instruments: []models.BelvoInvestmentInstrument{
+						{Type: "BOND", Name: "CDB", BalanceGross: 4000},
+						{Type: "ETF", Code: "BOVA11", Quantity: 20, Price: 100},
+					},
+				}},
+			},
+			want: summaryTotals{TotalBalance: 1000, TotalInvested: 6000, NetWorth: 7000},
+		},
+		{
+			name: "debts from cards and loans",
+			input: SummaryInput{
+				Accounts: []models.BelvoAccount{
+					checking,
+					{
+						ID: "card", Category: "CREDIT_CARD", Currency: "BRL",
+						Balance:    models.BelvoBalance{Current: -800, Available: 1200},
+						CreditData: &models.BelvoCreditData{NoInterestPayment: 500, InterestRate: 120},
+					},
+				},
+				Loans: []models.BelvoLoan{{ID: "car", Type: "VEHICLE", Currency: "BRL", OutstandingPrincipal: 10000, MonthlyPayment: 700}},
+			},
+			want: summaryTotals{TotalBalance: 1000, TotalLiabilities: 10800, NetWorth: -9800, Debts: 2},
+		},
+		{
+			name: "amounts in other currencies are converted",
+			input: SummaryInput{
+				ReportingCurrency: "BRL",
+				FXRates:           FXTable{"BRL": {Base: "USD", Quote: "BRL", Rate: 5}},
+				Accounts:          []models.BelvoAccount{checking, testAccount("usd", "SAVINGS_ACCOUNT", "USD", 200)},
+				Incomes:           []models.BelvoIncome{{ID: "salary", MonthlyAverage: 1000, Currency: "USD"}},
+				RecurringExpenses: []models.BelvoRecurringExpense{{ID: "rent", AverageTransactionAmount: 1000, Currency: "BRL"}},
+				Transactions: []models.BelvoTransaction{
+					testTransaction("rent-1", "chk", 0, 1000, "OUTFLOW", "Housing", "BRL"),
+					testTransaction("travel", "usd", 30, 700, "OUTFLOW", "Travel", "USD"),
+				},
+				Investments: []models.BelvoInvestmentPortfolio{{
+					ID: "portfolio", Type: "EQUITY", Currency: "USD",
+					Instruments: []models.BelvoInvestmentInstrument{{Type: "STOCK", Code: "AAPL", BalanceGross: 100}},
+				}},
+			},
+			want: summaryTotals{
+				MonthlyIncome: 5000, MonthlyFixedExpenses: 1000, MonthlyVariableExpenses: 500, MonthlySurplus: 3500,
+				TotalBalance: 2000, TotalInvested: 500, NetWorth: 2500,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := tt.input
+			input.UserID = "user"
+			input.LookbackMonths = 3
+			input.From, input.To = testWindowFrom, testWindowTo
+
+			summary := DefaultSummaryEngine.Build(input)
+			if len(input.Transactions) > 0 && math.Abs(summary.Period.MonthsOfData-3) > 0.01 {
+				t.Fatalf("Period = %+v, want 3 months of data", summary.Period)
+			}
+			if got := totalsOf(summary); got != tt.want {
+				t.Errorf("totals = %+v\nwant     %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryEngineKeepsOriginalCurrencies(t *testing.T) {
+	input := SummaryInput{
+		LookbackMonths:    3,
+		From:              testWindowFrom,
+		To:                testWindowTo,
+		ReportingCurrency: "brl",
+		FXRates:           FXTable{"BRL": {Base: "USD", Quote: "BRL", Rate: 5}},
+		Accounts:          []models.BelvoAccount{testAccount("usd", "CHECKING_ACCOUNT", "USD", 200)},
+		Transactions: []models.BelvoTransaction{
+			testTransaction("coffee", "usd", 5, 4, "OUTFLOW", "Food & Groceries", "USD"),
+		},
+	}
+
+	summary := DefaultSummaryEngine.Build(input)
+	if summary.Currency != "BRL" {
+		t.Errorf("Currency = %s, want BRL", summary.Currency)
+	}
+	if summary.TotalBalance != 1000 {
+		t.Errorf("TotalBalance = %.2f, want 1000", summary.TotalBalance)
+	}
+	if got := summary.Accounts[0]; got.Currency != "USD" || got.Balance.Current != 200 {
+		t.Errorf("Accounts[0] = %s %.2f, want the original USD 200", got.Currency, got.Balance.Current)
+	}
+	if got := summary.RecentTransactions[0]; got.Currency != "USD" || got.Amount != 4 {
+		t.Errorf("RecentTransactions[0] = %s %.2f, want the original USD 4", got.Currency, got.Amount)
+	}
+	if len(summary.FXRates) != 1 || summary.FXRates[0].Base != "USD" || summary.FXRates[0].Rate != 5 {
+		t.Errorf("FXRates = %+v, want the USD to BRL rate", summary.FXRates)
+	}
+}
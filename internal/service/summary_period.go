@@ -0,0 +1,138 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// SummaryOptions controls the window a financial summary averages over and the
+// currency it reports in
+type SummaryOptions struct {
+	LookbackMonths    int    // Months of transactions to look at; zero uses DefaultSummaryOptions
+	ReportingCurrency string // ISO code amounts are converted to; empty uses the first account's currency
+}
+
+// DefaultSummaryOptions look at the last three months
+var DefaultSummaryOptions = SummaryOptions{LookbackMonths: 3}
+
+// MaxLookbackMonths bounds the lookback window of a summary
+const MaxLookbackMonths = 24
+
+const (
+	// daysPerMonth converts a span of days into months
+	daysPerMonth = 365.25 / 12
+
+	// historyStartTolerance is how late the first transaction may fall after the start
+	// of the window while still counting as history that covers the whole window
+	historyStartTolerance = 7 * 24 * time.Hour
+
+	// minSpanDays keeps a few days of history from inflating the monthly averages
+	minSpanDays = 7
+
+	// minConfidentSpanDays is the shortest history that spans a whole billing cycle
+	minConfidentSpanDays = 28
+)
+
+// ValidateSummaryOptions fills in defaults and rejects windows outside 1..MaxLookbackMonths
+// and reporting currencies that can't be converted to
+func ValidateSummaryOptions(opts SummaryOptions) (SummaryOptions, error) {
+	opts.ReportingCurrency = strings.ToUpper(strings.TrimSpace(opts.ReportingCurrency))
+	if opts.ReportingCurrency != "" && !IsSupportedCurrency(opts.ReportingCurrency) {
+		return opts, fmt.Errorf("unsupported reporting currency %s", opts.ReportingCurrency)
+	}
+	if opts.LookbackMonths == 0 {
+		opts.LookbackMonths = DefaultSummaryOptions.LookbackMonths
+	}
+	if opts.LookbackMonths < 1 || opts.LookbackMonths > MaxLookbackMonths {
+		return opts, fmt.Errorf("lookback must be between 1 and %d months", MaxLookbackMonths)
+	}
+	return opts, nil
+}
+
+// summaryWindow returns the dates a summary with these options covers, ending at now
+func summaryWindow(opts SummaryOptions, now time.Time) (time.Time, time.Time) {
+	return now.AddDate(0, -opts.LookbackMonths, 0), now
+}
+
+// AnalyzePeriod measures how much of the window from..to the transactions actually
+// cover and totals them per calendar month. Monthly averages should be divided by the
+// returned MonthsOfData rather than the requested lookback.
+func AnalyzePeriod(transactions []models.BelvoTransaction, lookbackMonths int, from, to time.Time) (*models.SummaryPeriod, []models.MonthlyBreakdown) {
+	period := &models.SummaryPeriod{
+		LookbackMonths: lookbackMonths,
+		From:           from,
+		To:             to,
+		DataFrom:       to,
+		Transactions:   len(transactions),
+	}
+
+	for _, transaction := range transactions {
+		if date := transaction.AccountingDate.Time(); date.Before(period.DataFrom) {
+			period.DataFrom = date
+		}
+	}
+	if period.DataFrom.Before(from) || period.DataFrom.Sub(from) <= historyStartTolerance {
+		period.DataFrom = from
+	}
+
+	spanDays := to.Sub(period.DataFrom).Hours() / 24
+	if spanDays < minSpanDays {
+		spanDays = minSpanDays
+	}
+	period.MonthsOfData = roundCents(spanDays / daysPerMonth)
+
+	coverage := period.MonthsOfData / float64(lookbackMonths)
+	switch {
+	case len(transactions) == 0:
+		period.Confidence = "low"
+		period.ConfidenceNote = "No transactions in the requested window"
+	case spanDays < minConfidentSpanDays:
+		period.Confidence = "low"
+		period.ConfidenceNote = fmt.Sprintf("Only %.0f days of history; monthly figures are extrapolated", spanDays)
+	case coverage < 0.9:
+		period.Confidence = "medium"
+		period.ConfidenceNote = fmt.Sprintf("History covers %.1f of the %d months requested", period.MonthsOfData, lookbackMonths)
+	default:
+		period.Confidence = "high"
+	}
+
+	return period, monthlyBreakdown(transactions, period.DataFrom, to)
+}
+
+// monthlyBreakdown totals inflows and outflows per calendar month from from to to.
+// Months the window or the history only partly covers are flagged.
+func monthlyBreakdown(transactions []models.BelvoTransaction, from, to time.Time) []models.MonthlyBreakdown {
+	index := make(map[string]int)
+	var months []models.MonthlyBreakdown
+	for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location()); !month.After(to); month = month.AddDate(0, 1, 0) {
+		index[month.Format("2006-01")] = len(months)
+		months = append(months, models.MonthlyBreakdown{
+			Month:   month.Format("2006-01"),
+			Partial: month.Before(startOfDay(from)) || month.AddDate(0, 1, 0).After(to),
+		})
+	}
+
+	for _, transaction := range transactions {
+		i, ok := index[transaction.AccountingDate.Time().In(from.Location()).Format("2006-01")]
+		if !ok {
+			continue
+		}
+		switch transaction.Type {
+		case "INFLOW":
+			months[i].Income += transaction.Amount
+		case "OUTFLOW":
+			months[i].Expenses += transaction.Amount
+		}
+		months[i].Transactions++
+	}
+
+	for i := range months {
+		months[i].Income = roundCents(months[i].Income)
+		months[i].Expenses = roundCents(months[i].Expenses)
+		months[i].Net = roundCents(months[i].Income - months[i].Expenses)
+	}
+	return months
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"ai-financial-coach/internal/models"
+)
+
+// TransactionStore persists the synced transactions of each link. Scope separates the
+// histories of different Belvo credentials so one caller can't read another's links.
+type TransactionStore interface {
+	// Load returns the stored state, or nil when nothing was synced for the link yet
+	Load(scope, linkID string) (*models.TransactionSyncState, error)
+	Save(scope string, state *models.TransactionSyncState) error
+	Delete(scope, linkID string) error
+}
+
+// storeKeyPattern restricts scopes and link IDs to safe file names
+var storeKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// FileTransactionStore keeps one JSON file per link under dir/<scope>/<link_id>.json
+type FileTransactionStore struct {
+	dir string
+}
+
+// NewFileTransactionStore creates a file store rooted at dir, creating it if needed
+func NewFileTransactionStore(dir string) (*FileTransactionStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create transaction store directory: %w", err)
+	}
+	return &FileTransactionStore{dir: dir}, nil
+}
+
+// Load implements TransactionStore
+func (fs *FileTransactionStore) Load(scope, linkID string) (*models.TransactionSyncState, error) {
+	path, err := fs.path(scope, linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored transactions: %w", err)
+	}
+
+	var state models.TransactionSyncState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse stored transactions: %w", err)
+	}
+	return &state, nil
+}
+
+// Save implements TransactionStore. The file is replaced atomically so a crash
+// mid-write never leaves a truncated history behind.
+func (fs *FileTransactionStore) Save(scope string, state *models.TransactionSyncState) error {
+	path, err := fs.path(scope, state.LinkID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create transaction store directory: %w", err)
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transactions: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".sync-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write transactions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write transactions: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store transactions: %w", err)
+	}
+	return nil
+}
+
+// Delete implements TransactionStore
+func (fs *FileTransactionStore) Delete(scope, linkID string) error {
+	path, err := fs.path(scope, linkID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete stored transactions: %w", err)
+	}
+	return nil
+}
+
+func (fs *FileTransactionStore) path(scope, linkID string) (string, error) {
+	if !storeKeyPattern.MatchString(scope) || !storeKeyPattern.MatchString(linkID) {
+		return "", fmt.Errorf("invalid link ID %q", linkID)
+	}
+	return filepath.Join(fs.dir, scope, linkID+".json"), nil
+}
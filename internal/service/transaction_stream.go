@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// TransactionFilter narrows a transaction export. Zero values don't filter.
+type TransactionFilter struct {
+	From *time.Time // First accounting date included
+	To   *time.Time // Last accounting date included
+	Type string     // "INFLOW" or "OUTFLOW"
+}
+
+// Matches reports whether a transaction passes the filter
+func (f TransactionFilter) Matches(transaction models.BelvoTransaction) bool {
+	date := transaction.AccountingDate.Time()
+	if f.From != nil && date.Before(startOfDay(*f.From)) {
+		return false
+	}
+	if f.To != nil && !date.Before(startOfDay(*f.To).AddDate(0, 0, 1)) {
+		return false
+	}
+	return f.Type == "" || strings.EqualFold(transaction.Type, f.Type)
+}
+
+// TransactionStreamResult reports what a transaction export streamed
+type TransactionStreamResult struct {
+	Streamed  int  `json:"streamed"`
+	Pages     int  `json:"pages"`
+	Skipped   int  `json:"skipped"`   // Records that couldn't be decoded
+	Truncated bool `json:"truncated"` // The max-pages cap stopped the export early
+}
+
+// StreamTransactions pages through a link's transactions and hands each one that
+// matches the filter to fn as soon as its page is read, so exports of any size only
+// hold one page in memory. The filter is sent to Belvo and applied again locally.
+func (bs *BelvoService) StreamTransactions(ctx context.Context, linkID string, filter TransactionFilter, fn func(models.BelvoTransaction) error) (*TransactionStreamResult, error) {
+	query := url.Values{}
+	query.Set("link", linkID)
+	if filter.From != nil {
+		query.Set("accounting_date__gte", filter.From.Format("2006-01-02"))
+	}
+	if filter.To != nil {
+		query.Set("accounting_date__lte", filter.To.Format("2006-01-02"))
+	}
+	if filter.Type != "" {
+		query.Set("type", strings.ToUpper(filter.Type))
+	}
+
+	// Exports want as few round trips as Belvo allows
+	opts := bs.pageOptions
+	opts.PageSize = maxBelvoPageSize
+
+	result := &TransactionStreamResult{}
+	it := newPageIterator[models.BelvoTransaction](ctx, bs, "/api/transactions/?"+query.Encode(), opts)
+	err := it.Stream(func(transaction models.BelvoTransaction) error {
+		if !filter.Matches(transaction) {
+			return nil
+		}
+		result.Streamed++
+		return fn(transaction)
+	})
+	result.Pages = it.PagesRead()
+	result.Skipped = it.Skipped()
+	result.Truncated = it.Truncated()
+	if err != nil {
+		return result, fmt.Errorf("failed to stream transactions: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ai-financial-coach/internal/models"
+)
+
+// ErrTransactionSyncDisabled is returned by SyncTransactions when no store is configured
+var ErrTransactionSyncDisabled = errors.New("transaction sync is not configured")
+
+// SyncOptions controls how much history a sync fetches and how long it stays fresh
+type SyncOptions struct {
+	InitialMonths int           // History fetched the first time a link is synced
+	Overlap       time.Duration // Re-fetched before the high-water mark to catch late-posted transactions
+	MaxAge        time.Duration // Stored history younger than this is served without calling Belvo
+}
+
+// DefaultSyncOptions keep a year of history and re-check Belvo every 15 minutes
+var DefaultSyncOptions = SyncOptions{
+	InitialMonths: 12,
+	Overlap:       7 * 24 * time.Hour,
+	MaxAge:        15 * time.Minute,
+}
+
+// TransactionSyncer keeps a local copy of each link's transactions up to date. The
+// first sync downloads InitialMonths of history; later syncs fetch from the last
+// accounting_date minus Overlap and upsert by transaction ID.
+type TransactionSyncer struct {
+	store TransactionStore
+	opts  SyncOptions
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewTransactionSyncer creates a syncer backed by store
+func NewTransactionSyncer(store TransactionStore, opts SyncOptions) *TransactionSyncer {
+	if opts.InitialMonths <= 0 {
+		opts.InitialMonths = DefaultSyncOptions.InitialMonths
+	}
+	if opts.Overlap < 0 {
+		opts.Overlap = 0
+	}
+	return &TransactionSyncer{
+		store: store,
+		opts:  opts,
+		locks: make(map[string]*sync.Mutex),
+	}
+}
+
+// sharedTransactionSyncer is used by every BelvoService; nil disables the local store
+var sharedTransactionSyncer atomic.Pointer[TransactionSyncer]
+
+// ConfigureTransactionSync enables the local transaction store for all Belvo clients
+func ConfigureTransactionSync(store TransactionStore, opts SyncOptions) {
+	sharedTransactionSyncer.Store(NewTransactionSyncer(store, opts))
+}
+
+// SyncTransactions fetches the transactions booked since the last sync, plus the
+// overlap window, and merges them into the local store
+func (bs *BelvoService) SyncTransactions(ctx context.Context, linkID string) (*models.TransactionSyncResult, error) {
+	syncer := sharedTransactionSyncer.Load()
+	if syncer == nil {
+		return nil, ErrTransactionSyncDisabled
+	}
+
+	unlock := syncer.lock(bs.storeScope(), linkID)
+	defer unlock()
+
+	_, result, err := syncer.sync(ctx, bs, linkID, time.Time{})
+	return result, err
+}
+
+// GetStoredTransactions returns a link's transactions between dateFrom and dateTo from
+// the local store, syncing first when the stored copy is stale or doesn't reach back
+// to dateFrom. Without a configured store it reads straight from Belvo.
+func (bs *BelvoService) GetStoredTransactions(ctx context.Context, linkID string, dateFrom, dateTo *time.Time) ([]models.BelvoTransaction, error) {
+	syncer := sharedTransactionSyncer.Load()
+	if syncer == nil {
+		return bs.GetTransactions(ctx, linkID, dateFrom, dateTo)
+	}
+
+	scope := bs.storeScope()
+	unlock := syncer.lock(scope, linkID)
+	defer unlock()
+
+	state, err := syncer.store.Load(scope, linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	var historyFrom time.Time
+	if dateFrom != nil {
+		historyFrom = startOfDay(*dateFrom)
+	}
+
+	stale := state == nil ||
+		time.Since(state.LastSyncedAt) > syncer.opts.MaxAge ||
+		(!historyFrom.IsZero() && historyFrom.Before(state.HistoryFrom))
+	if stale {
+		state, _, err = syncer.sync(ctx, bs, linkID, historyFrom)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		fmt.Printf("⚡ Serving %d stored transactions for link %s (synced %s ago)\n", len(state.Transactions), linkID, time.Since(state.LastSyncedAt).Round(time.Second))
+	}
+
+	return filterTransactionsByDate(state.Transactions, dateFrom, dateTo), nil
+}
+
+// forgetTransactions drops the stored history of a deleted link
+func (bs *BelvoService) forgetTransactions(linkID string) {
+	syncer := sharedTransactionSyncer.Load()
+	if syncer == nil {
+		return
+	}
+	if err := syncer.store.Delete(bs.storeScope(), linkID); err != nil {
+		fmt.Printf("⚠️ Failed to delete stored transactions for link %s: %v\n", linkID, err)
+	}
+}
+
+// storeScope identifies these credentials in the store without persisting the secret ID
+func (bs *BelvoService) storeScope() string {
+	return CredentialScope(bs.credentials.Environment, bs.credentials.SecretID)
+}
+
+// CredentialScope identifies a Belvo account and environment by a hash of the secret
+// ID, for keeping stored data apart per account without storing the ID itself
+func CredentialScope(environment, secretID string) string {
+	sum := sha256.Sum256([]byte(environment + ":" + secretID))
+	return hex.EncodeToString(sum[:8])
+}
+
+// lock serializes syncs of one link so concurrent requests don't race on its file
+func (ts *TransactionSyncer) lock(scope, linkID string) func() {
+	key := scope + "/" + linkID
+
+	ts.mu.Lock()
+	linkLock, ok := ts.locks[key]
+	if !ok {
+		linkLock = &sync.Mutex{}
+		ts.locks[key] = linkLock
+	}
+	ts.mu.Unlock()
+
+	linkLock.Lock()
+	return linkLock.Unlock
+}
+
+// sync brings the stored history up to date. A non-zero historyFrom that predates the
+// stored history also backfills the gap. The caller must hold the link's lock.
+func (ts *TransactionSyncer) sync(ctx context.Context, bs *BelvoService, linkID string, historyFrom time.Time) (*models.TransactionSyncState, *models.TransactionSyncResult, error) {
+	scope := bs.storeScope()
+	state, err := ts.store.Load(scope, linkID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	result := &models.TransactionSyncResult{
+		LinkID:   linkID,
+		DateTo:   now,
+		SyncedAt: now,
+	}
+
+	var fetched []models.BelvoTransaction
+	if state == nil {
+		from := startOfDay(now.AddDate(0, -ts.opts.InitialMonths, 0))
+		if !historyFrom.IsZero() && historyFrom.Before(from) {
+			from = historyFrom
+		}
+		state = &models.TransactionSyncState{LinkID: linkID, HistoryFrom: from}
+		result.FullSync = true
+		result.DateFrom = from
+
+		fetched, err = bs.GetTransactions(ctx, linkID, &from, &now)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		from := state.HistoryFrom
+		if !state.LastAccountingDate.IsZero() {
+			from = startOfDay(state.LastAccountingDate.Add(-ts.opts.Overlap))
+		}
+		if from.Before(state.HistoryFrom) {
+			from = state.HistoryFrom
+		}
+		result.DateFrom = from
+
+		fetched, err = bs.GetTransactions(ctx, linkID, &from, &now)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Backfill history older than what was stored
+		if !historyFrom.IsZero() && historyFrom.Before(state.HistoryFrom) {
+			backfillTo := state.HistoryFrom
+			older, err := bs.GetTransactions(ctx, linkID, &historyFrom, &backfillTo)
+			if err != nil {
+				return nil, nil, err
+			}
+			fetched = append(fetched, older...)
+			state.HistoryFrom = historyFrom
+			result.DateFrom = historyFrom
+		}
+	}
+
+	result.Fetched = len(fetched)
+	result.Added, result.Updated = upsertTransactions(state, fetched)
+	state.LastSyncedAt = now
+
+	if err := ts.store.Save(scope, state); err != nil {
+		return nil, nil, err
+	}
+
+	result.Total = len(state.Transactions)
+	result.LastAccountingDate = state.LastAccountingDate
+	result.LastCollectedAt = state.LastCollectedAt
+
+	fmt.Printf("🔄 Synced link %s: fetched %d, added %d, updated %d, stored %d (from %s)\n",
+		linkID, result.Fetched, result.Added, result.Updated, result.Total, result.DateFrom.Format("2006-01-02"))
+	return state, result, nil
+}
+
+// upsertTransactions merges fetched transactions into state by ID, advances the
+// high-water marks and keeps the history sorted by accounting date
+func upsertTransactions(state *models.TransactionSyncState, fetched []models.BelvoTransaction) (added, updated int) {
+	index := make(map[string]int, len(state.Transactions))
+	for i, transaction := range state.Transactions {
+		index[transaction.ID] = i
+	}
+
+	for _, transaction := range fetched {
+		if i, ok := index[transaction.ID]; ok {
+			if !reflect.DeepEqual(state.Transactions[i], transaction) {
+				state.Transactions[i] = transaction
+				updated++
+			}
+		} else {
+			index[transaction.ID] = len(state.Transactions)
+			state.Transactions = append(state.Transactions, transaction)
+			added++
+		}
+
+		if accountingDate := transaction.AccountingDate.Time(); accountingDate.After(state.LastAccountingDate) {
+			state.LastAccountingDate = accountingDate
+		}
+		if collectedAt := transaction.CollectedAt.Time(); collectedAt.After(state.LastCollectedAt) {
+			state.LastCollectedAt = collectedAt
+		}
+	}
+
+	sort.SliceStable(state.Transactions, func(a, b int) bool {
+		return state.Transactions[a].AccountingDate.Time().Before(state.Transactions[b].AccountingDate.Time())
+	})
+	return added, updated
+}
+
+// filterTransactionsByDate keeps transactions whose accounting date falls on or
+// between the days of dateFrom and dateTo, matching Belvo's date filters
+func filterTransactionsByDate(transactions []models.BelvoTransaction, dateFrom, dateTo *time.Time) []models.BelvoTransaction {
+	filtered := make([]models.BelvoTransaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		accountingDate := transaction.AccountingDate.Time()
+		if dateFrom != nil && accountingDate.Before(startOfDay(*dateFrom)) {
+			continue
+		}
+		if dateTo != nil && !accountingDate.Before(startOfDay(*dateTo).AddDate(0, 0, 1)) {
+			continue
+		}
+		filtered = append(filtered, transaction)
+	}
+	return filtered
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}